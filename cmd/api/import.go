@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hippias-fiber/internal/importer"
+	"os"
+
+	supa "github.com/nedpals/supabase-go"
+)
+
+// runImport implements `main import [-source openlibrary|isbn] [-commit] FILE`.
+// Without -commit it prints the planned changes and writes nothing.
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	source := flags.String("source", importer.SourceOpenLibrary, "input format: openlibrary or isbn")
+	commit := flags.Bool("commit", false, "write the changes instead of printing a dry-run diff")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: main import [-source openlibrary|isbn] [-commit] FILE")
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	records, err := importer.Parse(*source, file)
+	if err != nil {
+		return err
+	}
+
+	im := importer.New(supa.CreateClient(os.Getenv("API_URL"), os.Getenv("API_KEY")))
+	plan, err := im.Plan(records)
+	if err != nil {
+		return err
+	}
+
+	out := json.NewEncoder(os.Stdout)
+	out.SetIndent("", "  ")
+	if err := out.Encode(plan); err != nil {
+		return err
+	}
+	if !*commit {
+		fmt.Fprintln(os.Stderr, "dry run: re-run with -commit to apply")
+		return nil
+	}
+
+	result, err := im.Apply(plan)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "created %d authors, %d books and %d editions, updated %d books\n",
+		result.AuthorsCreated, result.BooksCreated, result.EditionsCreated, result.BooksUpdated)
+	return nil
+}
@@ -10,6 +10,13 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := runImport(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "import failed: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	server := server.New()
 	port, _ := strconv.Atoi(os.Getenv("PORT"))
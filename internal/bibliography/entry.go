@@ -0,0 +1,132 @@
+// Package bibliography renders course reading lists as citation data (BibTeX,
+// RIS, CSL-JSON) and as formatted Chicago or MLA bibliographies, and parses
+// BibTeX and RIS files back into entries.
+package bibliography
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Entry kinds, mapped onto each output format's own types.
+const (
+	KindBook    = "book"
+	KindChapter = "chapter"
+	KindArticle = "article"
+	KindVideo   = "video"
+	KindAudio   = "audio"
+	KindWebPage = "webpage"
+)
+
+// Entry is a format-neutral bibliographic record.
+type Entry struct {
+	// Citation key, unique within a bibliography
+	Key        string
+	Kind       string
+	Title      string
+	Authors    []string
+	Translator string
+	// Book title for chapters, journal or site for articles and media
+	Container string
+	Publisher string
+	Year      int
+	ISBN      string
+	Language  string
+	URL       string
+	Chapter   string
+	PageStart int
+	PageEnd   int
+}
+
+// Pages renders the page range as "71-112", a single page as "71", or "" when
+// unknown. A range with only an end page is that single page.
+func (e Entry) Pages() string {
+	switch {
+	case e.PageStart > 0 && e.PageEnd > e.PageStart:
+		return strconv.Itoa(e.PageStart) + "-" + strconv.Itoa(e.PageEnd)
+	case e.PageStart > 0:
+		return strconv.Itoa(e.PageStart)
+	case e.PageEnd > 0:
+		return strconv.Itoa(e.PageEnd)
+	}
+	return ""
+}
+
+// SplitName splits a display name into family and given names. Names already
+// written "Family, Given" are respected; otherwise the last word is the family name.
+func SplitName(name string) (family, given string) {
+	name = strings.TrimSpace(name)
+	if f, g, ok := strings.Cut(name, ","); ok {
+		return strings.TrimSpace(f), strings.TrimSpace(g)
+	}
+	i := strings.LastIndex(name, " ")
+	if i < 0 {
+		return name, ""
+	}
+	return name[i+1:], name[:i]
+}
+
+// Sort orders entries as a bibliography lists them: by first author's family
+// name, then title.
+func Sort(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := sortKey(entries[i]), sortKey(entries[j])
+		return a < b
+	})
+}
+
+func sortKey(e Entry) string {
+	key := ""
+	if len(e.Authors) > 0 {
+		family, given := SplitName(e.Authors[0])
+		key = family + " " + given + " "
+	}
+	return strings.ToLower(key + strings.TrimLeft(e.Title, "\"'“‘"))
+}
+
+// Keys assigns citation keys of the form family+year(+suffix), e.g.
+// "nietzsche1966" and "nietzsche1966a", to entries that lack one.
+func Keys(entries []Entry) {
+	used := map[string]bool{}
+	for i := range entries {
+		if entries[i].Key != "" {
+			used[entries[i].Key] = true
+		}
+	}
+	for i := range entries {
+		if entries[i].Key != "" {
+			continue
+		}
+		base := "anon"
+		if len(entries[i].Authors) > 0 {
+			family, _ := SplitName(entries[i].Authors[0])
+			base = keyword(family)
+		} else if w := keyword(entries[i].Title); w != "" {
+			base = w
+		}
+		if entries[i].Year > 0 {
+			base += strconv.Itoa(entries[i].Year)
+		}
+		key := base
+		for suffix := 'a'; used[key]; suffix++ {
+			key = base + string(suffix)
+		}
+		used[key] = true
+		entries[i].Key = key
+	}
+}
+
+// keyword lowercases s and keeps the ASCII letters and digits of its first word.
+func keyword(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' && b.Len() > 0:
+			return b.String()
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package bibliography
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitName(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantFamily string
+		wantGiven  string
+	}{
+		{"Plato", "Plato", ""},
+		{"Friedrich Nietzsche", "Nietzsche", "Friedrich"},
+		{"Simone de Beauvoir", "Beauvoir", "Simone de"},
+		{"de Beauvoir, Simone", "de Beauvoir", "Simone"},
+		{"  Hannah Arendt ", "Arendt", "Hannah"},
+	}
+	for _, tt := range tests {
+		family, given := SplitName(tt.name)
+		if family != tt.wantFamily || given != tt.wantGiven {
+			t.Errorf("SplitName(%q) = %q, %q, want %q, %q", tt.name, family, given, tt.wantFamily, tt.wantGiven)
+		}
+	}
+}
+
+func TestPages(t *testing.T) {
+	tests := []struct {
+		start, end int
+		want       string
+	}{
+		{0, 0, ""},
+		{71, 0, "71"},
+		{71, 71, "71"},
+		{71, 112, "71-112"},
+		{0, 112, "112"},
+	}
+	for _, tt := range tests {
+		if got := (Entry{PageStart: tt.start, PageEnd: tt.end}).Pages(); got != tt.want {
+			t.Errorf("Pages(%d, %d) = %q, want %q", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestSortAndKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    []string
+	}{
+		{
+			name: "sorted by family name then title",
+			entries: []Entry{
+				{Title: "Symposium", Authors: []string{"Plato"}},
+				{Title: "The Human Condition", Authors: []string{"Hannah Arendt"}, Year: 1958},
+				{Title: "Apology", Authors: []string{"Plato"}},
+			},
+			want: []string{"arendt1958", "plato", "platoa"},
+		},
+		{
+			name: "existing keys are kept and suffixes skip them",
+			entries: []Entry{
+				{Key: "nietzsche1966", Title: "Beyond Good and Evil", Authors: []string{"Friedrich Nietzsche"}, Year: 1966},
+				{Title: "The Birth of Tragedy", Authors: []string{"Friedrich Nietzsche"}, Year: 1966},
+				{Title: "The Gay Science", Authors: []string{"Friedrich Nietzsche"}, Year: 1966},
+			},
+			want: []string{"nietzsche1966", "nietzsche1966a", "nietzsche1966b"},
+		},
+		{
+			name: "entries without authors fall back to the title",
+			entries: []Entry{
+				{Title: "“Beowulf”"},
+				{},
+			},
+			want: []string{"anon", "beowulf"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Sort(tt.entries)
+			Keys(tt.entries)
+			var keys []string
+			for _, entry := range tt.entries {
+				keys = append(keys, entry.Key)
+			}
+			if !reflect.DeepEqual(keys, tt.want) {
+				t.Errorf("keys = %v, want %v", keys, tt.want)
+			}
+		})
+	}
+}
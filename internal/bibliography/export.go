@@ -0,0 +1,197 @@
+package bibliography
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BibTeX renders entries as BibTeX records.
+func BibTeX(entries []Entry) string {
+	var b strings.Builder
+	for i, e := range entries {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		entryType, containerField := "book", ""
+		switch e.Kind {
+		case KindChapter:
+			entryType, containerField = "incollection", "booktitle"
+		case KindArticle:
+			entryType, containerField = "article", "journal"
+		case KindVideo, KindAudio, KindWebPage:
+			entryType, containerField = "misc", "howpublished"
+		}
+
+		fmt.Fprintf(&b, "@%s{%s,\n", entryType, e.Key)
+		field := func(name, value string) {
+			if value != "" {
+				fmt.Fprintf(&b, "  %s = {%s},\n", name, bibtexEscape(value))
+			}
+		}
+		var authors []string
+		for _, author := range e.Authors {
+			family, given := SplitName(author)
+			if given == "" {
+				authors = append(authors, family)
+			} else {
+				authors = append(authors, family+", "+given)
+			}
+		}
+		field("author", strings.Join(authors, " and "))
+		field("title", e.Title)
+		if containerField != "" {
+			field(containerField, e.Container)
+		}
+		field("translator", e.Translator)
+		field("publisher", e.Publisher)
+		if e.Year > 0 {
+			field("year", strconv.Itoa(e.Year))
+		}
+		field("chapter", e.Chapter)
+		field("pages", strings.Replace(e.Pages(), "-", "--", 1))
+		field("isbn", e.ISBN)
+		field("language", e.Language)
+		field("url", e.URL)
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+func bibtexEscape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\textbackslash{}`,
+		"{", `\{`, "}", `\}`,
+		"&", `\&`, "%", `\%`, "$", `\$`, "#", `\#`, "_", `\_`,
+	).Replace(s)
+}
+
+// RIS renders entries in the RIS tagged format.
+func RIS(entries []Entry) string {
+	var b strings.Builder
+	for _, e := range entries {
+		risType := map[string]string{
+			KindBook:    "BOOK",
+			KindChapter: "CHAP",
+			KindArticle: "JOUR",
+			KindVideo:   "VIDEO",
+			KindAudio:   "SOUND",
+			KindWebPage: "ELEC",
+		}[e.Kind]
+		if risType == "" {
+			risType = "GEN"
+		}
+		tag := func(name, value string) {
+			if value != "" {
+				fmt.Fprintf(&b, "%s  - %s\r\n", name, value)
+			}
+		}
+		tag("TY", risType)
+		tag("ID", e.Key)
+		for _, author := range e.Authors {
+			family, given := SplitName(author)
+			if given != "" {
+				family += ", " + given
+			}
+			tag("AU", family)
+		}
+		if e.Translator != "" {
+			family, given := SplitName(e.Translator)
+			if given != "" {
+				family += ", " + given
+			}
+			tag("A4", family)
+		}
+		tag("TI", e.Title)
+		tag("T2", e.Container)
+		tag("PB", e.Publisher)
+		if e.Year > 0 {
+			tag("PY", strconv.Itoa(e.Year))
+		}
+		if e.PageStart > 0 {
+			tag("SP", strconv.Itoa(e.PageStart))
+		}
+		if e.PageEnd > 0 {
+			tag("EP", strconv.Itoa(e.PageEnd))
+		}
+		tag("SN", e.ISBN)
+		tag("LA", e.Language)
+		tag("UR", e.URL)
+		b.WriteString("ER  - \r\n")
+	}
+	return b.String()
+}
+
+type cslName struct {
+	Family string `json:"family,omitempty"`
+	Given  string `json:"given,omitempty"`
+}
+
+type cslItem struct {
+	ID             string    `json:"id"`
+	Type           string    `json:"type"`
+	Title          string    `json:"title,omitempty"`
+	Author         []cslName `json:"author,omitempty"`
+	Translator     []cslName `json:"translator,omitempty"`
+	ContainerTitle string    `json:"container-title,omitempty"`
+	Publisher      string    `json:"publisher,omitempty"`
+	Issued         *cslDate  `json:"issued,omitempty"`
+	Page           string    `json:"page,omitempty"`
+	ChapterNumber  string    `json:"chapter-number,omitempty"`
+	ISBN           string    `json:"ISBN,omitempty"`
+	Language       string    `json:"language,omitempty"`
+	URL            string    `json:"URL,omitempty"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+// CSLJSON renders entries as a CSL-JSON array.
+func CSLJSON(entries []Entry) ([]byte, error) {
+	items := make([]cslItem, 0, len(entries))
+	for _, e := range entries {
+		item := cslItem{
+			ID:             e.Key,
+			Type:           cslType(e.Kind),
+			Title:          e.Title,
+			ContainerTitle: e.Container,
+			Publisher:      e.Publisher,
+			Page:           e.Pages(),
+			ChapterNumber:  e.Chapter,
+			ISBN:           e.ISBN,
+			Language:       e.Language,
+			URL:            e.URL,
+		}
+		for _, author := range e.Authors {
+			family, given := SplitName(author)
+			item.Author = append(item.Author, cslName{Family: family, Given: given})
+		}
+		if e.Translator != "" {
+			family, given := SplitName(e.Translator)
+			item.Translator = []cslName{{Family: family, Given: given}}
+		}
+		if e.Year > 0 {
+			item.Issued = &cslDate{DateParts: [][]int{{e.Year}}}
+		}
+		items = append(items, item)
+	}
+	return json.MarshalIndent(items, "", "  ")
+}
+
+func cslType(kind string) string {
+	switch kind {
+	case KindChapter:
+		return "chapter"
+	case KindArticle:
+		return "article-journal"
+	case KindVideo:
+		return "motion_picture"
+	case KindAudio:
+		return "broadcast"
+	case KindWebPage:
+		return "webpage"
+	}
+	return "book"
+}
@@ -0,0 +1,478 @@
+package bibliography
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Import formats accepted by Parse.
+const (
+	FormatBibTeX = "bibtex"
+	FormatRIS    = "ris"
+)
+
+// Parse reads BibTeX or RIS records. An empty format is detected from the
+// content. Records of a type with no matching Kind are returned with an empty
+// Kind so that callers can report them.
+func Parse(format string, r io.Reader) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	if format == "" {
+		format = DetectFormat(text)
+	}
+	switch format {
+	case FormatBibTeX:
+		return ParseBibTeX(text)
+	case FormatRIS:
+		return ParseRIS(text)
+	}
+	return nil, fmt.Errorf("unknown bibliography format %q (want %s or %s)", format, FormatBibTeX, FormatRIS)
+}
+
+// DetectFormat guesses whether text is BibTeX or RIS, returning "" when it is
+// neither.
+func DetectFormat(text string) string {
+	trimmed := strings.TrimSpace(text)
+	switch {
+	case strings.HasPrefix(trimmed, "TY  -"):
+		return FormatRIS
+	case strings.Contains(trimmed, "@"):
+		return FormatBibTeX
+	}
+	return ""
+}
+
+var bibtexKinds = map[string]string{
+	"book":          KindBook,
+	"mvbook":        KindBook,
+	"inbook":        KindChapter,
+	"incollection":  KindChapter,
+	"inproceedings": KindChapter,
+	"article":       KindArticle,
+	"video":         KindVideo,
+	"movie":         KindVideo,
+	"audio":         KindAudio,
+	"online":        KindWebPage,
+	"electronic":    KindWebPage,
+	"www":           KindWebPage,
+}
+
+// ParseBibTeX parses BibTeX or BibLaTeX records, expanding @string macros.
+// @preamble and @comment blocks are ignored.
+func ParseBibTeX(text string) ([]Entry, error) {
+	p := &bibtexParser{src: text, macros: map[string]string{}}
+	var entries []Entry
+	for {
+		i := strings.IndexByte(p.src[p.pos:], '@')
+		if i < 0 {
+			return entries, nil
+		}
+		p.pos += i + 1
+		entryType := strings.ToLower(p.ident())
+		p.skipSpace()
+		if p.pos >= len(p.src) || (p.src[p.pos] != '{' && p.src[p.pos] != '(') {
+			continue
+		}
+		if entryType == "string" {
+			if err := p.macro(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if entryType == "comment" || entryType == "preamble" {
+			if _, err := p.delimited(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		fields, key, err := p.record()
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", len(entries)+1, err)
+		}
+		entries = append(entries, bibtexEntry(entryType, key, fields))
+	}
+}
+
+func bibtexEntry(entryType, key string, fields map[string]string) Entry {
+	e := Entry{
+		Key:        key,
+		Kind:       bibtexKinds[entryType],
+		Title:      fields["title"],
+		Translator: fields["translator"],
+		Publisher:  fields["publisher"],
+		ISBN:       fields["isbn"],
+		Language:   fields["language"],
+		URL:        fields["url"],
+		Chapter:    fields["chapter"],
+	}
+	if e.Kind == "" && entryType == "misc" && e.URL != "" {
+		e.Kind = KindWebPage
+	}
+	if authors := fields["author"]; authors != "" {
+		for _, name := range authorSeparator.Split(authors, -1) {
+			if name = strings.TrimSpace(name); name != "" && name != "others" {
+				e.Authors = append(e.Authors, flipName(name))
+			}
+		}
+	}
+	if e.Translator != "" {
+		e.Translator = flipName(strings.SplitN(e.Translator, " and ", 2)[0])
+	}
+	for _, field := range []string{"booktitle", "journal", "journaltitle", "howpublished", "organization"} {
+		if fields[field] != "" {
+			e.Container = fields[field]
+			break
+		}
+	}
+	e.Year = leadingYear(fields["year"])
+	if e.Year == 0 {
+		e.Year = leadingYear(fields["date"])
+	}
+	e.PageStart, e.PageEnd = parsePages(fields["pages"])
+	return e
+}
+
+type bibtexParser struct {
+	src    string
+	pos    int
+	macros map[string]string
+}
+
+func (p *bibtexParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *bibtexParser) ident() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.src) {
+		ch := p.src[p.pos]
+		if unicode.IsSpace(rune(ch)) || strings.IndexByte(",={}()\"#", ch) >= 0 {
+			break
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// delimited consumes a {...} or (...) group and returns its contents.
+func (p *bibtexParser) delimited() (string, error) {
+	open := p.src[p.pos]
+	close := byte('}')
+	if open == '(' {
+		close = ')'
+	}
+	depth := 0
+	start := p.pos + 1
+	for ; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				p.pos++
+				return p.src[start : p.pos-1], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unbalanced %q", open)
+}
+
+// record parses "{key, field = value, ...}" and returns the fields by
+// lowercase name.
+func (p *bibtexParser) record() (map[string]string, string, error) {
+	close := byte('}')
+	if p.src[p.pos] == '(' {
+		close = ')'
+	}
+	p.pos++
+	key := p.ident()
+	fields := map[string]string{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, key, fmt.Errorf("unexpected end of input")
+		}
+		switch p.src[p.pos] {
+		case ',':
+			p.pos++
+			continue
+		case close:
+			p.pos++
+			return fields, key, nil
+		}
+
+		name := strings.ToLower(p.ident())
+		p.skipSpace()
+		if name == "" || p.pos >= len(p.src) || p.src[p.pos] != '=' {
+			return nil, key, fmt.Errorf("expected field name and '=' near offset %d", p.pos)
+		}
+		p.pos++
+		value, err := p.value()
+		if err != nil {
+			return nil, key, fmt.Errorf("field %s: %w", name, err)
+		}
+		fields[name] = cleanLaTeX(value)
+	}
+}
+
+// macro parses the body of @string{name = value}.
+func (p *bibtexParser) macro() error {
+	close := byte('}')
+	if p.src[p.pos] == '(' {
+		close = ')'
+	}
+	p.pos++
+	name := strings.ToLower(p.ident())
+	p.skipSpace()
+	if name == "" || p.pos >= len(p.src) || p.src[p.pos] != '=' {
+		return fmt.Errorf("malformed @string near offset %d", p.pos)
+	}
+	p.pos++
+	value, err := p.value()
+	if err != nil {
+		return fmt.Errorf("@string %s: %w", name, err)
+	}
+	p.skipSpace()
+	if p.pos >= len(p.src) || p.src[p.pos] != close {
+		return fmt.Errorf("malformed @string %s", name)
+	}
+	p.pos++
+	p.macros[name] = value
+	return nil
+}
+
+// value parses a field value, including "#" concatenation. Undefined macro
+// references, such as month abbreviations, are kept as their names.
+func (p *bibtexParser) value() (string, error) {
+	var b strings.Builder
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return "", fmt.Errorf("unexpected end of input")
+		}
+		switch p.src[p.pos] {
+		case '{':
+			part, err := p.delimited()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(part)
+		case '"':
+			end := p.pos + 1
+			depth := 0
+			for ; end < len(p.src); end++ {
+				ch := p.src[end]
+				if ch == '{' {
+					depth++
+				} else if ch == '}' {
+					depth--
+				} else if ch == '"' && depth == 0 {
+					break
+				}
+			}
+			if end >= len(p.src) {
+				return "", fmt.Errorf("unterminated quoted value")
+			}
+			b.WriteString(p.src[p.pos+1 : end])
+			p.pos = end + 1
+		default:
+			word := p.ident()
+			if expanded, ok := p.macros[strings.ToLower(word)]; ok {
+				word = expanded
+			}
+			b.WriteString(word)
+		}
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == '#' {
+			p.pos++
+			continue
+		}
+		return b.String(), nil
+	}
+}
+
+var latexAccents = map[string]map[rune]string{
+	`'`: {'a': "á", 'e': "é", 'i': "í", 'o': "ó", 'u': "ú", 'A': "Á", 'E': "É", 'I': "Í", 'O': "Ó", 'U': "Ú", 'c': "ć", 'n': "ń", 's': "ś", 'y': "ý", 'z': "ź"},
+	"`": {'a': "à", 'e': "è", 'i': "ì", 'o': "ò", 'u': "ù", 'A': "À", 'E': "È", 'I': "Ì", 'O': "Ò", 'U': "Ù"},
+	`"`: {'a': "ä", 'e': "ë", 'i': "ï", 'o': "ö", 'u': "ü", 'A': "Ä", 'E': "Ë", 'I': "Ï", 'O': "Ö", 'U': "Ü", 'y': "ÿ"},
+	`^`: {'a': "â", 'e': "ê", 'i': "î", 'o': "ô", 'u': "û", 'A': "Â", 'E': "Ê", 'I': "Î", 'O': "Ô", 'U': "Û"},
+	`~`: {'a': "ã", 'n': "ñ", 'o': "õ", 'A': "Ã", 'N': "Ñ", 'O': "Õ"},
+	`c`: {'c': "ç", 'C': "Ç", 's': "ş", 'S': "Ş"},
+	`v`: {'c': "č", 'C': "Č", 's': "š", 'S': "Š", 'z': "ž", 'Z': "Ž", 'r': "ř", 'e': "ě"},
+}
+
+var latexSymbols = map[string]string{
+	"ss": "ß", "ae": "æ", "AE": "Æ", "oe": "œ", "OE": "Œ", "o": "ø", "O": "Ø",
+	"aa": "å", "AA": "Å", "l": "ł", "L": "Ł", "i": "ı",
+	"textendash": "–", "textemdash": "—", "textbackslash": `\`,
+}
+
+var (
+	latexAccent  = regexp.MustCompile(`\\([` + "`" + `'"^~]|[cv](?:\s|\{))\s*\{?\\?([A-Za-z])\}?`)
+	latexSymbol  = regexp.MustCompile(`\\([A-Za-z]+)\b(?:\{\})?`)
+	latexEscaped = regexp.MustCompile(`\\([&%$#_{}])`)
+)
+
+// cleanLaTeX turns the common LaTeX accents and escapes into plain text and
+// drops grouping braces.
+func cleanLaTeX(s string) string {
+	s = latexAccent.ReplaceAllStringFunc(s, func(m string) string {
+		parts := latexAccent.FindStringSubmatch(m)
+		accent := strings.TrimRight(parts[1], " \t\n{")
+		if table, ok := latexAccents[accent]; ok {
+			if out, ok := table[rune(parts[2][0])]; ok {
+				return out
+			}
+		}
+		return parts[2]
+	})
+	s = latexSymbol.ReplaceAllStringFunc(s, func(m string) string {
+		name := latexSymbol.FindStringSubmatch(m)[1]
+		if out, ok := latexSymbols[name]; ok {
+			return out
+		}
+		return ""
+	})
+	s = latexEscaped.ReplaceAllString(s, "\x00$1")
+	s = strings.NewReplacer("{", "", "}", "", "---", "—", "--", "–", "~", " ").Replace(s)
+	s = strings.ReplaceAll(s, "\x00", "")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var risKinds = map[string]string{
+	"BOOK":   KindBook,
+	"EBOOK":  KindBook,
+	"EDBOOK": KindBook,
+	"CHAP":   KindChapter,
+	"ECHAP":  KindChapter,
+	"JOUR":   KindArticle,
+	"EJOUR":  KindArticle,
+	"MGZN":   KindArticle,
+	"NEWS":   KindArticle,
+	"VIDEO":  KindVideo,
+	"MPCT":   KindVideo,
+	"SOUND":  KindAudio,
+	"ELEC":   KindWebPage,
+	"WEB":    KindWebPage,
+	"BLOG":   KindWebPage,
+}
+
+// authorSeparator splits a BibTeX author list
+var authorSeparator = regexp.MustCompile(`\s+and\s+`)
+
+var risLine = regexp.MustCompile(`^([A-Z][A-Z0-9])  -( (.*))?$`)
+
+// ParseRIS parses RIS records as exported by Zotero, EndNote and Mendeley.
+func ParseRIS(text string) ([]Entry, error) {
+	var entries []Entry
+	var current *Entry
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		m := risLine.FindStringSubmatch(text)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: not a RIS tag", line)
+		}
+		tag, value := m[1], strings.TrimSpace(m[3])
+
+		if tag == "TY" {
+			if current != nil {
+				return nil, fmt.Errorf("line %d: TY before ER", line)
+			}
+			current = &Entry{Kind: risKinds[value]}
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d: %s outside a record", line, tag)
+		}
+		switch tag {
+		case "ER":
+			entries = append(entries, *current)
+			current = nil
+		case "ID":
+			current.Key = value
+		case "AU", "A1":
+			current.Authors = append(current.Authors, flipName(value))
+		case "A4":
+			if current.Translator == "" {
+				current.Translator = flipName(value)
+			}
+		case "TI", "T1":
+			current.Title = value
+		case "T2", "BT", "JO", "JF", "JA":
+			if current.Container == "" {
+				current.Container = value
+			}
+		case "PB":
+			current.Publisher = value
+		case "PY", "Y1", "DA":
+			if current.Year == 0 {
+				current.Year = leadingYear(value)
+			}
+		case "SP":
+			current.PageStart, current.PageEnd = parsePages(value)
+		case "EP":
+			current.PageEnd, _ = strconv.Atoi(value)
+		case "SN":
+			if current.ISBN == "" {
+				current.ISBN = value
+			}
+		case "LA":
+			current.Language = value
+		case "UR":
+			if current.URL == "" {
+				current.URL = value
+			}
+		case "SE":
+			current.Chapter = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if current != nil {
+		return nil, fmt.Errorf("record without ER at end of input")
+	}
+	return entries, nil
+}
+
+// flipName turns "Family, Given" into "Given Family".
+func flipName(name string) string {
+	family, given := SplitName(name)
+	if given == "" {
+		return family
+	}
+	return given + " " + family
+}
+
+var yearPattern = regexp.MustCompile(`\d{4}`)
+
+func leadingYear(s string) int {
+	year, _ := strconv.Atoi(yearPattern.FindString(s))
+	return year
+}
+
+// parsePages reads "71--112", "71-112", "71–112" or "71".
+func parsePages(s string) (start, end int) {
+	s = strings.NewReplacer("–", "-", "—", "-").Replace(s)
+	first, last, _ := strings.Cut(s, "-")
+	start, _ = strconv.Atoi(strings.TrimSpace(first))
+	end, _ = strconv.Atoi(strings.Trim(last, "- "))
+	return start, end
+}
@@ -0,0 +1,203 @@
+package bibliography
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBibTeX(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Entry
+	}{
+		{
+			name: "book with accents, macros and concatenation",
+			text: `@string{vin = "Vintage"}
+@comment{ignored}
+@Book{nietzsche1966,
+  author = {Nietzsche, Friedrich},
+  title = {Beyond Good and {Evil}},
+  translator = {Kaufmann, Walter and Hollingdale, R. J.},
+  publisher = vin # " Books",
+  year = 1966,
+}`,
+			want: []Entry{{
+				Key:        "nietzsche1966",
+				Kind:       KindBook,
+				Title:      "Beyond Good and Evil",
+				Authors:    []string{"Friedrich Nietzsche"},
+				Translator: "Walter Kaufmann",
+				Publisher:  "Vintage Books",
+				Year:       1966,
+			}},
+		},
+		{
+			name: "chapter with several authors and a page range",
+			text: `@incollection{g,
+  author = "G{\"o}del, Kurt and Emmy Noether and others",
+  title = "On Undecidable Propositions",
+  booktitle = {From Frege to G\"odel},
+  date = {1967-01-01},
+  pages = {596--616}
+}`,
+			want: []Entry{{
+				Key:       "g",
+				Kind:      KindChapter,
+				Title:     "On Undecidable Propositions",
+				Authors:   []string{"Kurt Gödel", "Emmy Noether"},
+				Container: "From Frege to Gödel",
+				Year:      1967,
+				PageStart: 596,
+				PageEnd:   616,
+			}},
+		},
+		{
+			name: "misc with a URL is a web page and unknown types have no kind",
+			text: `@misc(sep, title = {Plato}, url = {https://plato.stanford.edu/entries/plato/})
+@patent{p, title = {Widget}}`,
+			want: []Entry{
+				{Key: "sep", Kind: KindWebPage, Title: "Plato", URL: "https://plato.stanford.edu/entries/plato/"},
+				{Key: "p", Title: "Widget"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBibTeX(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBibTeX() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRIS(t *testing.T) {
+	text := "TY  - CHAP\r\nID  - plato2002\r\nAU  - Plato\r\nTI  - Apology\r\nT2  - Five Dialogues\r\n" +
+		"PB  - Hackett\r\nPY  - 2002/01/01\r\nSP  - 21\r\nEP  - 44\r\nSN  - 9780872206335\r\nER  - \r\n\r\n" +
+		"TY  - JOUR\r\nAU  - Turing, Alan\r\nTI  - Computing Machinery and Intelligence\r\nJO  - Mind\r\nPY  - 1950\r\nSP  - 433-460\r\nER  - \r\n"
+	want := []Entry{
+		{
+			Key:       "plato2002",
+			Kind:      KindChapter,
+			Title:     "Apology",
+			Authors:   []string{"Plato"},
+			Container: "Five Dialogues",
+			Publisher: "Hackett",
+			Year:      2002,
+			ISBN:      "9780872206335",
+			PageStart: 21,
+			PageEnd:   44,
+		},
+		{
+			Kind:      KindArticle,
+			Title:     "Computing Machinery and Intelligence",
+			Authors:   []string{"Alan Turing"},
+			Container: "Mind",
+			Year:      1950,
+			PageStart: 433,
+			PageEnd:   460,
+		},
+	}
+	got, err := ParseRIS(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRIS() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		text   string
+	}{
+		{"unknown format", "", "plain text"},
+		{"unterminated bibtex record", FormatBibTeX, "@book{a, title = {Open"},
+		{"bibtex field without value", FormatBibTeX, "@book{a, title}"},
+		{"ris tag outside a record", FormatRIS, "TI  - Apology\n"},
+		{"ris record without ER", FormatRIS, "TY  - BOOK\nTI  - Apology\n"},
+		{"ris nested records", FormatRIS, "TY  - BOOK\nTY  - BOOK\n"},
+		{"ris garbage line", FormatRIS, "TY  - BOOK\nnot a tag\nER  - \n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.format, strings.NewReader(tt.text)); err == nil {
+				t.Error("Parse succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"\n  TY  - BOOK\nER  - \n", FormatRIS},
+		{"% exported\n@book{a, title = {A}}", FormatBibTeX},
+		{"Title,Author\n", ""},
+	}
+	for _, tt := range tests {
+		if got := DetectFormat(tt.text); got != tt.want {
+			t.Errorf("DetectFormat(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// Exported entries parse back to what was exported.
+func TestExportRoundTrip(t *testing.T) {
+	entries := []Entry{
+		{
+			Key:        "nietzsche1966",
+			Kind:       KindBook,
+			Title:      "Beyond Good & Evil",
+			Authors:    []string{"Friedrich Nietzsche"},
+			Translator: "Walter Kaufmann",
+			Publisher:  "Vintage",
+			Year:       1966,
+			ISBN:       "9780679724650",
+			Language:   "en",
+		},
+		{
+			Key:       "plato2002",
+			Kind:      KindChapter,
+			Title:     "Apology",
+			Authors:   []string{"Plato", "G. M. A. Grube"},
+			Container: "Five Dialogues",
+			Year:      2002,
+			PageStart: 21,
+			PageEnd:   44,
+		},
+		{
+			Key:   "sep",
+			Kind:  KindWebPage,
+			Title: "100% Plato",
+			URL:   "https://plato.stanford.edu/entries/plato/",
+		},
+	}
+	tests := []struct {
+		format string
+		export func([]Entry) string
+	}{
+		{FormatBibTeX, BibTeX},
+		{FormatRIS, RIS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := Parse("", strings.NewReader(tt.export(entries)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, entries) {
+				t.Errorf("round trip =\n%+v\nwant\n%+v", got, entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package bibliography
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+const (
+	StyleChicago = "chicago"
+	StyleMLA     = "mla"
+
+	MarkupHTML     = "html"
+	MarkupMarkdown = "markdown"
+)
+
+// Format renders a sorted bibliography in the given citation style, as HTML or
+// Markdown.
+func Format(entries []Entry, style, markup string) (string, error) {
+	var m markupWriter
+	switch markup {
+	case MarkupHTML:
+		m = htmlMarkup{}
+	case MarkupMarkdown:
+		m = markdownMarkup{}
+	default:
+		return "", fmt.Errorf("unknown markup %q", markup)
+	}
+
+	var format func(Entry, markupWriter) string
+	switch style {
+	case StyleChicago:
+		format = chicago
+	case StyleMLA:
+		format = mla
+	default:
+		return "", fmt.Errorf("unknown citation style %q: expected %s or %s", style, StyleChicago, StyleMLA)
+	}
+
+	sorted := append([]Entry(nil), entries...)
+	Sort(sorted)
+	lines := make([]string, len(sorted))
+	for i, e := range sorted {
+		lines[i] = format(e, m)
+	}
+	return m.list(lines), nil
+}
+
+type markupWriter interface {
+	text(s string) string
+	italic(s string) string
+	list(entries []string) string
+}
+
+type htmlMarkup struct{}
+
+func (htmlMarkup) text(s string) string   { return html.EscapeString(s) }
+func (htmlMarkup) italic(s string) string { return "<i>" + html.EscapeString(s) + "</i>" }
+func (htmlMarkup) list(entries []string) string {
+	var b strings.Builder
+	b.WriteString("<div class=\"csl-bib-body\">\n")
+	for _, entry := range entries {
+		b.WriteString("  <div class=\"csl-entry\">" + entry + "</div>\n")
+	}
+	b.WriteString("</div>\n")
+	return b.String()
+}
+
+type markdownMarkup struct{}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "`", "\\`")
+
+func (markdownMarkup) text(s string) string   { return markdownEscaper.Replace(s) }
+func (markdownMarkup) italic(s string) string { return "*" + markdownEscaper.Replace(s) + "*" }
+func (markdownMarkup) list(entries []string) string {
+	return strings.Join(entries, "\n\n") + "\n"
+}
+
+// terminate appends a period unless s already ends in terminal punctuation.
+func terminate(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.ContainsAny(s[len(s)-1:], ".?!") {
+		return s
+	}
+	return s + "."
+}
+
+// quoted renders a title in quotation marks with the trailing period inside.
+func quoted(m markupWriter, title string) string {
+	return "“" + m.text(terminate(title)) + "”"
+}
+
+// chicagoAuthors writes the first author inverted and the rest in natural order.
+func chicagoAuthors(authors []string) string {
+	names := make([]string, len(authors))
+	for i, author := range authors {
+		if i == 0 {
+			family, given := SplitName(author)
+			names[i] = strings.TrimSpace(family + ", " + given)
+			names[i] = strings.TrimSuffix(names[i], ",")
+		} else {
+			names[i] = author
+		}
+	}
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	case 2:
+		return names[0] + ", and " + names[1]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + ", and " + names[len(names)-1]
+}
+
+func mlaAuthors(authors []string) string {
+	if len(authors) == 0 {
+		return ""
+	}
+	family, given := SplitName(authors[0])
+	first := family
+	if given != "" {
+		first += ", " + given
+	}
+	switch len(authors) {
+	case 1:
+		return first
+	case 2:
+		return first + ", and " + authors[1]
+	}
+	return first + ", et al"
+}
+
+func chicago(e Entry, m markupWriter) string {
+	var parts []string
+	if authors := chicagoAuthors(e.Authors); authors != "" {
+		parts = append(parts, m.text(terminate(authors)))
+	}
+	imprint := strings.Trim(e.Publisher+", "+yearString(e.Year), ", ")
+
+	switch e.Kind {
+	case KindBook:
+		parts = append(parts, m.italic(e.Title)+".")
+		if e.Translator != "" {
+			parts = append(parts, m.text("Translated by "+e.Translator+"."))
+		}
+		if imprint != "" {
+			parts = append(parts, m.text(terminate(imprint)))
+		}
+	case KindChapter:
+		parts = append(parts, quoted(m, e.Title))
+		in := "In " + m.italic(e.Container)
+		if e.Translator != "" {
+			in += m.text(", translated by " + e.Translator)
+		}
+		if pages := e.Pages(); pages != "" {
+			in += m.text(", " + strings.Replace(pages, "-", "–", 1))
+		}
+		parts = append(parts, in+".")
+		if imprint != "" {
+			parts = append(parts, m.text(terminate(imprint)))
+		}
+	case KindArticle:
+		parts = append(parts, quoted(m, e.Title))
+		journal := ""
+		if e.Container != "" {
+			journal = m.italic(e.Container)
+		}
+		if e.Year > 0 {
+			journal = strings.TrimSpace(journal + m.text(" ("+strconv.Itoa(e.Year)+")"))
+		}
+		if pages := e.Pages(); pages != "" {
+			journal += m.text(": " + strings.Replace(pages, "-", "–", 1))
+		}
+		if journal != "" {
+			parts = append(parts, journal+".")
+		}
+	default:
+		parts = append(parts, quoted(m, e.Title))
+		if e.Container != "" {
+			parts = append(parts, m.text(terminate(e.Container)))
+		}
+	}
+
+	if e.URL != "" {
+		parts = append(parts, m.text(terminate(e.URL)))
+	}
+	return strings.Join(parts, " ")
+}
+
+func mla(e Entry, m markupWriter) string {
+	var parts []string
+	if authors := mlaAuthors(e.Authors); authors != "" {
+		parts = append(parts, m.text(terminate(authors)))
+	}
+
+	// MLA lists the remaining "containers" as one comma-separated sentence.
+	var rest []string
+	switch e.Kind {
+	case KindBook:
+		parts = append(parts, m.italic(e.Title)+".")
+		if e.Translator != "" {
+			rest = append(rest, m.text("Translated by "+e.Translator))
+		}
+	default:
+		parts = append(parts, quoted(m, e.Title))
+		if e.Container != "" {
+			rest = append(rest, m.italic(e.Container))
+		}
+		if e.Translator != "" {
+			rest = append(rest, m.text("translated by "+e.Translator))
+		}
+	}
+	if e.Publisher != "" {
+		rest = append(rest, m.text(e.Publisher))
+	}
+	if e.Year > 0 {
+		rest = append(rest, m.text(strconv.Itoa(e.Year)))
+	}
+	if pages := e.Pages(); pages != "" {
+		prefix := "pp. "
+		if !strings.Contains(pages, "-") {
+			prefix = "p. "
+		}
+		rest = append(rest, m.text(prefix+pages))
+	}
+	if e.URL != "" {
+		rest = append(rest, m.text(e.URL))
+	}
+	if len(rest) > 0 {
+		parts = append(parts, strings.Join(rest, ", ")+".")
+	}
+	return strings.Join(parts, " ")
+}
+
+func yearString(year int) string {
+	if year <= 0 {
+		return ""
+	}
+	return strconv.Itoa(year)
+}
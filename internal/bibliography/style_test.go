@@ -0,0 +1,130 @@
+package bibliography
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	book := Entry{
+		Kind:       KindBook,
+		Title:      "Beyond Good and Evil",
+		Authors:    []string{"Friedrich Nietzsche"},
+		Translator: "Walter Kaufmann",
+		Publisher:  "Vintage",
+		Year:       1966,
+	}
+	chapter := Entry{
+		Kind:      KindChapter,
+		Title:     "Apology",
+		Authors:   []string{"Plato"},
+		Container: "Five Dialogues",
+		Publisher: "Hackett",
+		Year:      2002,
+		PageStart: 21,
+		PageEnd:   44,
+	}
+	article := Entry{
+		Kind:      KindArticle,
+		Title:     "Computing Machinery and Intelligence",
+		Authors:   []string{"Alan Turing"},
+		Container: "Mind",
+		Year:      1950,
+		PageStart: 433,
+		PageEnd:   460,
+	}
+
+	tests := []struct {
+		name   string
+		entry  Entry
+		style  string
+		markup string
+		want   string
+	}{
+		{
+			name:   "chicago book",
+			entry:  book,
+			style:  StyleChicago,
+			markup: MarkupMarkdown,
+			want:   "Nietzsche, Friedrich. *Beyond Good and Evil*. Translated by Walter Kaufmann. Vintage, 1966.\n",
+		},
+		{
+			name:   "chicago chapter",
+			entry:  chapter,
+			style:  StyleChicago,
+			markup: MarkupHTML,
+			want:   "<div class=\"csl-bib-body\">\n  <div class=\"csl-entry\">Plato. “Apology.” In <i>Five Dialogues</i>, 21–44. Hackett, 2002.</div>\n</div>\n",
+		},
+		{
+			name:   "chicago article",
+			entry:  article,
+			style:  StyleChicago,
+			markup: MarkupMarkdown,
+			want:   "Turing, Alan. “Computing Machinery and Intelligence.” *Mind* (1950): 433–460.\n",
+		},
+		{
+			name:   "mla book",
+			entry:  book,
+			style:  StyleMLA,
+			markup: MarkupMarkdown,
+			want:   "Nietzsche, Friedrich. *Beyond Good and Evil*. Translated by Walter Kaufmann, Vintage, 1966.\n",
+		},
+		{
+			name:   "mla article",
+			entry:  article,
+			style:  StyleMLA,
+			markup: MarkupMarkdown,
+			want:   "Turing, Alan. “Computing Machinery and Intelligence.” *Mind*, 1950, pp. 433-460.\n",
+		},
+		{
+			name:   "mla single page and several authors",
+			entry:  Entry{Kind: KindChapter, Title: "Preface", Authors: []string{"Ada Lovelace", "Ben Franklin", "Cy Young"}, Container: "Notes", PageStart: 5},
+			style:  StyleMLA,
+			markup: MarkupMarkdown,
+			want:   "Lovelace, Ada, et al. “Preface.” *Notes*, p. 5.\n",
+		},
+		{
+			name:   "mla end page only",
+			entry:  Entry{Kind: KindArticle, Title: "Letter", Container: "Mind", PageEnd: 112},
+			style:  StyleMLA,
+			markup: MarkupMarkdown,
+			want:   "“Letter.” *Mind*, p. 112.\n",
+		},
+		{
+			name:   "markdown is escaped",
+			entry:  Entry{Kind: KindBook, Title: "Snake_case [and] *stars*"},
+			style:  StyleChicago,
+			markup: MarkupMarkdown,
+			want:   "*Snake\\_case \\[and\\] \\*stars\\**.\n",
+		},
+		{
+			name:   "html is escaped",
+			entry:  Entry{Kind: KindWebPage, Title: "Q&A", URL: "https://example.com/?a=1&b=2"},
+			style:  StyleChicago,
+			markup: MarkupHTML,
+			want:   "<div class=\"csl-bib-body\">\n  <div class=\"csl-entry\">“Q&amp;A.” https://example.com/?a=1&amp;b=2.</div>\n</div>\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format([]Entry{tt.entry}, tt.style, tt.markup)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatRejectsUnknownOptions(t *testing.T) {
+	tests := []struct {
+		style, markup string
+	}{
+		{"apa", MarkupHTML},
+		{StyleChicago, "latex"},
+	}
+	for _, tt := range tests {
+		if _, err := Format(nil, tt.style, tt.markup); err == nil {
+			t.Errorf("Format(%q, %q) succeeded, want an error", tt.style, tt.markup)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Package dedupe finds authors and books that are probably the same record
+// entered more than once, e.g. "Nietzsche", "F. Nietzsche" and "Friedrich
+// Nietzsche".
+package dedupe
+
+import (
+	"hippias-fiber/internal/importer"
+	"hippias-fiber/internal/models"
+	"sort"
+	"strings"
+)
+
+// Similarity scores at or above which records are proposed for merging.
+const (
+	DefaultMinScore = 0.85
+	// Jaro-Winkler similarity needed between folded names or titles to count
+	// as a spelling variant. Titles need more, since "Critique of Pure Reason"
+	// and "Critique of Practical Reason" are already 0.92 alike.
+	nameSpellingThreshold  = 0.92
+	titleSpellingThreshold = 0.96
+)
+
+// Candidate proposes merging Merge into Keep. Keep is the record with more
+// books or references, so that fewer rows need re-pointing.
+type Candidate struct {
+	KeepID    int     `json:"keep_id"`
+	KeepName  string  `json:"keep_name"`
+	MergeID   int     `json:"merge_id"`
+	MergeName string  `json:"merge_name"`
+	Score     float64 `json:"score"`
+	Reason    string  `json:"reason"`
+}
+
+// Authors proposes merges between authors scoring at least minScore. bookCounts
+// maps author IDs to their number of books and decides which record survives.
+func Authors(authors []models.Author, bookCounts map[int]int, minScore float64) []Candidate {
+	var candidates []Candidate
+	for i := range authors {
+		for j := i + 1; j < len(authors); j++ {
+			score, reason := NameScore(authors[i].Name, authors[j].Name)
+			if score < minScore {
+				continue
+			}
+			keep, merge := authors[i], authors[j]
+			if survivorFirst(bookCounts[merge.ID], bookCounts[keep.ID], merge.Name, keep.Name, merge.ID, keep.ID) {
+				keep, merge = merge, keep
+			}
+			candidates = append(candidates, Candidate{
+				KeepID: keep.ID, KeepName: keep.Name,
+				MergeID: merge.ID, MergeName: merge.Name,
+				Score: score, Reason: reason,
+			})
+		}
+	}
+	sortCandidates(candidates)
+	return candidates
+}
+
+// Books proposes merges between books by the same author (or with no author
+// recorded on one side) whose titles score at least minScore. refCounts maps
+// book IDs to how many course_books rows and readings use them.
+func Books(books []models.Book, refCounts map[int]int, minScore float64) []Candidate {
+	var candidates []Candidate
+	for i := range books {
+		for j := i + 1; j < len(books); j++ {
+			a, b := books[i], books[j]
+			if !sameAuthor(a, b) {
+				continue
+			}
+			score, reason := TitleScore(a.Title, b.Title)
+			if score < minScore {
+				continue
+			}
+			keep, merge := a, b
+			if survivorFirst(refCounts[merge.ID], refCounts[keep.ID], merge.Title, keep.Title, merge.ID, keep.ID) {
+				keep, merge = merge, keep
+			}
+			candidates = append(candidates, Candidate{
+				KeepID: keep.ID, KeepName: keep.Title,
+				MergeID: merge.ID, MergeName: merge.Title,
+				Score: score, Reason: reason,
+			})
+		}
+	}
+	sortCandidates(candidates)
+	return candidates
+}
+
+func sameAuthor(a, b models.Book) bool {
+	if a.AuthorID != 0 && a.AuthorID == b.AuthorID {
+		return true
+	}
+	nameA, nameB := importer.NormalizeName(a.Author), importer.NormalizeName(b.Author)
+	if nameA == "" || nameB == "" {
+		return true
+	}
+	score, _ := NameScore(a.Author, b.Author)
+	return score >= DefaultMinScore
+}
+
+// survivorFirst reports whether the first record should survive a merge with
+// the second: more references wins, then the fuller name, then the older ID.
+func survivorFirst(refs1, refs2 int, name1, name2 string, id1, id2 int) bool {
+	if refs1 != refs2 {
+		return refs1 > refs2
+	}
+	if len(name1) != len(name2) {
+		return len(name1) > len(name2)
+	}
+	return id1 < id2
+}
+
+func sortCandidates(candidates []Candidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].KeepID < candidates[j].KeepID
+	})
+}
+
+// NameScore rates how likely two author names refer to the same person.
+func NameScore(a, b string) (float64, string) {
+	foldedA, foldedB := importer.NormalizeName(a), importer.NormalizeName(b)
+	if foldedA == "" || foldedB == "" {
+		return 0, ""
+	}
+	if foldedA == foldedB {
+		return 1, "same name"
+	}
+
+	wordsA, wordsB := strings.Fields(foldedA), strings.Fields(foldedB)
+	familyA, familyB := wordsA[len(wordsA)-1], wordsB[len(wordsB)-1]
+	if familyA == familyB {
+		givenA, givenB := wordsA[:len(wordsA)-1], wordsB[:len(wordsB)-1]
+		switch {
+		case len(givenA) == 0 || len(givenB) == 0:
+			return 0.85, "same family name, one has no given name"
+		case givenNamesCompatible(givenA, givenB):
+			return 0.95, "same family name, matching given names or initials"
+		}
+	}
+
+	if similarity := jaroWinkler(foldedA, foldedB); similarity >= nameSpellingThreshold {
+		return similarity * 0.95, "similar spelling"
+	}
+	return 0, ""
+}
+
+// givenNamesCompatible reports whether two lists of given names agree,
+// treating a single letter as an initial: "f" matches "friedrich", and
+// "friedrich" matches "friedrich wilhelm".
+func givenNamesCompatible(a, b []string) bool {
+	for i := 0; i < min(len(a), len(b)); i++ {
+		x, y := a[i], b[i]
+		if x == y {
+			continue
+		}
+		if (len(x) == 1 && strings.HasPrefix(y, x)) || (len(y) == 1 && strings.HasPrefix(x, y)) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// TitleScore rates how likely two titles name the same work. A leading
+// article and a subtitle are ignored, so "The Republic" matches "Republic" and
+// "Being and Time: A Translation" matches "Being and Time".
+func TitleScore(a, b string) (float64, string) {
+	foldedA, foldedB := foldTitle(a), foldTitle(b)
+	if foldedA == "" || foldedB == "" {
+		return 0, ""
+	}
+	if foldedA == foldedB {
+		return 1, "same title"
+	}
+	mainA, mainB := mainTitle(a), mainTitle(b)
+	if mainA != "" && mainA == mainB {
+		return 0.9, "same title ignoring articles and subtitles"
+	}
+	if similarity := jaroWinkler(foldedA, foldedB); similarity >= titleSpellingThreshold {
+		return similarity * 0.95, "similar spelling"
+	}
+	return 0, ""
+}
+
+func foldTitle(title string) string {
+	return importer.NormalizeTitle(strings.ReplaceAll(title, "&", " and "))
+}
+
+func mainTitle(title string) string {
+	if i := strings.IndexAny(title, ":;("); i > 0 {
+		title = title[:i]
+	}
+	folded := foldTitle(title)
+	for _, article := range []string{"the ", "a ", "an "} {
+		folded = strings.TrimPrefix(folded, article)
+	}
+	return folded
+}
@@ -0,0 +1,140 @@
+package dedupe
+
+import (
+	"hippias-fiber/internal/models"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNameScore(t *testing.T) {
+	tests := []struct {
+		a, b       string
+		wantMin    float64
+		wantMax    float64
+		wantReason string
+	}{
+		{"Friedrich Nietzsche", "Nietzsche, Friedrich", 1, 1, "same name"},
+		{"Émile Durkheim", "Emile Durkheim", 1, 1, "same name"},
+		{"F. Nietzsche", "Friedrich Nietzsche", 0.95, 0.95, "same family name, matching given names or initials"},
+		{"Friedrich Wilhelm Nietzsche", "Friedrich Nietzsche", 0.95, 0.95, "same family name, matching given names or initials"},
+		{"Nietzsche", "Friedrich Nietzsche", 0.85, 0.85, "same family name, one has no given name"},
+		{"Friedrich Nietzsche", "Friedrich Nietzche", 0.9, 0.95, "similar spelling"},
+		{"Karl Marx", "Groucho Marx", 0, 0, ""},
+		{"Plato", "Aristotle", 0, 0, ""},
+		{"", "Plato", 0, 0, ""},
+	}
+	for _, tt := range tests {
+		score, reason := NameScore(tt.a, tt.b)
+		if score < tt.wantMin || score > tt.wantMax || reason != tt.wantReason {
+			t.Errorf("NameScore(%q, %q) = %v, %q, want %v-%v, %q", tt.a, tt.b, score, reason, tt.wantMin, tt.wantMax, tt.wantReason)
+		}
+	}
+}
+
+func TestTitleScore(t *testing.T) {
+	tests := []struct {
+		a, b       string
+		wantMin    float64
+		wantMax    float64
+		wantReason string
+	}{
+		{"Fear & Trembling", "Fear and Trembling", 1, 1, "same title"},
+		{"The Republic", "Republic", 0.9, 0.9, "same title ignoring articles and subtitles"},
+		{"Being and Time: A Translation", "Being and Time", 0.9, 0.9, "same title ignoring articles and subtitles"},
+		{"Phenomenology of Spirit", "Phenomenology of Sprit", 0.9, 0.95, "similar spelling"},
+		{"Thus Spoke Zarathustra", "Thus Spake Zarathustra", 0, 0, ""},
+		{"Critique of Pure Reason", "Critique of Practical Reason", 0, 0, ""},
+		{"Republic", "", 0, 0, ""},
+	}
+	for _, tt := range tests {
+		score, reason := TitleScore(tt.a, tt.b)
+		if score < tt.wantMin || score > tt.wantMax || reason != tt.wantReason {
+			t.Errorf("TitleScore(%q, %q) = %v, %q, want %v-%v, %q", tt.a, tt.b, score, reason, tt.wantMin, tt.wantMax, tt.wantReason)
+		}
+	}
+}
+
+func TestJaroWinkler(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 1},
+		{"plato", "", 0},
+		{"plato", "plato", 1},
+		{"abc", "xyz", 0},
+		{"martha", "marhta", 0.9611},
+		{"dwayne", "duane", 0.84},
+		{"dixon", "dicksonx", 0.8133},
+	}
+	for _, tt := range tests {
+		got := jaroWinkler(tt.a, tt.b)
+		if math.Abs(got-tt.want) > 0.0001 {
+			t.Errorf("jaroWinkler(%q, %q) = %.4f, want %.4f", tt.a, tt.b, got, tt.want)
+		}
+		if reverse := jaroWinkler(tt.b, tt.a); math.Abs(reverse-got) > 1e-9 {
+			t.Errorf("jaroWinkler(%q, %q) = %.4f, not symmetric with %.4f", tt.b, tt.a, reverse, got)
+		}
+	}
+}
+
+func TestAuthors(t *testing.T) {
+	authors := []models.Author{
+		{ID: 1, Name: "Nietzsche"},
+		{ID: 2, Name: "Friedrich Nietzsche"},
+		{ID: 3, Name: "Plato"},
+		{ID: 4, Name: "F. Nietzsche"},
+	}
+	tests := []struct {
+		name       string
+		bookCounts map[int]int
+		minScore   float64
+		want       []Candidate
+	}{
+		{
+			name:     "fuller name survives when book counts tie",
+			minScore: 0.9,
+			want: []Candidate{
+				{KeepID: 2, KeepName: "Friedrich Nietzsche", MergeID: 4, MergeName: "F. Nietzsche", Score: 0.95, Reason: "same family name, matching given names or initials"},
+			},
+		},
+		{
+			name:       "record with more books survives",
+			bookCounts: map[int]int{1: 3, 2: 1},
+			minScore:   DefaultMinScore,
+			want: []Candidate{
+				{KeepID: 2, KeepName: "Friedrich Nietzsche", MergeID: 4, MergeName: "F. Nietzsche", Score: 0.95, Reason: "same family name, matching given names or initials"},
+				{KeepID: 1, KeepName: "Nietzsche", MergeID: 2, MergeName: "Friedrich Nietzsche", Score: 0.85, Reason: "same family name, one has no given name"},
+				{KeepID: 1, KeepName: "Nietzsche", MergeID: 4, MergeName: "F. Nietzsche", Score: 0.85, Reason: "same family name, one has no given name"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Authors(authors, tt.bookCounts, tt.minScore)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Authors() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBooks(t *testing.T) {
+	books := []models.Book{
+		{ID: 1, Title: "The Republic", AuthorID: 7, Author: "Plato"},
+		{ID: 2, Title: "Republic", Author: "Plato"},
+		{ID: 3, Title: "Republic", Author: "Cicero"},
+		{ID: 4, Title: "Republic"},
+	}
+	got := Books(books, map[int]int{2: 4}, DefaultMinScore)
+	want := []Candidate{
+		{KeepID: 2, KeepName: "Republic", MergeID: 4, MergeName: "Republic", Score: 1, Reason: "same title"},
+		{KeepID: 3, KeepName: "Republic", MergeID: 4, MergeName: "Republic", Score: 1, Reason: "same title"},
+		{KeepID: 1, KeepName: "The Republic", MergeID: 4, MergeName: "Republic", Score: 0.9, Reason: "same title ignoring articles and subtitles"},
+		{KeepID: 2, KeepName: "Republic", MergeID: 1, MergeName: "The Republic", Score: 0.9, Reason: "same title ignoring articles and subtitles"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Books() =\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -0,0 +1,58 @@
+package dedupe
+
+// jaroWinkler returns the Jaro-Winkler similarity of a and b, from 0 (nothing
+// in common) to 1 (identical). It favours strings sharing a prefix, which
+// suits names and titles that differ in their endings.
+func jaroWinkler(a, b string) float64 {
+	r1, r2 := []rune(a), []rune(b)
+	if len(r1) == 0 && len(r2) == 0 {
+		return 1
+	}
+	if len(r1) == 0 || len(r2) == 0 {
+		return 0
+	}
+
+	window := max(len(r1), len(r2))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+	matched1 := make([]bool, len(r1))
+	matched2 := make([]bool, len(r2))
+	matches := 0
+	for i := range r1 {
+		for j := max(0, i-window); j < min(len(r2), i+window+1); j++ {
+			if !matched2[j] && r1[i] == r2[j] {
+				matched1[i], matched2[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range r1 {
+		if !matched1[i] {
+			continue
+		}
+		for !matched2[j] {
+			j++
+		}
+		if r1[i] != r2[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(r1)) + m/float64(len(r2)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(r1), len(r2)) && r1[prefix] == r2[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
@@ -0,0 +1,158 @@
+// Package feed writes Atom 1.0 and RSS 2.0 syndication feeds.
+package feed
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Feed is the content common to both formats.
+type Feed struct {
+	Title       string
+	Description string
+	// Page the feed belongs to
+	Link string
+	// URL of the feed itself
+	Self    string
+	Updated time.Time
+	Items   []Item
+}
+
+type Item struct {
+	// Permanent, unique identifier, usually the item's URL
+	ID         string
+	Title      string
+	Link       string
+	AuthorName string
+	Published  time.Time
+	Updated    time.Time
+	// Rendered HTML body
+	ContentHTML string
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Link      atomLink    `xml:"link"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Author    *atomAuthor `xml:"author,omitempty"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// Atom renders the feed as an Atom 1.0 document.
+func Atom(f Feed) ([]byte, error) {
+	doc := atomFeed{
+		Title:   f.Title,
+		ID:      f.Self,
+		Updated: f.Updated.UTC().Format(time.RFC3339),
+		Links:   []atomLink{{Href: f.Link}, {Href: f.Self, Rel: "self"}},
+		Entries: []atomEntry{},
+	}
+	for _, item := range f.Items {
+		entry := atomEntry{
+			Title:     item.Title,
+			ID:        item.ID,
+			Link:      atomLink{Href: item.Link},
+			Published: item.Published.UTC().Format(time.RFC3339),
+			Updated:   item.Updated.UTC().Format(time.RFC3339),
+			Content:   atomContent{Type: "html", Body: item.ContentHTML},
+		}
+		if item.AuthorName != "" {
+			entry.Author = &atomAuthor{Name: item.AuthorName}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return marshal(doc)
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Self          rssSelf   `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssSelf struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS renders the feed as an RSS 2.0 document.
+func RSS(f Feed) ([]byte, error) {
+	doc := rssDocument{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.Link,
+			Description:   f.Description,
+			LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+			Self:          rssSelf{Href: f.Self, Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+	for _, item := range f.Items {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        rssGUID{IsPermaLink: item.ID == item.Link, Value: item.ID},
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+			Description: item.ContentHTML,
+		})
+	}
+	return marshal(doc)
+}
+
+func marshal(doc interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,303 @@
+// Package importer creates and updates books and authors from offline
+// bibliographic data such as Open Library dumps and ISBN metadata files.
+package importer
+
+import (
+	"fmt"
+	"hippias-fiber/internal/models"
+	"hippias-fiber/internal/rpc"
+	"io"
+	"strconv"
+
+	supa "github.com/nedpals/supabase-go"
+)
+
+const (
+	SourceOpenLibrary = "openlibrary"
+	SourceISBN        = "isbn"
+)
+
+// Parse reads records from r in the given source format. Open Library dumps
+// are read twice, so r must be seekable.
+func Parse(source string, r io.ReadSeeker) ([]Record, error) {
+	switch source {
+	case SourceOpenLibrary:
+		return ParseOpenLibrary(r)
+	case SourceISBN:
+		return ParseISBNFile(r)
+	}
+	return nil, fmt.Errorf("unknown import source %q: expected %s or %s", source, SourceOpenLibrary, SourceISBN)
+}
+
+// Plan is the diff an import would apply. It is returned as-is for dry runs.
+type Plan struct {
+	NewAuthors   []models.Author `json:"new_authors"`
+	NewBooks     []models.Book   `json:"new_books"`
+	NewEditions  []NewEdition    `json:"new_editions"`
+	UpdatedBooks []BookUpdate    `json:"updated_books"`
+	Unchanged    int             `json:"unchanged"`
+	// Records that could not be imported, with the reason
+	Skipped []string `json:"skipped"`
+}
+
+// NewEdition is an edition to create. BookID is zero when the book is itself
+// new in the plan.
+type NewEdition struct {
+	models.Edition
+	BookTitle string `json:"book_title"`
+	bookKey   string
+}
+
+// BookUpdate fills in empty fields of an existing book. authorKey names an
+// author new in the plan, whose id is only known once it has been created.
+type BookUpdate struct {
+	BookID    int                    `json:"book_id"`
+	Title     string                 `json:"title"`
+	Changes   map[string]FieldChange `json:"changes"`
+	book      models.Book
+	authorKey string
+}
+
+type FieldChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Result reports what Apply wrote.
+type Result struct {
+	AuthorsCreated  int `json:"authors_created"`
+	BooksCreated    int `json:"books_created"`
+	EditionsCreated int `json:"editions_created"`
+	BooksUpdated    int `json:"books_updated"`
+}
+
+type Importer struct {
+	sb *supa.Client
+}
+
+func New(sb *supa.Client) *Importer {
+	return &Importer{sb: sb}
+}
+
+// Plan compares records with the books, authors and editions already stored.
+// Authors are matched by normalized name, books by normalized title and
+// author, and editions by ISBN. Existing books only have empty fields filled
+// in; curated data is never overwritten.
+func (im *Importer) Plan(records []Record) (*Plan, error) {
+	var authors []models.Author
+	if err := im.sb.DB.From("authors").Select("*").Execute(&authors); err != nil {
+		return nil, fmt.Errorf("loading authors: %w", err)
+	}
+	var books []models.Book
+	if err := im.sb.DB.From("books").Select("*").Execute(&books); err != nil {
+		return nil, fmt.Errorf("loading books: %w", err)
+	}
+	var editions []models.Edition
+	if err := im.sb.DB.From("editions").Select("id,book_id,isbn").Execute(&editions); err != nil {
+		return nil, fmt.Errorf("loading editions: %w", err)
+	}
+	return BuildPlan(records, authors, books, editions), nil
+}
+
+// BuildPlan is the comparison behind Importer.Plan.
+func BuildPlan(records []Record, authors []models.Author, books []models.Book, editions []models.Edition) *Plan {
+	plan := &Plan{
+		NewAuthors:   []models.Author{},
+		NewBooks:     []models.Book{},
+		NewEditions:  []NewEdition{},
+		UpdatedBooks: []BookUpdate{},
+		Skipped:      []string{},
+	}
+
+	authorByName := map[string]models.Author{}
+	for _, author := range authors {
+		authorByName[NormalizeName(author.Name)] = author
+	}
+	type bookKey struct{ title, author string }
+	bookIndex := map[bookKey]int{}
+	for i, book := range books {
+		bookIndex[bookKey{NormalizeTitle(book.Title), NormalizeName(book.Author)}] = i
+	}
+	updateIndex := map[int]int{}
+	knownISBNs := map[string]bool{}
+	for _, edition := range editions {
+		if isbn := NormalizeISBN(edition.ISBN); isbn != "" {
+			knownISBNs[isbn] = true
+		}
+	}
+
+	// addEditions plans the record's editions not already in the catalog.
+	// Editions without an ISBN can only be told apart for books new to the
+	// catalog, so they are only added there.
+	addEditions := func(record Record, book models.Book, key bookKey) {
+		for _, edition := range record.AllEditions() {
+			isbn := NormalizeISBN(edition.ISBN)
+			if isbn == "" && book.ID != 0 {
+				continue
+			}
+			if isbn != "" {
+				if knownISBNs[isbn] {
+					continue
+				}
+				knownISBNs[isbn] = true
+			}
+			plan.NewEditions = append(plan.NewEditions, NewEdition{
+				Edition: models.Edition{
+					BookID:    book.ID,
+					Title:     record.Title,
+					Publisher: edition.Publisher,
+					Year:      PublishYear(edition.PublishDate),
+					ISBN:      edition.ISBN,
+					Language:  edition.Language,
+					PageCount: edition.PageCount,
+				},
+				BookTitle: book.Title,
+				bookKey:   key.title + "|" + key.author,
+			})
+		}
+	}
+
+	for i, record := range records {
+		if NormalizeTitle(record.Title) == "" {
+			plan.Skipped = append(plan.Skipped, fmt.Sprintf("record %d: missing title", i+1))
+			continue
+		}
+
+		authorName := ""
+		if len(record.Authors) > 0 {
+			authorName = record.Authors[0]
+			key := NormalizeName(authorName)
+			if existing, ok := authorByName[key]; ok {
+				authorName = existing.Name
+			} else if key != "" {
+				author := models.Author{Name: authorName}
+				plan.NewAuthors = append(plan.NewAuthors, author)
+				authorByName[key] = author
+			}
+		}
+
+		key := bookKey{NormalizeTitle(record.Title), NormalizeName(authorName)}
+		idx, exists := bookIndex[key]
+		if !exists {
+			book := models.Book{
+				Title:       record.Title,
+				Author:      authorName,
+				AuthorID:    authorByName[NormalizeName(authorName)].ID,
+				Description: record.Description,
+			}
+			plan.NewBooks = append(plan.NewBooks, book)
+			books = append(books, book)
+			bookIndex[key] = len(books) - 1
+			addEditions(record, book, key)
+			continue
+		}
+
+		book := books[idx]
+		addEditions(record, book, key)
+		changes := map[string]FieldChange{}
+		if book.Description == "" && record.Description != "" {
+			changes["description"] = FieldChange{From: book.Description, To: record.Description}
+			book.Description = record.Description
+		}
+		authorKey := ""
+		if book.AuthorID == 0 && NormalizeName(authorName) != "" {
+			if author := authorByName[NormalizeName(authorName)]; author.ID != 0 {
+				book.AuthorID = author.ID
+				changes["authorId"] = FieldChange{From: "", To: strconv.Itoa(book.AuthorID)}
+			} else if u, ok := updateIndex[book.ID]; !ok || plan.UpdatedBooks[u].authorKey == "" {
+				// The author is created by this plan; import_catalog links it
+				authorKey = NormalizeName(authorName)
+				changes["authorId"] = FieldChange{From: "", To: authorName}
+			}
+		}
+		if len(changes) == 0 || book.ID == 0 {
+			plan.Unchanged++
+			continue
+		}
+		books[idx] = book
+
+		if u, ok := updateIndex[book.ID]; ok {
+			for field, change := range changes {
+				plan.UpdatedBooks[u].Changes[field] = change
+			}
+			plan.UpdatedBooks[u].book = book
+			if authorKey != "" {
+				plan.UpdatedBooks[u].authorKey = authorKey
+			}
+			continue
+		}
+		updateIndex[book.ID] = len(plan.UpdatedBooks)
+		plan.UpdatedBooks = append(plan.UpdatedBooks, BookUpdate{
+			BookID:    book.ID,
+			Title:     book.Title,
+			Changes:   changes,
+			book:      book,
+			authorKey: authorKey,
+		})
+	}
+	return plan
+}
+
+// Apply writes a plan in one call to the import_catalog function in
+// sql/import_catalog.sql, so either all of it is written or none of it. New
+// books and editions refer to the new authors and books they belong to by key.
+func (im *Importer) Apply(plan *Plan) (*Result, error) {
+	type newAuthor struct {
+		Key  string `json:"key"`
+		Name string `json:"name"`
+	}
+	type newBook struct {
+		Key         string `json:"key"`
+		Title       string `json:"title"`
+		Author      string `json:"author"`
+		Description string `json:"description"`
+		AuthorID    int    `json:"author_id"`
+		AuthorKey   string `json:"author_key"`
+	}
+	type newEdition struct {
+		models.Edition
+		BookKey string `json:"book_key"`
+	}
+	type bookUpdate struct {
+		ID          int    `json:"id"`
+		Description string `json:"description"`
+		AuthorID    int    `json:"author_id"`
+		AuthorKey   string `json:"author_key"`
+	}
+
+	authors := make([]newAuthor, len(plan.NewAuthors))
+	for i, author := range plan.NewAuthors {
+		authors[i] = newAuthor{Key: NormalizeName(author.Name), Name: author.Name}
+	}
+	books := make([]newBook, len(plan.NewBooks))
+	for i, book := range plan.NewBooks {
+		books[i] = newBook{
+			Key:         NormalizeTitle(book.Title) + "|" + NormalizeName(book.Author),
+			Title:       book.Title,
+			Author:      book.Author,
+			Description: book.Description,
+			AuthorID:    book.AuthorID,
+			AuthorKey:   NormalizeName(book.Author),
+		}
+	}
+	editions := make([]newEdition, len(plan.NewEditions))
+	for i, edition := range plan.NewEditions {
+		editions[i] = newEdition{Edition: edition.Edition, BookKey: edition.bookKey}
+	}
+	updates := make([]bookUpdate, len(plan.UpdatedBooks))
+	for i, update := range plan.UpdatedBooks {
+		updates[i] = bookUpdate{
+			ID:          update.BookID,
+			Description: update.book.Description,
+			AuthorID:    update.book.AuthorID,
+			AuthorKey:   update.authorKey,
+		}
+	}
+
+	result := &Result{}
+	params := map[string]interface{}{"authors": authors, "books": books, "editions": editions, "updates": updates}
+	if err := rpc.Call(im.sb, "import_catalog", params, result); err != nil {
+		return nil, fmt.Errorf("applying import: %w", err)
+	}
+	return result, nil
+}
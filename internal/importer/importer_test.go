@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"hippias-fiber/internal/models"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestBuildPlan(t *testing.T) {
+	authors := []models.Author{{ID: 1, Name: "Friedrich Nietzsche"}}
+	books := []models.Book{
+		{ID: 10, Title: "The Gay Science", Author: "Friedrich Nietzsche", AuthorID: 1, Description: "Curated"},
+		{ID: 11, Title: "Beyond Good and Evil", Author: "Friedrich Nietzsche"},
+		{ID: 12, Title: "The Sickness unto Death", Author: "Søren Kierkegaard"},
+	}
+	editions := []models.Edition{{ID: 100, BookID: 10, ISBN: "978-0-394-71985-1"}}
+
+	tests := []struct {
+		name          string
+		records       []Record
+		wantAuthors   []string
+		wantBooks     []string
+		wantEditions  []string
+		wantUpdated   map[int][]string
+		wantUnchanged int
+		wantSkipped   int
+	}{
+		{
+			name:    "missing title is skipped",
+			records: []Record{{Title: "  ", Authors: []string{"Nobody"}}},
+			// The author is only planned for records with a title
+			wantSkipped: 1,
+		},
+		{
+			name:          "existing book matched across name order",
+			records:       []Record{{Title: "the gay science", Authors: []string{"Nietzsche, Friedrich"}, Description: "Imported"}},
+			wantUnchanged: 1,
+		},
+		{
+			name:        "empty fields of existing books are filled in",
+			records:     []Record{{Title: "Beyond Good and Evil", Authors: []string{"Friedrich Nietzsche"}, Description: "Prelude"}},
+			wantUpdated: map[int][]string{11: {"authorId", "description"}},
+		},
+		{
+			name: "existing book linked to an author new in the plan",
+			records: []Record{
+				{Title: "The Sickness unto Death", Authors: []string{"Søren Kierkegaard"}},
+				{Title: "The Sickness Unto Death", Authors: []string{"Kierkegaard, Søren"}},
+			},
+			wantAuthors:   []string{"Søren Kierkegaard"},
+			wantUpdated:   map[int][]string{12: {"authorId"}},
+			wantUnchanged: 1,
+		},
+		{
+			name: "new author and book once each",
+			records: []Record{
+				{Title: "Either/Or", Authors: []string{"Søren Kierkegaard"}},
+				{Title: "Either / Or", Authors: []string{"Kierkegaard, Søren"}},
+			},
+			wantAuthors:   []string{"Søren Kierkegaard"},
+			wantBooks:     []string{"Either/Or"},
+			wantUnchanged: 1,
+		},
+		{
+			name: "editions are kept and known ISBNs skipped",
+			records: []Record{
+				{Title: "The Gay Science", Authors: []string{"Friedrich Nietzsche"}, Editions: []EditionRecord{
+					{ISBN: "978 0394 719851", Publisher: "Vintage"},
+					{ISBN: "978-0-521-63645-0", Publisher: "Cambridge", PublishDate: "2001", PageCount: 344},
+					{Publisher: "No ISBN"},
+				}},
+				{Title: "Fear and Trembling", Authors: []string{"Søren Kierkegaard"}, ISBN: "9780140444490", Publisher: "Penguin", PageCount: 160},
+				{Title: "Fear and Trembling", Authors: []string{"Søren Kierkegaard"}, ISBN: "978-0-14-044449-0"},
+			},
+			wantAuthors:   []string{"Søren Kierkegaard"},
+			wantBooks:     []string{"Fear and Trembling"},
+			wantEditions:  []string{"9780521636450", "9780140444490"},
+			wantUnchanged: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// BuildPlan appends to books, so give each case its own copy
+			plan := BuildPlan(tt.records, authors, append([]models.Book(nil), books...), editions)
+
+			var gotAuthors, gotBooks, gotEditions []string
+			for _, author := range plan.NewAuthors {
+				gotAuthors = append(gotAuthors, author.Name)
+			}
+			for _, book := range plan.NewBooks {
+				gotBooks = append(gotBooks, book.Title)
+			}
+			for _, edition := range plan.NewEditions {
+				gotEditions = append(gotEditions, NormalizeISBN(edition.ISBN))
+			}
+			if !reflect.DeepEqual(gotAuthors, tt.wantAuthors) {
+				t.Errorf("new authors = %v, want %v", gotAuthors, tt.wantAuthors)
+			}
+			if !reflect.DeepEqual(gotBooks, tt.wantBooks) {
+				t.Errorf("new books = %v, want %v", gotBooks, tt.wantBooks)
+			}
+			if !reflect.DeepEqual(gotEditions, tt.wantEditions) {
+				t.Errorf("new editions = %v, want %v", gotEditions, tt.wantEditions)
+			}
+
+			gotUpdated := map[int][]string{}
+			for _, update := range plan.UpdatedBooks {
+				for field := range update.Changes {
+					gotUpdated[update.BookID] = append(gotUpdated[update.BookID], field)
+				}
+			}
+			for id := range gotUpdated {
+				sort.Strings(gotUpdated[id])
+			}
+			if len(gotUpdated) > 0 || len(tt.wantUpdated) > 0 {
+				if !reflect.DeepEqual(gotUpdated, tt.wantUpdated) {
+					t.Errorf("updated books = %v, want %v", gotUpdated, tt.wantUpdated)
+				}
+			}
+			if plan.Unchanged != tt.wantUnchanged {
+				t.Errorf("unchanged = %d, want %d", plan.Unchanged, tt.wantUnchanged)
+			}
+			if len(plan.Skipped) != tt.wantSkipped {
+				t.Errorf("skipped = %v, want %d", plan.Skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
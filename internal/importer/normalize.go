@@ -0,0 +1,77 @@
+package importer
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeName folds an author name to a comparison key: "Nietzsche,
+// Friedrich" and "friedrich  nietzsche" both become "friedrich nietzsche".
+func NormalizeName(name string) string {
+	if last, first, ok := strings.Cut(name, ","); ok && !strings.Contains(first, ",") {
+		name = first + " " + last
+	}
+	return fold(name)
+}
+
+// NormalizeTitle folds a title to a comparison key.
+func NormalizeTitle(title string) string {
+	return fold(title)
+}
+
+// fold lowercases, strips diacritics and punctuation, and collapses whitespace.
+func fold(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// NormalizeISBN strips the hyphens and spaces from an ISBN and upper-cases a
+// trailing check digit X. It returns "" for anything that is not an ISBN-10
+// or ISBN-13.
+func NormalizeISBN(isbn string) string {
+	var b strings.Builder
+	for _, r := range isbn {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == 'x' || r == 'X':
+			b.WriteByte('X')
+		case r == '-' || r == ' ':
+		default:
+			return ""
+		}
+	}
+	s := b.String()
+	if len(s) == 13 && !strings.Contains(s, "X") || len(s) == 10 && !strings.Contains(s[:9], "X") {
+		return s
+	}
+	return ""
+}
+
+var yearPattern = regexp.MustCompile(`\b\d{4}\b`)
+
+// PublishYear reads the year from a free-form publish date such as "1998",
+// "March 1998" or "1998-03-04", returning 0 when there is none.
+func PublishYear(date string) int {
+	year, _ := strconv.Atoi(yearPattern.FindString(date))
+	return year
+}
@@ -0,0 +1,83 @@
+package importer
+
+import "testing"
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Friedrich Nietzsche", "friedrich nietzsche"},
+		{"Nietzsche, Friedrich", "friedrich nietzsche"},
+		{"  friedrich   NIETZSCHE ", "friedrich nietzsche"},
+		{"Søren Kierkegaard", "søren kierkegaard"},
+		{"Simone de Beauvoir", "simone de beauvoir"},
+		{"Émile Durkheim", "emile durkheim"},
+		{"Beauvoir, Simone de", "simone de beauvoir"},
+		{"Smith, John, Jr.", "smith john jr"},
+		{"G.W.F. Hegel", "g w f hegel"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeName(tt.name); got != tt.want {
+			t.Errorf("NormalizeName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Thus Spoke Zarathustra", "thus spoke zarathustra"},
+		{"Thus Spoke Zarathustra!", "thus spoke zarathustra"},
+		{"Being and Time: A Translation", "being and time a translation"},
+		{"L'Être et le néant", "l etre et le neant"},
+		{"Phenomenology, of Spirit", "phenomenology of spirit"},
+		{"   ", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeTitle(tt.title); got != tt.want {
+			t.Errorf("NormalizeTitle(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeISBN(t *testing.T) {
+	tests := []struct {
+		isbn string
+		want string
+	}{
+		{"978-0-14-044118-5", "9780140441185"},
+		{"0 14 044118 x", "014044118X"},
+		{"014044118X", "014044118X"},
+		{"01404X1185", ""},
+		{"12345", ""},
+		{"ISBN 9780140441185", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeISBN(tt.isbn); got != tt.want {
+			t.Errorf("NormalizeISBN(%q) = %q, want %q", tt.isbn, got, tt.want)
+		}
+	}
+}
+
+func TestPublishYear(t *testing.T) {
+	tests := []struct {
+		date string
+		want int
+	}{
+		{"1998", 1998},
+		{"March 3, 1998", 1998},
+		{"1998-03-04", 1998},
+		{"c. 1880s", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := PublishYear(tt.date); got != tt.want {
+			t.Errorf("PublishYear(%q) = %d, want %d", tt.date, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Record is one bibliographic entry read from an import source. The ISBN,
+// Publisher, PublishDate, Language and PageCount fields describe one edition
+// of it; Editions lists any further editions.
+type Record struct {
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Authors     []string        `json:"authors"`
+	ISBN        string          `json:"isbn"`
+	Publisher   string          `json:"publisher"`
+	PublishDate string          `json:"publish_date"`
+	Language    string          `json:"language"`
+	PageCount   int             `json:"page_count"`
+	Editions    []EditionRecord `json:"editions,omitempty"`
+}
+
+// EditionRecord is one published edition of a Record.
+type EditionRecord struct {
+	ISBN        string `json:"isbn"`
+	Publisher   string `json:"publisher"`
+	PublishDate string `json:"publish_date"`
+	Language    string `json:"language"`
+	PageCount   int    `json:"page_count"`
+}
+
+// AllEditions returns the record's own edition, when it has any edition
+// details, followed by Editions.
+func (r Record) AllEditions() []EditionRecord {
+	var editions []EditionRecord
+	own := EditionRecord{ISBN: r.ISBN, Publisher: r.Publisher, PublishDate: r.PublishDate, Language: r.Language, PageCount: r.PageCount}
+	if own != (EditionRecord{}) {
+		editions = append(editions, own)
+	}
+	return append(editions, r.Editions...)
+}
+
+const maxLineSize = 16 << 20
+
+// Open Library dump lines are tab separated: type, key, revision, last
+// modified, JSON.
+type olDocument struct {
+	Key         string          `json:"key"`
+	Name        string          `json:"name"`
+	Title       string          `json:"title"`
+	Subtitle    string          `json:"subtitle"`
+	Description json.RawMessage `json:"description"`
+	Authors     []struct {
+		Key    string `json:"key"`
+		Author struct {
+			Key string `json:"key"`
+		} `json:"author"`
+	} `json:"authors"`
+	Works []struct {
+		Key string `json:"key"`
+	} `json:"works"`
+	ISBN13      []string `json:"isbn_13"`
+	ISBN10      []string `json:"isbn_10"`
+	Publishers  []string `json:"publishers"`
+	PublishDate string   `json:"publish_date"`
+	Languages   []struct {
+		Key string `json:"key"`
+	} `json:"languages"`
+	NumberOfPages int `json:"number_of_pages"`
+}
+
+// ParseOpenLibrary reads an Open Library dump (authors, works and editions, in
+// any mix) and returns a record per work, carrying the editions of that work
+// found in the dump, plus one per edition whose work is not in the dump.
+// Author keys are resolved against author entries in the same file;
+// unresolved keys are dropped.
+//
+// Dumps run to gigabytes, so the file is read twice rather than held in
+// memory: the first pass only notes author names and which works exist.
+func ParseOpenLibrary(r io.ReadSeeker) ([]Record, error) {
+	authorNames := map[string]string{}
+	workKeys := map[string]bool{}
+	err := scanOpenLibrary(r, func(kind string, raw []byte) error {
+		var doc struct {
+			Key  string `json:"key"`
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return err
+		}
+		if kind == "" {
+			kind = olKindFromKey(doc.Key)
+		}
+		switch kind {
+		case "/type/author":
+			authorNames[doc.Key] = strings.TrimSpace(doc.Name)
+		case "/type/work":
+			workKeys[doc.Key] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	workIndex := map[string]int{}
+	// work returns the record for a work, adding an empty one when an edition
+	// comes before its work in the file
+	work := func(key string) *Record {
+		i, ok := workIndex[key]
+		if !ok {
+			i = len(records)
+			workIndex[key] = i
+			records = append(records, Record{})
+		}
+		return &records[i]
+	}
+	err = scanOpenLibrary(r, func(kind string, raw []byte) error {
+		var doc olDocument
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return err
+		}
+		if kind == "" {
+			kind = olKindFromKey(doc.Key)
+		}
+		switch kind {
+		case "/type/work":
+			record := work(doc.Key)
+			editions := record.Editions
+			*record = olRecord(doc, authorNames)
+			record.Editions = editions
+		case "/type/edition":
+			edition := olRecord(doc, authorNames)
+			if len(doc.Works) > 0 && workKeys[doc.Works[0].Key] {
+				record := work(doc.Works[0].Key)
+				record.Editions = append(record.Editions, edition.AllEditions()...)
+				return nil
+			}
+			records = append(records, edition)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// scanOpenLibrary calls fn with each document in a dump and its type, which is
+// empty for bare JSON lines.
+func scanOpenLibrary(r io.Reader, fn func(kind string, raw []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := bytes.Split(scanner.Bytes(), []byte("\t"))
+		var kind string
+		var raw []byte
+		switch {
+		case len(fields) >= 5:
+			kind, raw = string(fields[0]), fields[len(fields)-1]
+		case len(bytes.TrimSpace(scanner.Bytes())) == 0:
+			continue
+		default:
+			// Accept bare JSON lines, as produced by the Open Library API
+			raw = scanner.Bytes()
+		}
+		if err := fn(kind, raw); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func olKindFromKey(key string) string {
+	switch {
+	case strings.HasPrefix(key, "/authors/"):
+		return "/type/author"
+	case strings.HasPrefix(key, "/works/"):
+		return "/type/work"
+	case strings.HasPrefix(key, "/books/"):
+		return "/type/edition"
+	}
+	return ""
+}
+
+func olRecord(doc olDocument, authorNames map[string]string) Record {
+	record := Record{
+		Title:       strings.TrimSpace(doc.Title),
+		Description: olText(doc.Description),
+		PublishDate: doc.PublishDate,
+		PageCount:   doc.NumberOfPages,
+	}
+	if doc.Subtitle != "" {
+		record.Title += ": " + strings.TrimSpace(doc.Subtitle)
+	}
+	for _, author := range doc.Authors {
+		key := author.Key
+		if key == "" {
+			key = author.Author.Key
+		}
+		if name := authorNames[key]; name != "" {
+			record.Authors = append(record.Authors, name)
+		}
+	}
+	if len(doc.ISBN13) > 0 {
+		record.ISBN = doc.ISBN13[0]
+	} else if len(doc.ISBN10) > 0 {
+		record.ISBN = doc.ISBN10[0]
+	}
+	if len(doc.Publishers) > 0 {
+		record.Publisher = doc.Publishers[0]
+	}
+	if len(doc.Languages) > 0 {
+		record.Language = strings.TrimPrefix(doc.Languages[0].Key, "/languages/")
+	}
+	return record
+}
+
+// olText reads a description that is either a plain string or a
+// {"type": "/type/text", "value": ...} object.
+func olText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return strings.TrimSpace(text)
+	}
+	var typed struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &typed); err == nil {
+		return strings.TrimSpace(typed.Value)
+	}
+	return ""
+}
+
+// ParseISBNFile reads a local ISBN metadata file, either CSV with a header row
+// or JSON lines, with the fields of Record. In CSV files multiple authors are
+// separated by semicolons.
+func ParseISBNFile(r io.Reader) ([]Record, error) {
+	br := bufio.NewReader(r)
+	first, err := br.Peek(1)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if first[0] == '{' {
+		return parseJSONLines(br)
+	}
+	return parseISBNCSV(br)
+}
+
+func parseJSONLines(r io.Reader) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+
+	var records []Record
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry struct {
+			Record
+			Author string `json:"author"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if entry.Author != "" && len(entry.Authors) == 0 {
+			entry.Authors = []string{entry.Author}
+		}
+		records = append(records, entry.Record)
+	}
+	return records, scanner.Err()
+}
+
+func parseISBNCSV(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("CSV header must include a title column")
+	}
+	get := func(row []string, names ...string) string {
+		for _, name := range names {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+		}
+		return ""
+	}
+
+	var records []Record
+	for _, row := range rows[1:] {
+		record := Record{
+			Title:       get(row, "title"),
+			Description: get(row, "description"),
+			ISBN:        get(row, "isbn", "isbn13", "isbn_13", "isbn10", "isbn_10"),
+			Publisher:   get(row, "publisher"),
+			PublishDate: get(row, "publish_date", "year"),
+			Language:    get(row, "language"),
+		}
+		fmt.Sscan(get(row, "page_count", "pages"), &record.PageCount)
+		for _, author := range strings.Split(get(row, "authors", "author"), ";") {
+			if author = strings.TrimSpace(author); author != "" {
+				record.Authors = append(record.Authors, author)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
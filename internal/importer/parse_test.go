@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOpenLibrary(t *testing.T) {
+	author := "/type/author\t/authors/OL1A\t1\t2020-01-01\t" + `{"key": "/authors/OL1A", "name": " Friedrich Nietzsche "}`
+	work := "/type/work\t/works/OL1W\t1\t2020-01-01\t" + `{"key": "/works/OL1W", "title": "Die fröhliche Wissenschaft", "authors": [{"author": {"key": "/authors/OL1A"}}], "description": {"type": "/type/text", "value": "Aphorisms"}}`
+	edition := "/type/edition\t/books/OL1M\t1\t2020-01-01\t" + `{"key": "/books/OL1M", "title": "The Gay Science", "works": [{"key": "/works/OL1W"}], "isbn_13": ["9780394719856"], "publishers": ["Vintage"], "publish_date": "1974", "number_of_pages": 407, "languages": [{"key": "/languages/eng"}]}`
+	orphan := `{"key": "/books/OL2M", "title": "Ecce Homo", "subtitle": "How One Becomes What One Is", "authors": [{"key": "/authors/OL9A"}], "isbn_10": ["0140445153"]}`
+
+	gayScience := Record{
+		Title:       "Die fröhliche Wissenschaft",
+		Description: "Aphorisms",
+		Authors:     []string{"Friedrich Nietzsche"},
+		Editions: []EditionRecord{{
+			ISBN: "9780394719856", Publisher: "Vintage", PublishDate: "1974", Language: "eng", PageCount: 407,
+		}},
+	}
+	ecceHomo := Record{Title: "Ecce Homo: How One Becomes What One Is", ISBN: "0140445153"}
+
+	tests := []struct {
+		name    string
+		lines   []string
+		want    []Record
+		wantErr string
+	}{
+		{name: "empty", lines: nil, want: nil},
+		{name: "edition joins its work", lines: []string{author, work, edition}, want: []Record{gayScience}},
+		{name: "edition before its work and author", lines: []string{edition, work, author}, want: []Record{gayScience}},
+		{name: "edition without its work stands alone", lines: []string{"", orphan, author}, want: []Record{ecceHomo}},
+		{name: "bad JSON reports the line", lines: []string{author, "{"}, wantErr: "line 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := ParseOpenLibrary(strings.NewReader(strings.Join(tt.lines, "\n")))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseOpenLibrary() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseOpenLibrary() error = %v", err)
+			}
+			if !reflect.DeepEqual(records, tt.want) {
+				t.Errorf("ParseOpenLibrary() = %+v, want %+v", records, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseISBNFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Record
+	}{
+		{name: "empty", input: "", want: nil},
+		{
+			name:  "CSV",
+			input: "Title,Authors,ISBN13,Pages\nEither/Or,Søren Kierkegaard; Victor Eremita,9780140445770,640\n",
+			want:  []Record{{Title: "Either/Or", Authors: []string{"Søren Kierkegaard", "Victor Eremita"}, ISBN: "9780140445770", PageCount: 640}},
+		},
+		{
+			name:  "JSON lines with a single author",
+			input: `{"title": "Fear and Trembling", "author": "Søren Kierkegaard", "publisher": "Penguin"}` + "\n\n",
+			want:  []Record{{Title: "Fear and Trembling", Authors: []string{"Søren Kierkegaard"}, Publisher: "Penguin"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := ParseISBNFile(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("ParseISBNFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(records, tt.want) {
+				t.Errorf("ParseISBNFile() = %+v, want %+v", records, tt.want)
+			}
+		})
+	}
+}
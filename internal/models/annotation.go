@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// Annotation visibilities.
+const (
+	// Only the author
+	AnnotationPrivate = "private"
+	// Everyone in the course
+	AnnotationCourse = "course"
+	// The author and the course facilitator
+	AnnotationFacilitator = "facilitator"
+)
+
+// Annotation is a note anchored to a passage of a reading, either by page and
+// paragraph or by a quote of the text (the W3C TextQuoteSelector: the exact
+// text plus some context before and after it). Replies set ParentID and take
+// their anchor and visibility from the annotation they reply to.
+type Annotation struct {
+	ID         int    `json:"id,omitempty"`
+	ReadingID  int    `json:"reading_id"`
+	AuthorID   int    `json:"author_id"`
+	ParentID   *int   `json:"parent_id"`
+	Visibility string `json:"visibility"`
+	// Markdown
+	Body      string    `json:"body"`
+	Page      *int      `json:"page"`
+	Paragraph *int      `json:"paragraph"`
+	Exact     string    `json:"exact"`
+	Prefix    string    `json:"prefix"`
+	Suffix    string    `json:"suffix"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AnnotationDto is an annotation as shown to readers, with its target in W3C
+// Web Annotation form and its replies.
+type AnnotationDto struct {
+	Annotation
+	AuthorName string          `json:"author_name"`
+	BodyHTML   string          `json:"body_html"`
+	Target     *W3CTarget      `json:"target,omitempty"`
+	Replies    []AnnotationDto `json:"replies"`
+}
+
+// W3CTarget is the target of a W3C Web Annotation
+// (https://www.w3.org/TR/annotation-model/#selectors).
+type W3CTarget struct {
+	Source   string        `json:"source"`
+	Selector []W3CSelector `json:"selector"`
+}
+
+// W3CSelector is a TextQuoteSelector or, for page anchors, a FragmentSelector
+// conforming to RFC 3778 ("page=12"). A paragraph within the page is given as
+// a refining "paragraph=3" FragmentSelector.
+type W3CSelector struct {
+	Type       string       `json:"type"`
+	Exact      string       `json:"exact,omitempty"`
+	Prefix     string       `json:"prefix,omitempty"`
+	Suffix     string       `json:"suffix,omitempty"`
+	Value      string       `json:"value,omitempty"`
+	ConformsTo string       `json:"conformsTo,omitempty"`
+	RefinedBy  *W3CSelector `json:"refinedBy,omitempty"`
+}
+
+// AnnotatedPassage counts the annotations anchored to one passage of a reading.
+type AnnotatedPassage struct {
+	Page        *int   `json:"page"`
+	Paragraph   *int   `json:"paragraph"`
+	Exact       string `json:"exact,omitempty"`
+	Annotations int    `json:"annotations"`
+	Replies     int    `json:"replies"`
+	// Distinct participants who annotated the passage
+	Annotators int `json:"annotators"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Announcement is a facilitator's notice to a course. It becomes visible to
+// participants, and appears in feeds, from PublishAt.
+type Announcement struct {
+	ID        int       `json:"id,omitempty"`
+	CourseID  int       `json:"course_id"`
+	AuthorID  int       `json:"author_id"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Pinned    bool      `json:"pinned"`
+	PublishAt time.Time `json:"publish_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type AnnouncementDto struct {
+	Announcement
+	AuthorName string `json:"author_name"`
+	BodyHTML   string `json:"body_html"`
+	// False while the announcement is scheduled for later
+	Published bool `json:"published"`
+}
+
+// AnnouncementRequest creates or updates an announcement. PublishAt defaults
+// to now.
+type AnnouncementRequest struct {
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	Pinned    bool       `json:"pinned"`
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// CourseFeedToken is the secret that lets feed readers fetch a non-public
+// course's announcement feed as ?token=, so no sign-in token ends up in feed
+// URLs. The facilitator can rotate it to shut out old subscriptions.
+type CourseFeedToken struct {
+	CourseID  int       `json:"course_id"`
+	Token     string    `json:"token"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CourseFeedTokenDto struct {
+	Token   string `json:"token"`
+	FeedURL string `json:"feed_url"`
+}
+
+// CourseVisibilityRequest makes a course public or private.
+type CourseVisibilityRequest struct {
+	Public *bool `json:"public"`
+}
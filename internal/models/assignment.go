@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Discussion roles rotated between participants.
+const (
+	RoleOpener         = "opener"
+	RoleNoteTaker      = "note_taker"
+	RoleDevilsAdvocate = "devils_advocate"
+)
+
+// DefaultRoles are assigned when a facilitator does not name any.
+var DefaultRoles = []string{RoleOpener, RoleNoteTaker, RoleDevilsAdvocate}
+
+// DiscussionAssignments are the roles and breakout groups for a discussion.
+// Participants only see them once the facilitator publishes them.
+type DiscussionAssignments struct {
+	ID           int              `json:"id,omitempty"`
+	DiscussionID int              `json:"discussion_id"`
+	Roles        []RoleAssignment `json:"roles"`
+	Groups       []BreakoutGroup  `json:"groups"`
+	Published    bool             `json:"published"`
+	PublishedAt  *time.Time       `json:"published_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+}
+
+type RoleAssignment struct {
+	Role   string `json:"role"`
+	UserID int    `json:"user_id"`
+}
+
+type BreakoutGroup struct {
+	Number  int   `json:"number"`
+	UserIDs []int `json:"user_ids"`
+}
+
+// GenerateAssignmentsRequest asks for roles and breakout groups to be drawn
+// up. Roles defaults to DefaultRoles and GroupSize to 4; set GroupSize to -1
+// to skip breakout groups.
+type GenerateAssignmentsRequest struct {
+	Roles     []string `json:"roles"`
+	GroupSize int      `json:"group_size"`
+}
+
+// AssignmentsRequest replaces the roles and groups with the facilitator's own.
+type AssignmentsRequest struct {
+	Roles  []RoleAssignment `json:"roles"`
+	Groups []BreakoutGroup  `json:"groups"`
+}
@@ -8,7 +8,7 @@ type Author struct {
 	// The unique identifier for the author
 	// example: 1
 	// required: true
-	ID int `json:"id"`
+	ID int `json:"id,omitempty"`
 
 	// The name of the author
 	// example: Jean Baudrillard (the illest fr fr)
@@ -24,11 +24,40 @@ type Author struct {
 	// required: true
 	Description string `json:"description"`
 
+	// Date of birth as YYYY, YYYY-MM or YYYY-MM-DD; years before the
+	// common era are negative
+	// example: 1844-10-15
+	BirthDate string `json:"birthDate,omitempty"`
+
+	// Date of death, in the same form as BirthDate
+	// example: 1900-08-25
+	DeathDate string `json:"deathDate,omitempty"`
+
+	// Schools of thought or movements the author belongs to
+	// example: ["Existentialism", "Perspectivism"]
+	Schools []string `json:"schools,omitempty"`
+
+	// The language the author mainly wrote in, as an ISO 639-1 code
+	// example: de
+	PrimaryLanguage string `json:"primaryLanguage,omitempty"`
+
+	// Reference pages about the author
+	Links []AuthorLink `json:"links,omitempty"`
+
 	// The time when the author record was created
 	// example: 2020-01-01T00:00:00Z
 	// required: true
 	CreatedAt time.Time `json:"createdAt"`
 
 	// The last time the author record was updated
-	//
+	// example: 2020-01-01T00:00:00Z
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// AuthorLink is a labelled reference page, e.g. an encyclopedia entry.
+type AuthorLink struct {
+	// example: Stanford Encyclopedia of Philosophy
+	Label string `json:"label"`
+	// example: https://plato.stanford.edu/entries/nietzsche/
+	URL string `json:"url"`
 }
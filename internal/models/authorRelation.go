@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Kinds of AuthorRelation. Relations are directed: FromAuthorID was influenced
+// by, or responded to, ToAuthorID.
+const (
+	RelationInfluencedBy = "influenced_by"
+	RelationRespondedTo  = "responded_to"
+)
+
+type AuthorRelation struct {
+	ID           int       `json:"id,omitempty"`
+	FromAuthorID int       `json:"from_author_id"`
+	ToAuthorID   int       `json:"to_author_id"`
+	Kind         string    `json:"kind"`
+	Note         string    `json:"note"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// AuthorNeighborhood is the part of the relation graph within a few steps of
+// an author.
+type AuthorNeighborhood struct {
+	AuthorID int              `json:"author_id"`
+	Depth    int              `json:"depth"`
+	Authors  []Author         `json:"authors"`
+	Edges    []AuthorRelation `json:"edges"`
+}
+
+// TimelineEntry places an author of a course's reading list in time.
+type TimelineEntry struct {
+	Author Author `json:"author"`
+	// Years parsed from the author's dates, negative before the common era
+	BirthYear *int `json:"birth_year"`
+	DeathYear *int `json:"death_year"`
+	// The course's books by this author
+	Books []Book `json:"books"`
+}
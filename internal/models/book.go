@@ -2,8 +2,10 @@ package models
 
 import "time"
 
+// Book is a catalog entry. Author mirrors the name of the author referenced by
+// AuthorID and is kept in sync by the API.
 type Book struct {
-	ID          int       `json:"id"`
+	ID          int       `json:"id,omitempty"`
 	Title       string    `json:"title"`
 	Author      string    `json:"author"`
 	Description string    `json:"description"`
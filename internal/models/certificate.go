@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// CompletionCriteria are the thresholds a participant must meet to earn a
+// completion certificate for a course.
+type CompletionCriteria struct {
+	ID       int `json:"id,omitempty"`
+	CourseID int `json:"course_id"`
+	// Minimum share of held discussions attended, 0-100
+	MinAttendancePercent float64   `json:"min_attendance_percent"`
+	MinReadingsRated     int       `json:"min_readings_rated"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// Certificate is an issued completion certificate. Code is printed on the PDF
+// and can be checked at /certificates/:code.
+type Certificate struct {
+	ID                int       `json:"id,omitempty"`
+	Code              string    `json:"code"`
+	CourseID          int       `json:"course_id"`
+	UserID            int       `json:"user_id"`
+	RecipientName     string    `json:"recipient_name"`
+	CourseTitle       string    `json:"course_title"`
+	AttendancePercent float64   `json:"attendance_percent"`
+	ReadingsRated     int       `json:"readings_rated"`
+	IssuedAt          time.Time `json:"issued_at"`
+}
+
+// CompletionEvaluation is the result of checking one participant against the
+// course's CompletionCriteria.
+type CompletionEvaluation struct {
+	UserID            int          `json:"user_id"`
+	Name              string       `json:"name"`
+	AttendancePercent float64      `json:"attendance_percent"`
+	ReadingsRated     int          `json:"readings_rated"`
+	Completed         bool         `json:"completed"`
+	Certificate       *Certificate `json:"certificate,omitempty"`
+}
+
+// CertificateVerification is the public response for a verification code.
+type CertificateVerification struct {
+	Valid         bool      `json:"valid"`
+	RecipientName string    `json:"recipient_name"`
+	CourseTitle   string    `json:"course_title"`
+	IssuedAt      time.Time `json:"issued_at"`
+}
@@ -7,9 +7,14 @@ type Course struct {
 	FacilitatorID int    `json:"facilitator_id"`
 	Title         string `json:"title"`
 	Description   string `json:"description"`
-	CreatedAt     string `json:"created_at"`
-	UpdatedAt     string `json:"updated_at"`
-	PhotoUrl      string `json:"photo_url"`
+	// IANA zone discussion times are entered and shown in, e.g. "Europe/Berlin"
+	Timezone  string `json:"timezone"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	PhotoUrl  string `json:"photo_url"`
+	// Public courses' announcements appear in the combined feed and their
+	// course feeds can be read without signing in
+	Public bool `json:"public"`
 }
 
 type CourseDetails struct {
@@ -1,9 +1,27 @@
 package models
 
+import "time"
+
 type CourseBook struct {
-	ID        int    `json:"id"`
-	CourseID  int    `json:"course_id"`
-	BookID    int    `json:"book_id"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	ID       int `json:"id,omitempty"`
+	CourseID int `json:"course_id"`
+	BookID   int `json:"book_id"`
+	// Zero-based position of the book in the course's reading order
+	Position int `json:"position"`
+	// Required books are core texts; the rest are supplementary
+	Required bool `json:"required"`
+	// The edition the course reads from, if one has been chosen
+	EditionID int       `json:"edition_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CourseBookDto is a book as it appears in a course, with its place in the
+// reading order.
+type CourseBookDto struct {
+	Book
+	CourseBookID int      `json:"course_book_id"`
+	Position     int      `json:"position"`
+	Required     bool     `json:"required"`
+	Edition      *Edition `json:"edition,omitempty"`
 }
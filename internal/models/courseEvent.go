@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Course event types streamed to dashboards.
+const (
+	EventDiscussionCreated  = "discussion.created"
+	EventDiscussionUpdated  = "discussion.updated"
+	EventDiscussionDeleted  = "discussion.deleted"
+	EventReadingAdded       = "reading.added"
+	EventAttendanceRecorded = "attendance.recorded"
+	EventRatingSubmitted    = "rating.submitted"
+)
+
+// CourseEvent is a change within a course. IDs come from a database sequence
+// so they increase across restarts and clients can resume from the last one
+// they saw.
+type CourseEvent struct {
+	ID       int64  `json:"id,omitempty"`
+	CourseID int    `json:"course_id"`
+	Type     string `json:"type"`
+	// The created, updated or deleted record
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// CourseReport is the participation report for a course: an attendance matrix
+// of participants by discussions, plus per-participant and per-discussion totals.
+type CourseReport struct {
+	Course       Course              `json:"course"`
+	GeneratedAt  time.Time           `json:"generated_at"`
+	Discussions  []DiscussionTurnout `json:"discussions"`
+	Participants []ParticipantReport `json:"participants"`
+	// Discussions already held whose turnout fell below LowTurnoutThreshold
+	LowTurnout          []DiscussionTurnout `json:"low_turnout"`
+	LowTurnoutThreshold float64             `json:"low_turnout_threshold"`
+	// Latest minutes of each discussion that has them, in discussion order
+	Minutes []DiscussionMinutesDto `json:"minutes"`
+}
+
+type DiscussionTurnout struct {
+	DiscussionID int       `json:"discussion_id"`
+	Name         string    `json:"name"`
+	DateTime     time.Time `json:"date_time"`
+	Held         bool      `json:"held"`
+	Present      int       `json:"present"`
+	Excused      int       `json:"excused"`
+	// Share of enrolled participants marked present, 0-100
+	TurnoutPercent float64 `json:"turnout_percent"`
+}
+
+type ParticipantReport struct {
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	// Attendance status per discussion, in the same order as CourseReport.Discussions;
+	// empty where nothing was recorded
+	Attendance []string `json:"attendance"`
+	Attended   int      `json:"attended"`
+	// Share of held discussions attended, 0-100
+	AttendancePercent float64 `json:"attendance_percent"`
+	RatingsSubmitted  int     `json:"ratings_submitted"`
+}
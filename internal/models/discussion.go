@@ -2,10 +2,34 @@ package models
 
 import "time"
 
+// Discussion is a single meeting of a course. DateTime is always stored in UTC;
+// handlers convert it to the course or requesting user's timezone on the way out.
 type Discussion struct {
-	ID          int       `json:"id"`
+	ID          int       `json:"id,omitempty"`
 	CourseID    int       `json:"course_id"`
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
 	DateTime    time.Time `json:"date_time"`
 }
+
+// DiscussionRequest is the body accepted when creating or updating a discussion.
+// DateTime may carry an explicit offset (RFC 3339) or be a wall-clock time such as
+// "2024-05-01T19:00", in which case it is read in the course's timezone.
+type DiscussionRequest struct {
+	CourseID    int    `json:"course_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	DateTime    string `json:"date_time"`
+}
+
+// RecurringDiscussionRequest generates a series of discussions that keep the same
+// wall-clock time in the course's timezone across DST transitions.
+type RecurringDiscussionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// First occurrence, in the same formats as DiscussionRequest.DateTime
+	StartsAt string `json:"starts_at"`
+	// Days between occurrences, e.g. 7 for weekly
+	IntervalDays int `json:"interval_days"`
+	Count        int `json:"count"`
+}
@@ -1,8 +1,37 @@
 package models
 
+import "time"
+
+const (
+	AttendancePresent = "present"
+	AttendanceAbsent  = "absent"
+	AttendanceExcused = "excused"
+)
+
 type DiscussionAttendance struct {
-	ID           int  `json:"id"`
+	ID           int  `json:"id,omitempty"`
 	DiscussionID int  `json:"discussion_id"`
 	UserID       int  `json:"user_id"`
 	Attended     bool `json:"attended"`
+	// One of present, absent or excused; Attended mirrors Status == present
+	Status      string     `json:"status"`
+	CheckedInAt *time.Time `json:"checked_in_at,omitempty"`
+}
+
+// BulkAttendanceRequest records a whole roster for a discussion in one call.
+type BulkAttendanceRequest struct {
+	Records []AttendanceRecord `json:"records"`
+}
+
+type AttendanceRecord struct {
+	UserID int    `json:"user_id"`
+	Status string `json:"status"`
+}
+
+// CheckInToken is the short-lived token a facilitator displays as a QR code.
+type CheckInToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// PNG QR code of Token as a data URI
+	QRCode string `json:"qr_code"`
 }
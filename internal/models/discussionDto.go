@@ -4,5 +4,8 @@ type DiscussionDto struct {
 	Discussion
 	Readings   []Reading              `json:"readings"`
 	Ratings    []ReadingRating        `json:"ratings"`
+	Progress   []ReadingProgress      `json:"progress,omitempty"`
+	Completion DiscussionCompletion   `json:"completion"`
 	Attendance []DiscussionAttendance `json:"attendance"`
+	Minutes    *DiscussionMinutesDto  `json:"minutes"`
 }
@@ -5,4 +5,12 @@ type DiscussionMgmtDto struct {
 	Participants []CourseParticipantDto `json:"participants"`
 	Readings     []ReadingDto           `json:"readings"`
 	Attendance   []DiscussionAttendance `json:"attendance"`
+	// Participants' questions in agenda order
+	Questions []QuestionDto `json:"questions"`
+	// Live polls with their tallies
+	Polls []Poll `json:"polls"`
+	// Latest version of the minutes, if any have been written
+	Minutes *DiscussionMinutesDto `json:"minutes"`
+	// Roles and breakout groups; drafts are only shown to the facilitator
+	Assignments *DiscussionAssignments `json:"assignments"`
 }
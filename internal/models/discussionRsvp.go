@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+const (
+	RsvpGoing    = "going"
+	RsvpMaybe    = "maybe"
+	RsvpNotGoing = "not_going"
+)
+
+// DiscussionRsvp is a participant's stated intention to attend, recorded
+// separately from DiscussionAttendance which captures what actually happened.
+type DiscussionRsvp struct {
+	ID           int       `json:"id,omitempty"`
+	DiscussionID int       `json:"discussion_id"`
+	UserID       int       `json:"user_id"`
+	Response     string    `json:"response"`
+	Note         string    `json:"note"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// RsvpReconciliation lines up one participant's RSVP with their recorded attendance.
+type RsvpReconciliation struct {
+	UserID int    `json:"user_id"`
+	User   User   `json:"user"`
+	Rsvp   string `json:"rsvp"`
+	Note   string `json:"note"`
+	// Attendance status, empty when none has been recorded
+	Attendance string `json:"attendance"`
+	// One of kept, no_show, unexpected, excused, absent, pending
+	Outcome string `json:"outcome"`
+}
+
+// ParticipantAttendanceStats summarises RSVPs against attendance across the
+// past discussions of a course.
+type ParticipantAttendanceStats struct {
+	RsvpGoing   int     `json:"rsvp_going"`
+	Attended    int     `json:"attended"`
+	NoShows     int     `json:"no_shows"`
+	NoShowRate  float64 `json:"no_show_rate"`
+	Unannounced int     `json:"unannounced"`
+}
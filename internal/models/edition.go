@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Edition is a specific published form of a Book: a translation, a particular
+// publisher's printing, and so on. Page numbers in readings refer to an edition.
+type Edition struct {
+	ID         int    `json:"id,omitempty"`
+	BookID     int    `json:"book_id"`
+	Title      string `json:"title"`
+	Translator string `json:"translator"`
+	Publisher  string `json:"publisher"`
+	Year       int    `json:"year"`
+	ISBN       string `json:"isbn"`
+	// ISO 639-1 language code, e.g. "en"
+	Language  string    `json:"language"`
+	PageCount int       `json:"page_count"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
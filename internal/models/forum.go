@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// ForumThread is a conversation attached to a discussion, and optionally to
+// one of its readings. The opening message is its first ForumPost.
+type ForumThread struct {
+	ID           int    `json:"id,omitempty"`
+	DiscussionID int    `json:"discussion_id"`
+	ReadingID    *int   `json:"reading_id"`
+	AuthorID     int    `json:"author_id"`
+	Title        string `json:"title"`
+	// Pinned threads are listed first; locked threads take no new posts
+	Pinned     bool       `json:"pinned"`
+	Locked     bool       `json:"locked"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastPostAt time.Time  `json:"last_post_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+}
+
+// ForumPost is a markdown message in a thread. ParentID is the post it replies
+// to, nil for the thread's opening post and top-level replies.
+type ForumPost struct {
+	ID        int        `json:"id,omitempty"`
+	ThreadID  int        `json:"thread_id"`
+	ParentID  *int       `json:"parent_id"`
+	AuthorID  int        `json:"author_id"`
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	EditCount int        `json:"edit_count"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy *int       `json:"deleted_by,omitempty"`
+}
+
+// ForumPostRevision keeps a post's body as it was before an edit.
+type ForumPostRevision struct {
+	ID       int       `json:"id,omitempty"`
+	PostID   int       `json:"post_id"`
+	Body     string    `json:"body"`
+	EditorID int       `json:"editor_id"`
+	EditedAt time.Time `json:"edited_at"`
+}
+
+// ForumPostDto is a post as shown to readers. Deleted posts keep their place
+// in the thread with an empty body.
+type ForumPostDto struct {
+	ForumPost
+	AuthorName string `json:"author_name"`
+	BodyHTML   string `json:"body_html"`
+	Deleted    bool   `json:"deleted"`
+}
+
+type ForumThreadDto struct {
+	ForumThread
+	AuthorName string `json:"author_name"`
+	// Replies, not counting the opening post or deleted posts
+	ReplyCount int `json:"reply_count"`
+}
+
+// ForumThreadPage is one page of a discussion's threads, pinned first and then
+// by latest activity.
+type ForumThreadPage struct {
+	Threads  []ForumThreadDto `json:"threads"`
+	Page     int              `json:"page"`
+	PageSize int              `json:"page_size"`
+	Total    int              `json:"total"`
+}
+
+// ForumThreadDetail is a thread with one page of its posts in posting order.
+type ForumThreadDetail struct {
+	Thread   ForumThreadDto `json:"thread"`
+	Posts    []ForumPostDto `json:"posts"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"page_size"`
+	Total    int            `json:"total"`
+}
+
+type ForumThreadRequest struct {
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	ReadingID *int   `json:"reading_id"`
+}
+
+type ForumPostRequest struct {
+	Body     string `json:"body"`
+	ParentID *int   `json:"parent_id"`
+}
+
+// ForumModerationRequest changes whichever of the flags are given.
+type ForumModerationRequest struct {
+	Pinned *bool `json:"pinned"`
+	Locked *bool `json:"locked"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// DiscussionMinutes is one version of the write-up of a discussion. Saving
+// the minutes adds a new version; earlier versions are kept unchanged.
+type DiscussionMinutes struct {
+	ID           int     `json:"id,omitempty"`
+	DiscussionID int     `json:"discussion_id"`
+	Version      int     `json:"version"`
+	AuthorID     int     `json:"author_id"`
+	Body         string  `json:"body"`
+	KeyQuotes    []Quote `json:"key_quotes"`
+	// Questions the discussion left open, for next time
+	OpenQuestions []string  `json:"open_questions"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Quote is a passage worth remembering, either from a reading or said during
+// the discussion.
+type Quote struct {
+	Text string `json:"text"`
+	// Who said or wrote it
+	Attribution string `json:"attribution,omitempty"`
+	ReadingID   *int   `json:"reading_id,omitempty"`
+	Page        *int   `json:"page,omitempty"`
+}
+
+type DiscussionMinutesDto struct {
+	DiscussionMinutes
+	AuthorName string `json:"author_name"`
+	BodyHTML   string `json:"body_html"`
+}
+
+// MinutesRequest saves a new version of the minutes. BaseVersion is the
+// version the edit started from; saving fails if someone else has saved a
+// newer one in the meantime. It is 0 when writing the first version.
+type MinutesRequest struct {
+	Body          string   `json:"body"`
+	KeyQuotes     []Quote  `json:"key_quotes"`
+	OpenQuestions []string `json:"open_questions"`
+	BaseVersion   int      `json:"base_version"`
+}
@@ -2,5 +2,6 @@ package models
 
 type CourseParticipantDto struct {
 	CourseParticipant
-	User User `json:"user"`
+	User  User                        `json:"user"`
+	Stats *ParticipantAttendanceStats `json:"stats,omitempty"`
 }
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Poll statuses. Participants can vote, and change their vote, while a poll
+// is open; closing it fixes the tallies.
+const (
+	PollOpen   = "open"
+	PollClosed = "closed"
+)
+
+// Poll is a quick question the facilitator puts to the room during a
+// discussion.
+type Poll struct {
+	ID           int      `json:"id,omitempty"`
+	DiscussionID int      `json:"discussion_id"`
+	Question     string   `json:"question"`
+	Options      []string `json:"options"`
+	Status       string   `json:"status"`
+	// Votes per option, in the order of Options. Stored once the poll is
+	// closed and counted live while it is open.
+	Tallies   []int      `json:"tallies"`
+	Votes     int        `json:"votes"`
+	CreatedBy int        `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+}
+
+// PollVote is one participant's answer to a poll.
+type PollVote struct {
+	ID        int       `json:"id,omitempty"`
+	PollID    int       `json:"poll_id"`
+	UserID    int       `json:"user_id"`
+	Option    int       `json:"option"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Live poll message types. Clients send create_poll and close_poll
+// (facilitators only) and vote; the server replies with state on connect and
+// broadcasts poll_created, poll_updated and poll_closed to everyone in the
+// discussion.
+const (
+	PollMessageCreate  = "create_poll"
+	PollMessageVote    = "vote"
+	PollMessageClose   = "close_poll"
+	PollMessageState   = "state"
+	PollMessageCreated = "poll_created"
+	PollMessageUpdated = "poll_updated"
+	PollMessageClosed  = "poll_closed"
+	PollMessageError   = "error"
+)
+
+// PollMessage is a message on a discussion's live poll WebSocket.
+type PollMessage struct {
+	Type     string   `json:"type"`
+	PollID   int      `json:"poll_id,omitempty"`
+	Question string   `json:"question,omitempty"`
+	Options  []string `json:"options,omitempty"`
+	// Index into the poll's options when voting
+	Option  *int   `json:"option,omitempty"`
+	Poll    *Poll  `json:"poll,omitempty"`
+	Polls   []Poll `json:"polls,omitempty"`
+	Message string `json:"message,omitempty"`
+}
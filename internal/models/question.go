@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Question statuses. Facilitators select questions for the session agenda and
+// mark them answered once discussed.
+const (
+	QuestionOpen     = "open"
+	QuestionSelected = "selected"
+	QuestionAnswered = "answered"
+)
+
+// Question is a participant-submitted question for a discussion, optionally
+// about one of its readings.
+type Question struct {
+	ID           int       `json:"id,omitempty"`
+	DiscussionID int       `json:"discussion_id"`
+	ReadingID    *int      `json:"reading_id"`
+	AuthorID     int       `json:"author_id"`
+	Body         string    `json:"body"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// QuestionVote is one participant's upvote of a question.
+type QuestionVote struct {
+	ID         int       `json:"id,omitempty"`
+	QuestionID int       `json:"question_id"`
+	UserID     int       `json:"user_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// QuestionDto is a question with its vote count and agenda position. Rank
+// orders selected questions first, then open and then answered ones, each by
+// upvotes.
+type QuestionDto struct {
+	Question
+	AuthorName string `json:"author_name"`
+	Upvotes    int    `json:"upvotes"`
+	Rank       int    `json:"rank"`
+	// Whether the requesting user has upvoted the question
+	Voted bool `json:"voted"`
+}
+
+type QuestionRequest struct {
+	Body      string `json:"body"`
+	ReadingID *int   `json:"reading_id"`
+}
+
+type QuestionStatusRequest struct {
+	Status string `json:"status"`
+}
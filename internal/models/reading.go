@@ -1,8 +1,24 @@
 package models
 
+// Reading kinds. Each kind has its own set of structured fields, checked when a
+// reading is created or updated.
+const (
+	// Part of a book: BookID, optionally EditionID, Chapter and a page range
+	ReadingBookSection = "book_section"
+	// Journal or magazine article: Title, Source and/or URL, optional page range or WordCount
+	ReadingArticle = "article"
+	// VideoURL (or URL), optionally trimmed with StartSeconds/EndSeconds
+	ReadingVideo = "video"
+	// URL, optionally trimmed with StartSeconds/EndSeconds
+	ReadingAudio = "audio"
+	// URL, optional WordCount
+	ReadingWebPage = "web_page"
+)
+
 type Reading struct {
-	ID               int    `json:"id"`
-	DiscussionID     int    `json:"discussion_id"`
+	ID           int `json:"id,omitempty"`
+	DiscussionID int `json:"discussion_id"`
+	// One of the Reading* kinds above
 	Type             string `json:"type"`
 	Title            string `json:"title"`
 	Description      string `json:"description"`
@@ -10,4 +26,20 @@ type Reading struct {
 	BookID           int    `json:"book_id"`
 	VideoURL         string `json:"video_url"`
 	DiscussionPrompt string `json:"discussion_prompt"`
+	// Edition the page range refers to; must be an edition of BookID
+	EditionID int `json:"edition_id,omitempty"`
+	// Free-form chapter reference, e.g. "3-4" or "Preface"
+	Chapter   string `json:"chapter,omitempty"`
+	PageStart int    `json:"page_start,omitempty"`
+	PageEnd   int    `json:"page_end,omitempty"`
+	// Journal, magazine or site an article appeared in
+	Source    string `json:"source,omitempty"`
+	WordCount int    `json:"word_count,omitempty"`
+	// Segment of a video or audio recording, in seconds from the start
+	StartSeconds int `json:"start_seconds,omitempty"`
+	EndSeconds   int `json:"end_seconds,omitempty"`
+	// Full length of a video or audio recording
+	DurationSeconds int `json:"duration_seconds,omitempty"`
+	// Computed from the page range, word count or duration when saved
+	EstimatedMinutes int `json:"estimated_minutes"`
 }
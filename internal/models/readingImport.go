@@ -0,0 +1,22 @@
+package models
+
+// ReadingImportResult reports what a BibTeX or RIS import into a discussion did.
+type ReadingImportResult struct {
+	Readings []Reading `json:"readings"`
+	// Catalog rows created because no existing book or author matched
+	CreatedBooks   []Book   `json:"created_books"`
+	CreatedAuthors []Author `json:"created_authors"`
+	// Entries matching a reading already in the discussion or earlier in the file
+	Duplicates []ReadingImportIssue `json:"duplicates"`
+	// Entries that could not be turned into a valid reading
+	Unmapped []ReadingImportIssue `json:"unmapped"`
+}
+
+// ReadingImportIssue identifies a skipped entry by its citation key and title.
+type ReadingImportIssue struct {
+	Key    string `json:"key"`
+	Title  string `json:"title"`
+	Reason string `json:"reason"`
+	// The existing reading a duplicate matched, if it was already saved
+	ReadingID int `json:"reading_id,omitempty"`
+}
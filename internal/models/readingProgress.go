@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Reading progress statuses.
+const (
+	ProgressNotStarted = "not_started"
+	ProgressInProgress = "in_progress"
+	ProgressFinished   = "finished"
+)
+
+// ReadingProgress is how far a participant has got with a reading. Percent and
+// Page are optional detail for readings in progress.
+type ReadingProgress struct {
+	ID        int       `json:"id,omitempty"`
+	ReadingID int       `json:"reading_id"`
+	UserID    int       `json:"user_id"`
+	Status    string    `json:"status"`
+	Percent   *int      `json:"percent"`
+	Page      *int      `json:"page"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ReadingCompletion summarizes the course participants' progress on one
+// reading. Participants with no progress recorded count as not started.
+type ReadingCompletion struct {
+	ReadingID    int    `json:"reading_id"`
+	Title        string `json:"title"`
+	Participants int    `json:"participants"`
+	NotStarted   int    `json:"not_started"`
+	InProgress   int    `json:"in_progress"`
+	Finished     int    `json:"finished"`
+	// Share of participants who finished, 0-100
+	FinishedPercent float64 `json:"finished_percent"`
+	// Mean progress across participants, counting finished as 100 and not
+	// started as 0
+	AveragePercent float64 `json:"average_percent"`
+}
+
+// DiscussionCompletion summarizes progress on all of a discussion's readings.
+type DiscussionCompletion struct {
+	DiscussionID int `json:"discussion_id"`
+	Participants int `json:"participants"`
+	// Participants who finished every reading
+	FinishedAll        int                 `json:"finished_all"`
+	FinishedAllPercent float64             `json:"finished_all_percent"`
+	Readings           []ReadingCompletion `json:"readings"`
+}
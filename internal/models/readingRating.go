@@ -1,8 +1,66 @@
 package models
 
+import "time"
+
+// Ratings and their dimensions are scored from 1 to RatingScaleMax.
+const RatingScaleMax = 5
+
 type ReadingRating struct {
-	ID        int `json:"id"`
+	ID        int `json:"id,omitempty"`
 	ReadingID int `json:"reading_id"`
-	UserID    int `json:"user_id"`
-	Rating    int `json:"rating"`
+	// Discussion the reading is assigned in, filled in from the reading
+	DiscussionID int `json:"discussion_id,omitempty"`
+	// Omitted when an anonymous rating is shown to other participants
+	UserID int `json:"user_id,omitempty"`
+	// Overall rating
+	Rating int `json:"rating"`
+	// Optional ratings on separate dimensions
+	Difficulty *int   `json:"difficulty,omitempty"`
+	Relevance  *int   `json:"relevance,omitempty"`
+	Enjoyment  *int   `json:"enjoyment,omitempty"`
+	Review     string `json:"review,omitempty"`
+	// Hide the rater from other participants; facilitators still see them
+	Anonymous bool      `json:"anonymous"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RatingStats summarizes the overall ratings and each dimension.
+type RatingStats struct {
+	Count      int            `json:"count"`
+	Overall    DimensionStats `json:"overall"`
+	Difficulty DimensionStats `json:"difficulty"`
+	Relevance  DimensionStats `json:"relevance"`
+	Enjoyment  DimensionStats `json:"enjoyment"`
+	// Ratings that include a written review
+	Reviews int `json:"reviews"`
+}
+
+// DimensionStats summarizes the scores given on one dimension. Mean and
+// Median are nil when nobody scored it.
+type DimensionStats struct {
+	Count  int      `json:"count"`
+	Mean   *float64 `json:"mean"`
+	Median *float64 `json:"median"`
+	// One bucket per score from 1 to RatingScaleMax
+	Histogram []RatingBucket `json:"histogram"`
+}
+
+type RatingBucket struct {
+	Score int `json:"score"`
+	Count int `json:"count"`
+}
+
+type ReadingRatingStats struct {
+	ReadingID int    `json:"reading_id"`
+	Title     string `json:"title"`
+	RatingStats
+}
+
+// DiscussionRatingStats combines the ratings of all of a discussion's readings
+// and breaks them down per reading.
+type DiscussionRatingStats struct {
+	DiscussionID int                  `json:"discussion_id"`
+	Overall      RatingStats          `json:"overall"`
+	Readings     []ReadingRatingStats `json:"readings"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Redirect points the ID of a merged author or book at the record it was
+// merged into.
+type Redirect struct {
+	OldID    int       `json:"old_id"`
+	NewID    int       `json:"new_id"`
+	MergedAt time.Time `json:"merged_at"`
+}
+
+// MergeRequest names the record that survives a merge.
+type MergeRequest struct {
+	Into int `json:"into"`
+}
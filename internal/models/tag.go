@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Kinds of record a tag can be assigned to.
+const (
+	TagCourse  = "course"
+	TagBook    = "book"
+	TagAuthor  = "author"
+	TagReading = "reading"
+)
+
+// Tag is a subject in the catalog taxonomy. Tags nest through ParentID, e.g.
+// "Virtue Ethics" under "Ethics".
+type Tag struct {
+	ID   int    `json:"id,omitempty"`
+	Name string `json:"name"`
+	// URL-safe identifier derived from Name, usable wherever a tag ID is
+	Slug        string    `json:"slug"`
+	ParentID    *int      `json:"parent_id"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TagAssignment attaches a tag to a course, book, author or reading.
+type TagAssignment struct {
+	ID         int       `json:"id,omitempty"`
+	TagID      int       `json:"tag_id"`
+	EntityType string    `json:"entity_type"`
+	EntityID   int       `json:"entity_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TagNode is a tag with its subtags, for browsing the taxonomy as a tree.
+type TagNode struct {
+	Tag
+	Children []TagNode `json:"children"`
+}
+
+// TagCount counts the records tagged with a tag. Total includes records tagged
+// with any of its subtags, each counted once.
+type TagCount struct {
+	TagID  int          `json:"tag_id"`
+	Name   string       `json:"name"`
+	Slug   string       `json:"slug"`
+	Direct EntityCounts `json:"direct"`
+	Total  EntityCounts `json:"total"`
+}
+
+type EntityCounts struct {
+	Courses  int `json:"courses"`
+	Books    int `json:"books"`
+	Authors  int `json:"authors"`
+	Readings int `json:"readings"`
+}
+
+// TaggedItems are the records under a tag and, unless excluded, its subtags.
+type TaggedItems struct {
+	Tag      Tag       `json:"tag"`
+	Courses  []Course  `json:"courses"`
+	Books    []Book    `json:"books"`
+	Authors  []Author  `json:"authors"`
+	Readings []Reading `json:"readings"`
+}
+
+// TagIDsRequest replaces the set of tags on a record.
+type TagIDsRequest struct {
+	TagIDs []int `json:"tag_ids"`
+}
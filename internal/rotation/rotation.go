@@ -0,0 +1,195 @@
+// Package rotation shares out discussion roles and breakout groups so that
+// over a course everyone takes their turn and meets as many others as
+// possible.
+package rotation
+
+import (
+	"sort"
+)
+
+// Candidate is a participant available for a discussion.
+type Candidate struct {
+	UserID int
+	// Going is true when they RSVPed that they will come; they are preferred
+	// for roles over those who have not said
+	Going bool
+}
+
+// History is what earlier discussions of the course already handed out.
+// Callers only count roles and pairings of participants who attended.
+type History struct {
+	// Times each user performed each role
+	Roles map[int]map[string]int
+	// Position of the last discussion in which each user had a role; higher
+	// is more recent and 0 means never
+	LastRole map[int]int
+	// Times each pair of users shared a breakout group, keyed by Pair
+	Pairings map[[2]int]int
+}
+
+// Pair is the key for two users in History.Pairings.
+func Pair(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+// Assignment gives a role to a user.
+type Assignment struct {
+	Role   string
+	UserID int
+}
+
+// Roles assigns each role to the candidate who has had the fewest roles so
+// far, then the fewest turns at that role, then who had a role least
+// recently. Nobody gets a second role in the discussion while another
+// candidate has none. No roles are assigned when there are no candidates.
+func Roles(roles []string, candidates []Candidate, history History) []Assignment {
+	if len(candidates) == 0 {
+		return nil
+	}
+	total := map[int]int{}
+	for _, candidate := range candidates {
+		for _, count := range history.Roles[candidate.UserID] {
+			total[candidate.UserID] += count
+		}
+	}
+
+	assigned := map[int]int{}
+	given := map[int]map[string]int{}
+	var assignments []Assignment
+	for _, role := range roles {
+		best := -1
+		for i, candidate := range candidates {
+			if best < 0 || rolePreferred(candidate, candidates[best], role, assigned, total, given, history) {
+				best = i
+			}
+		}
+		userID := candidates[best].UserID
+		assignments = append(assignments, Assignment{Role: role, UserID: userID})
+		assigned[userID]++
+		total[userID]++
+		if given[userID] == nil {
+			given[userID] = map[string]int{}
+		}
+		given[userID][role]++
+	}
+	return assignments
+}
+
+func rolePreferred(a, b Candidate, role string, assigned, total map[int]int, given map[int]map[string]int, history History) bool {
+	if assigned[a.UserID] != assigned[b.UserID] {
+		return assigned[a.UserID] < assigned[b.UserID]
+	}
+	if total[a.UserID] != total[b.UserID] {
+		return total[a.UserID] < total[b.UserID]
+	}
+	ra := history.Roles[a.UserID][role] + given[a.UserID][role]
+	rb := history.Roles[b.UserID][role] + given[b.UserID][role]
+	if ra != rb {
+		return ra < rb
+	}
+	if a.Going != b.Going {
+		return a.Going
+	}
+	if la, lb := history.LastRole[a.UserID], history.LastRole[b.UserID]; la != lb {
+		return la < lb
+	}
+	return a.UserID < b.UserID
+}
+
+// Groups splits users into groups of about size members whose sizes differ by
+// at most one, keeping apart those who have shared a group most often.
+func Groups(userIDs []int, size int, pairings map[[2]int]int) [][]int {
+	if len(userIDs) == 0 || size <= 0 {
+		return nil
+	}
+	count := (len(userIDs) + size - 1) / size
+	capacity := make([]int, count)
+	for i := range capacity {
+		capacity[i] = len(userIDs) / count
+		if i < len(userIDs)%count {
+			capacity[i]++
+		}
+	}
+
+	// Place those with the most shared history first, while there is still
+	// room to keep them apart
+	users := append([]int(nil), userIDs...)
+	seen := map[int]int{}
+	for _, a := range users {
+		for _, b := range users {
+			if a != b {
+				seen[a] += pairings[Pair(a, b)]
+			}
+		}
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		if seen[users[i]] != seen[users[j]] {
+			return seen[users[i]] > seen[users[j]]
+		}
+		return users[i] < users[j]
+	})
+
+	groups := make([][]int, count)
+	for _, user := range users {
+		best := -1
+		bestCost := 0
+		for i, group := range groups {
+			if len(group) >= capacity[i] {
+				continue
+			}
+			cost := groupCost(user, group, pairings)
+			if best < 0 || cost < bestCost || (cost == bestCost && len(group) < len(groups[best])) {
+				best, bestCost = i, cost
+			}
+		}
+		groups[best] = append(groups[best], user)
+	}
+
+	improveGroups(groups, pairings)
+	for _, group := range groups {
+		sort.Ints(group)
+	}
+	return groups
+}
+
+func groupCost(user int, group []int, pairings map[[2]int]int) int {
+	cost := 0
+	for _, member := range group {
+		if member != user {
+			cost += pairings[Pair(user, member)]
+		}
+	}
+	return cost
+}
+
+// improveGroups swaps members between groups while that lowers the number of
+// repeated pairings. Swaps keep the group sizes unchanged.
+func improveGroups(groups [][]int, pairings map[[2]int]int) {
+	const maxPasses = 10
+	for pass := 0; pass < maxPasses; pass++ {
+		improved := false
+		for g := range groups {
+			for h := g + 1; h < len(groups); h++ {
+				for i := range groups[g] {
+					for j := range groups[h] {
+						a, b := groups[g][i], groups[h][j]
+						before := groupCost(a, groups[g], pairings) + groupCost(b, groups[h], pairings)
+						groups[g][i], groups[h][j] = b, a
+						after := groupCost(b, groups[g], pairings) + groupCost(a, groups[h], pairings)
+						if after < before {
+							improved = true
+							continue
+						}
+						groups[g][i], groups[h][j] = a, b
+					}
+				}
+			}
+		}
+		if !improved {
+			return
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package rotation
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestRoles(t *testing.T) {
+	users := func(ids ...int) []Candidate {
+		var candidates []Candidate
+		for _, id := range ids {
+			candidates = append(candidates, Candidate{UserID: id})
+		}
+		return candidates
+	}
+
+	tests := []struct {
+		name       string
+		roles      []string
+		candidates []Candidate
+		history    History
+		want       []int
+	}{
+		{
+			name:  "no candidates",
+			roles: []string{"facilitator"},
+			want:  nil,
+		},
+		{
+			name:       "no history falls back to user ID",
+			roles:      []string{"facilitator", "notetaker"},
+			candidates: users(3, 1, 2),
+			want:       []int{1, 2},
+		},
+		{
+			name:       "fewest roles so far first",
+			roles:      []string{"facilitator", "notetaker"},
+			candidates: users(1, 2, 3),
+			history: History{Roles: map[int]map[string]int{
+				1: {"facilitator": 1, "notetaker": 1},
+				2: {"timekeeper": 1},
+			}},
+			want: []int{3, 2},
+		},
+		{
+			name:       "fewest turns at the role breaks a tie",
+			roles:      []string{"facilitator"},
+			candidates: users(1, 2),
+			history: History{Roles: map[int]map[string]int{
+				1: {"facilitator": 1},
+				2: {"notetaker": 1},
+			}},
+			want: []int{2},
+		},
+		{
+			name:       "those going are preferred",
+			roles:      []string{"facilitator"},
+			candidates: []Candidate{{UserID: 1}, {UserID: 2, Going: true}},
+			want:       []int{2},
+		},
+		{
+			name:       "least recent role breaks a tie",
+			roles:      []string{"facilitator"},
+			candidates: users(1, 2),
+			history: History{
+				Roles:    map[int]map[string]int{1: {"facilitator": 1}, 2: {"facilitator": 1}},
+				LastRole: map[int]int{1: 5, 2: 2},
+			},
+			want: []int{2},
+		},
+		{
+			name:       "second roles only once everyone has one",
+			roles:      []string{"facilitator", "notetaker", "timekeeper"},
+			candidates: users(1, 2),
+			history:    History{Roles: map[int]map[string]int{2: {"timekeeper": 3}}},
+			want:       []int{1, 2, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assignments := Roles(tt.roles, tt.candidates, tt.history)
+			var got []int
+			for i, assignment := range assignments {
+				if assignment.Role != tt.roles[i] {
+					t.Errorf("assignment %d is for %q, want %q", i, assignment.Role, tt.roles[i])
+				}
+				got = append(got, assignment.UserID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Roles() assigned %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroups(t *testing.T) {
+	tests := []struct {
+		name     string
+		userIDs  []int
+		size     int
+		pairings map[[2]int]int
+		want     [][]int
+	}{
+		{
+			name: "no users",
+			size: 3,
+			want: nil,
+		},
+		{
+			name:    "no size",
+			userIDs: []int{1, 2},
+			want:    nil,
+		},
+		{
+			name:    "sizes differ by at most one",
+			userIDs: []int{5, 4, 3, 2, 1},
+			size:    2,
+			want:    [][]int{{1, 4}, {2, 5}, {3}},
+		},
+		{
+			name:     "those who met before are kept apart",
+			userIDs:  []int{1, 2, 3, 4},
+			size:     2,
+			pairings: map[[2]int]int{Pair(1, 2): 3, Pair(3, 4): 3},
+			want:     [][]int{{1, 3}, {2, 4}},
+		},
+		{
+			name:     "fewest repeats when some are unavoidable",
+			userIDs:  []int{1, 2, 3, 4, 5, 6},
+			size:     3,
+			pairings: map[[2]int]int{Pair(1, 2): 2, Pair(1, 3): 2, Pair(2, 3): 1, Pair(4, 5): 1},
+			want:     [][]int{{1, 4, 6}, {2, 3, 5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Groups(tt.userIDs, tt.size, tt.pairings)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Groups() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImproveGroups(t *testing.T) {
+	tests := []struct {
+		name        string
+		groups      [][]int
+		pairings    map[[2]int]int
+		wantRepeats int
+	}{
+		{
+			name:        "swaps apart pairs that met before",
+			groups:      [][]int{{1, 2}, {3, 4}},
+			pairings:    map[[2]int]int{Pair(1, 2): 2, Pair(3, 4): 2},
+			wantRepeats: 0,
+		},
+		{
+			name:        "leaves groups alone when no swap helps",
+			groups:      [][]int{{1, 2}, {3, 4}},
+			pairings:    map[[2]int]int{Pair(1, 3): 1, Pair(2, 4): 1},
+			wantRepeats: 0,
+		},
+		{
+			name:        "three who all met can't all be separated",
+			groups:      [][]int{{1, 2, 3}, {4, 5, 6}},
+			pairings:    map[[2]int]int{Pair(1, 2): 1, Pair(1, 3): 1, Pair(2, 3): 1},
+			wantRepeats: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before []int
+			for _, group := range tt.groups {
+				before = append(before, len(group))
+			}
+			members := flatten(tt.groups)
+
+			improveGroups(tt.groups, tt.pairings)
+
+			var after []int
+			for _, group := range tt.groups {
+				after = append(after, len(group))
+			}
+			if !reflect.DeepEqual(after, before) {
+				t.Errorf("group sizes changed from %v to %v", before, after)
+			}
+			if got := flatten(tt.groups); !reflect.DeepEqual(got, members) {
+				t.Errorf("members changed from %v to %v", members, got)
+			}
+			if got := repeats(tt.groups, tt.pairings); got != tt.wantRepeats {
+				t.Errorf("%v has %d repeated pairings, want %d", tt.groups, got, tt.wantRepeats)
+			}
+		})
+	}
+}
+
+func flatten(groups [][]int) []int {
+	var all []int
+	for _, group := range groups {
+		all = append(all, group...)
+	}
+	sort.Ints(all)
+	return all
+}
+
+func repeats(groups [][]int, pairings map[[2]int]int) int {
+	total := 0
+	for _, group := range groups {
+		for i := range group {
+			for j := i + 1; j < len(group); j++ {
+				total += pairings[Pair(group[i], group[j])]
+			}
+		}
+	}
+	return total
+}
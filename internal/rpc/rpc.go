@@ -0,0 +1,50 @@
+// Package rpc calls Postgres functions exposed through PostgREST, for writes
+// that have to commit or fail as a whole.
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	supa "github.com/nedpals/supabase-go"
+)
+
+// Call calls a Postgres function and decodes what it returns into result,
+// unless result is nil. The client's own Rpc never resolves its path against
+// the API URL, so every call through it fails.
+func Call(sb *supa.Client, function string, params, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/%s/rpc/%s", strings.TrimRight(sb.BaseURL, "/"), supa.RestEndpoint, function)
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = sb.DB.Headers()
+
+	resp, err := sb.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		var failure struct {
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(data, &failure) == nil && failure.Message != "" {
+			return fmt.Errorf("%s failed: %s", function, failure.Message)
+		}
+		return fmt.Errorf("%s failed with status %s", function, resp.Status)
+	}
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
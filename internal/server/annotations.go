@@ -0,0 +1,441 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"hippias-fiber/internal/importer"
+	"hippias-fiber/internal/models"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const defaultPassageLimit = 10
+
+// annotationVisibleTo reports whether a top-level annotation can be seen by
+// the user.
+func annotationVisibleTo(annotation models.Annotation, userID int, facilitator bool) bool {
+	if annotation.AuthorID == userID {
+		return true
+	}
+	switch annotation.Visibility {
+	case models.AnnotationCourse:
+		return true
+	case models.AnnotationFacilitator:
+		return facilitator
+	}
+	return false
+}
+
+// listAnnotations returns the annotations on a reading that the user may see,
+// in page order, each with its replies. ?page= limits it to one page.
+func (s *Server) listAnnotations(c *fiber.Ctx) error {
+	reading, user, facilitator, err := s.readingAccess(c, c.Params("id"))
+	if err != nil {
+		return readingErrorResponse(c, err)
+	}
+
+	var annotations []models.Annotation
+	err = s.sb.DB.From("annotations").Select("*").Eq("reading_id", strconv.Itoa(reading.ID)).Execute(&annotations)
+	if err != nil {
+		log.Printf("Error querying annotations: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	pageFilter := c.QueryInt("page", 0)
+
+	userIDs := map[int]bool{}
+	for _, annotation := range annotations {
+		userIDs[annotation.AuthorID] = true
+	}
+	names, err := s.userNames(userIDs)
+	if err != nil {
+		log.Printf("Error querying annotation authors: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	replies := map[int][]models.AnnotationDto{}
+	for _, annotation := range annotations {
+		if annotation.ParentID != nil {
+			replies[*annotation.ParentID] = append(replies[*annotation.ParentID], annotationDto(annotation, names, nil))
+		}
+	}
+	dtos := []models.AnnotationDto{}
+	for _, annotation := range annotations {
+		if annotation.ParentID != nil || !annotationVisibleTo(annotation, user.ID, facilitator) {
+			continue
+		}
+		if pageFilter != 0 && (annotation.Page == nil || *annotation.Page != pageFilter) {
+			continue
+		}
+		dtos = append(dtos, annotationDto(annotation, names, replies[annotation.ID]))
+	}
+	sort.SliceStable(dtos, func(i, j int) bool {
+		a, b := anchorOrder(dtos[i].Annotation), anchorOrder(dtos[j].Annotation)
+		if a != b {
+			return a < b
+		}
+		return dtos[i].ID < dtos[j].ID
+	})
+	return c.JSON(dtos)
+}
+
+// anchorOrder sorts annotations by page and paragraph; quote-only anchors
+// come last.
+func anchorOrder(annotation models.Annotation) int {
+	order := 1 << 30
+	if annotation.Page != nil {
+		order = *annotation.Page * 10000
+		if annotation.Paragraph != nil {
+			order += *annotation.Paragraph
+		}
+	}
+	return order
+}
+
+func annotationDto(annotation models.Annotation, names map[int]string, replies []models.AnnotationDto) models.AnnotationDto {
+	sort.Slice(replies, func(i, j int) bool { return replies[i].ID < replies[j].ID })
+	if replies == nil {
+		replies = []models.AnnotationDto{}
+	}
+	dto := models.AnnotationDto{
+		Annotation: annotation,
+		AuthorName: names[annotation.AuthorID],
+		BodyHTML:   renderMarkdown(annotation.Body),
+		Replies:    replies,
+	}
+	if annotation.ParentID == nil {
+		dto.Target = w3cTarget(annotation)
+	}
+	return dto
+}
+
+// w3cTarget describes an annotation's anchor with W3C Web Annotation selectors.
+func w3cTarget(annotation models.Annotation) *models.W3CTarget {
+	target := &models.W3CTarget{
+		Source:   "/readings/" + strconv.Itoa(annotation.ReadingID),
+		Selector: []models.W3CSelector{},
+	}
+	if annotation.Exact != "" {
+		target.Selector = append(target.Selector, models.W3CSelector{
+			Type:   "TextQuoteSelector",
+			Exact:  annotation.Exact,
+			Prefix: annotation.Prefix,
+			Suffix: annotation.Suffix,
+		})
+	}
+	if annotation.Page != nil {
+		selector := models.W3CSelector{
+			Type:       "FragmentSelector",
+			ConformsTo: "http://tools.ietf.org/rfc/rfc3778",
+			Value:      "page=" + strconv.Itoa(*annotation.Page),
+		}
+		if annotation.Paragraph != nil {
+			selector.RefinedBy = &models.W3CSelector{
+				Type:  "FragmentSelector",
+				Value: "paragraph=" + strconv.Itoa(*annotation.Paragraph),
+			}
+		}
+		target.Selector = append(target.Selector, selector)
+	}
+	return target
+}
+
+// createAnnotation anchors a new annotation to a reading, or replies to one
+// when parent_id is set.
+func (s *Server) createAnnotation(c *fiber.Ctx) error {
+	reading, user, facilitator, err := s.readingAccess(c, c.Params("id"))
+	if err != nil {
+		return readingErrorResponse(c, err)
+	}
+
+	var annotation models.Annotation
+	if err := c.BodyParser(&annotation); err != nil {
+		log.Printf("Error parsing annotation: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	annotation.Body = strings.TrimSpace(annotation.Body)
+	if annotation.Body == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "body is required"})
+	}
+
+	if annotation.ParentID != nil {
+		parent, err := s.getAnnotationByID(strconv.Itoa(*annotation.ParentID))
+		if err != nil || parent.ReadingID != reading.ID || !annotationVisibleTo(*parent, user.ID, facilitator) {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "parent_id is not an annotation on this reading"})
+		}
+		if parent.ParentID != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "replies cannot be replied to; reply to the annotation instead"})
+		}
+		annotation.Visibility = parent.Visibility
+		annotation.Page, annotation.Paragraph = parent.Page, parent.Paragraph
+		annotation.Exact, annotation.Prefix, annotation.Suffix = parent.Exact, parent.Prefix, parent.Suffix
+	} else if err := validateAnnotationAnchor(&annotation); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	now := time.Now().UTC()
+	annotation.ID = 0
+	annotation.ReadingID = reading.ID
+	annotation.AuthorID = user.ID
+	annotation.CreatedAt = now
+	annotation.UpdatedAt = now
+
+	var created []models.Annotation
+	if err := s.sb.DB.From("annotations").Insert(annotation).Execute(&created); err != nil {
+		log.Printf("Error inserting annotation: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(created) > 0 {
+		annotation = created[0]
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(annotationDto(annotation, map[int]string{user.ID: user.Name}, nil))
+}
+
+// validateAnnotationAnchor checks the visibility and that a top-level
+// annotation is anchored by a page or a quote.
+func validateAnnotationAnchor(annotation *models.Annotation) error {
+	if annotation.Visibility == "" {
+		annotation.Visibility = models.AnnotationCourse
+	}
+	switch annotation.Visibility {
+	case models.AnnotationPrivate, models.AnnotationCourse, models.AnnotationFacilitator:
+	default:
+		return fmt.Errorf("visibility must be %s, %s or %s",
+			models.AnnotationPrivate, models.AnnotationCourse, models.AnnotationFacilitator)
+	}
+
+	annotation.Exact = strings.TrimSpace(annotation.Exact)
+	if annotation.Page == nil && annotation.Exact == "" {
+		return fmt.Errorf("an annotation needs a page or an exact quote to anchor it")
+	}
+	if annotation.Page != nil && *annotation.Page < 1 {
+		return fmt.Errorf("page must be positive")
+	}
+	if annotation.Paragraph != nil {
+		if annotation.Page == nil {
+			return fmt.Errorf("paragraph needs a page")
+		}
+		if *annotation.Paragraph < 1 {
+			return fmt.Errorf("paragraph must be positive")
+		}
+	}
+	if annotation.Exact == "" && (annotation.Prefix != "" || annotation.Suffix != "") {
+		return fmt.Errorf("prefix and suffix need an exact quote")
+	}
+	return nil
+}
+
+var errAnnotationNotFound = errors.New("annotation not found")
+
+func (s *Server) getAnnotationByID(annotationID string) (*models.Annotation, error) {
+	var annotations []models.Annotation
+	if err := s.sb.DB.From("annotations").Select("*").Eq("id", annotationID).Execute(&annotations); err != nil {
+		return nil, err
+	}
+	if len(annotations) == 0 {
+		return nil, errAnnotationNotFound
+	}
+	return &annotations[0], nil
+}
+
+// annotationAccess loads an annotation the user can see, with whether they
+// facilitate its course.
+func (s *Server) annotationAccess(c *fiber.Ctx) (*models.Annotation, *models.User, bool, error) {
+	annotation, err := s.getAnnotationByID(c.Params("id"))
+	if err != nil {
+		return nil, nil, false, err
+	}
+	_, user, facilitator, err := s.readingAccess(c, strconv.Itoa(annotation.ReadingID))
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	anchor := annotation
+	if annotation.ParentID != nil {
+		if anchor, err = s.getAnnotationByID(strconv.Itoa(*annotation.ParentID)); err != nil {
+			return nil, nil, false, err
+		}
+	}
+	if !annotationVisibleTo(*anchor, user.ID, facilitator) && annotation.AuthorID != user.ID {
+		return nil, nil, false, errAnnotationNotFound
+	}
+	return annotation, user, facilitator, nil
+}
+
+func annotationErrorResponse(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errAnnotationNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Annotation not found"})
+	}
+	return readingErrorResponse(c, err)
+}
+
+// updateAnnotation lets the author change the body, and for top-level
+// annotations the anchor and visibility. Replies follow a visibility change.
+func (s *Server) updateAnnotation(c *fiber.Ctx) error {
+	existing, user, _, err := s.annotationAccess(c)
+	if err != nil {
+		return annotationErrorResponse(c, err)
+	}
+	if existing.AuthorID != user.ID {
+		return authErrorResponse(c, errForbidden)
+	}
+
+	var annotation models.Annotation
+	if err := c.BodyParser(&annotation); err != nil {
+		log.Printf("Error parsing annotation: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	annotation.Body = strings.TrimSpace(annotation.Body)
+	if annotation.Body == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "body is required"})
+	}
+
+	changes := map[string]interface{}{"body": annotation.Body, "updated_at": time.Now().UTC()}
+	if existing.ParentID == nil {
+		if err := validateAnnotationAnchor(&annotation); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+		}
+		changes["visibility"] = annotation.Visibility
+		changes["page"] = annotation.Page
+		changes["paragraph"] = annotation.Paragraph
+		changes["exact"] = annotation.Exact
+		changes["prefix"] = annotation.Prefix
+		changes["suffix"] = annotation.Suffix
+	}
+
+	var updated []models.Annotation
+	err = s.sb.DB.From("annotations").Update(changes).Eq("id", strconv.Itoa(existing.ID)).Execute(&updated)
+	if err != nil {
+		log.Printf("Error updating annotation: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if existing.ParentID == nil {
+		delete(changes, "body")
+		delete(changes, "updated_at")
+		var replies []models.Annotation
+		err = s.sb.DB.From("annotations").Update(changes).Eq("parent_id", strconv.Itoa(existing.ID)).Execute(&replies)
+		if err != nil {
+			log.Printf("Error updating annotation replies: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+	}
+	if len(updated) > 0 {
+		existing = &updated[0]
+	}
+	return c.JSON(annotationDto(*existing, map[int]string{user.ID: user.Name}, nil))
+}
+
+// deleteAnnotation removes an annotation and its replies. Authors can delete
+// their own; the facilitator can delete any annotation they can see.
+func (s *Server) deleteAnnotation(c *fiber.Ctx) error {
+	annotation, user, facilitator, err := s.annotationAccess(c)
+	if err != nil {
+		return annotationErrorResponse(c, err)
+	}
+	if annotation.AuthorID != user.ID && !facilitator {
+		return authErrorResponse(c, errForbidden)
+	}
+
+	annotationID := strconv.Itoa(annotation.ID)
+	var deleted []models.Annotation
+	err = s.sb.DB.From("annotations").Delete().Eq("parent_id", annotationID).Execute(&deleted)
+	if err == nil {
+		err = s.sb.DB.From("annotations").Delete().Eq("id", annotationID).Execute(&deleted)
+	}
+	if err != nil {
+		log.Printf("Error deleting annotation: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	log.Printf("Deleted annotation with ID: %s", annotationID)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// getAnnotatedPassages shows the facilitator which passages of a reading drew
+// the most annotations. Private annotations are not counted. ?limit= caps the
+// list (default 10).
+func (s *Server) getAnnotatedPassages(c *fiber.Ctx) error {
+	reading, _, facilitator, err := s.readingAccess(c, c.Params("id"))
+	if err != nil {
+		return readingErrorResponse(c, err)
+	}
+	if !facilitator {
+		return authErrorResponse(c, errForbidden)
+	}
+	limit := c.QueryInt("limit", defaultPassageLimit)
+	if limit < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "limit must be positive"})
+	}
+
+	var annotations []models.Annotation
+	err = s.sb.DB.From("annotations").Select("*").Eq("reading_id", strconv.Itoa(reading.ID)).Execute(&annotations)
+	if err != nil {
+		log.Printf("Error querying annotations: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	return c.JSON(annotatedPassages(annotations, limit))
+}
+
+// annotatedPassages groups shared annotations by the passage they anchor to:
+// the same page and paragraph, or the same quote (compared ignoring case and
+// punctuation). Passages are ranked by annotations, then annotators.
+func annotatedPassages(annotations []models.Annotation, limit int) []models.AnnotatedPassage {
+	byKey := map[string]*models.AnnotatedPassage{}
+	annotators := map[string]map[int]bool{}
+	var keys []string
+	for _, annotation := range annotations {
+		if annotation.Visibility == models.AnnotationPrivate {
+			continue
+		}
+		key := passageKey(annotation)
+		passage, ok := byKey[key]
+		if !ok {
+			passage = &models.AnnotatedPassage{Page: annotation.Page, Paragraph: annotation.Paragraph, Exact: annotation.Exact}
+			byKey[key] = passage
+			annotators[key] = map[int]bool{}
+			keys = append(keys, key)
+		}
+		if annotation.ParentID != nil {
+			passage.Replies++
+		} else {
+			passage.Annotations++
+		}
+		annotators[key][annotation.AuthorID] = true
+	}
+
+	passages := make([]models.AnnotatedPassage, 0, len(keys))
+	for _, key := range keys {
+		byKey[key].Annotators = len(annotators[key])
+		passages = append(passages, *byKey[key])
+	}
+	sort.SliceStable(passages, func(i, j int) bool {
+		if passages[i].Annotations != passages[j].Annotations {
+			return passages[i].Annotations > passages[j].Annotations
+		}
+		return passages[i].Annotators > passages[j].Annotators
+	})
+	if len(passages) > limit {
+		passages = passages[:limit]
+	}
+	return passages
+}
+
+func passageKey(annotation models.Annotation) string {
+	if annotation.Exact != "" {
+		return "quote:" + importer.NormalizeTitle(annotation.Exact)
+	}
+	key := "page:"
+	if annotation.Page != nil {
+		key += strconv.Itoa(*annotation.Page)
+	}
+	if annotation.Paragraph != nil {
+		key += "/" + strconv.Itoa(*annotation.Paragraph)
+	}
+	return key
+}
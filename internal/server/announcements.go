@@ -0,0 +1,445 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"hippias-fiber/internal/feed"
+	"hippias-fiber/internal/models"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Most recent announcements included in a feed
+const feedSize = 50
+
+// listAnnouncements returns a course's announcements, pinned ones first and
+// then newest first. The facilitator also sees those scheduled for later.
+func (s *Server) listAnnouncements(c *fiber.Ctx) error {
+	courseID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid course ID"})
+	}
+	_, facilitator, err := s.requireCourseMember(c, courseID)
+	if err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	announcements, err := s.courseAnnouncements([]int{courseID}, !facilitator, time.Now())
+	if err != nil {
+		log.Printf("Error querying announcements: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	sort.SliceStable(announcements, func(i, j int) bool {
+		return announcements[i].Pinned && !announcements[j].Pinned
+	})
+	return c.JSON(announcements)
+}
+
+func (s *Server) getAnnouncement(c *fiber.Ctx) error {
+	announcement, err := s.getAnnouncementByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying announcement: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Announcement not found"})
+	}
+	_, facilitator, err := s.requireCourseMember(c, announcement.CourseID)
+	if err != nil {
+		return authErrorResponse(c, err)
+	}
+	now := time.Now()
+	if !facilitator && announcement.PublishAt.After(now) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Announcement not found"})
+	}
+
+	dtos, err := s.announcementDtos([]models.Announcement{*announcement}, now)
+	if err != nil {
+		log.Printf("Error querying announcement authors: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	return c.JSON(dtos[0])
+}
+
+func (s *Server) createAnnouncement(c *fiber.Ctx) error {
+	courseID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid course ID"})
+	}
+	user, err := s.requireFacilitator(c, courseID)
+	if err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var body models.AnnouncementRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Printf("Error parsing announcement: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	now := time.Now().UTC()
+	announcement := models.Announcement{CourseID: courseID, AuthorID: user.ID, CreatedAt: now}
+	if err := applyAnnouncementRequest(&announcement, body, now); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	var created []models.Announcement
+	if err := s.sb.DB.From("announcements").Insert(announcement).Execute(&created); err != nil {
+		log.Printf("Error inserting announcement: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(created) > 0 {
+		announcement = created[0]
+	}
+
+	log.Printf("Created announcement %d for course %d", announcement.ID, courseID)
+	return c.Status(fiber.StatusCreated).JSON(models.AnnouncementDto{
+		Announcement: announcement,
+		AuthorName:   user.Name,
+		BodyHTML:     renderMarkdown(announcement.Body),
+		Published:    !announcement.PublishAt.After(now),
+	})
+}
+
+func (s *Server) updateAnnouncement(c *fiber.Ctx) error {
+	announcement, err := s.getAnnouncementByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying announcement: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Announcement not found"})
+	}
+	if _, err := s.requireFacilitator(c, announcement.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var body models.AnnouncementRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Printf("Error parsing announcement: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	now := time.Now().UTC()
+	if body.PublishAt == nil {
+		body.PublishAt = &announcement.PublishAt
+	}
+	if err := applyAnnouncementRequest(announcement, body, now); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	err = s.sb.DB.From("announcements").Update(announcement).Eq("id", strconv.Itoa(announcement.ID)).Execute(nil)
+	if err != nil {
+		log.Printf("Error updating announcement: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	dtos, err := s.announcementDtos([]models.Announcement{*announcement}, now)
+	if err != nil {
+		log.Printf("Error querying announcement authors: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	return c.JSON(dtos[0])
+}
+
+func (s *Server) deleteAnnouncement(c *fiber.Ctx) error {
+	announcement, err := s.getAnnouncementByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying announcement: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Announcement not found"})
+	}
+	if _, err := s.requireFacilitator(c, announcement.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	err = s.sb.DB.From("announcements").Delete().Eq("id", strconv.Itoa(announcement.ID)).Execute(nil)
+	if err != nil {
+		log.Printf("Error deleting announcement: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	log.Printf("Deleted announcement with ID: %d", announcement.ID)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func applyAnnouncementRequest(announcement *models.Announcement, body models.AnnouncementRequest, now time.Time) error {
+	announcement.Title = strings.TrimSpace(body.Title)
+	announcement.Body = strings.TrimSpace(body.Body)
+	if announcement.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+	if announcement.Body == "" {
+		return fmt.Errorf("body is required")
+	}
+	announcement.Pinned = body.Pinned
+	announcement.PublishAt = now
+	if body.PublishAt != nil && !body.PublishAt.IsZero() {
+		announcement.PublishAt = body.PublishAt.UTC()
+	}
+	announcement.UpdatedAt = now
+	return nil
+}
+
+func (s *Server) getAnnouncementByID(announcementID string) (*models.Announcement, error) {
+	var announcement models.Announcement
+	err := s.sb.DB.From("announcements").Select("*").Single().Eq("id", announcementID).Execute(&announcement)
+	if err != nil {
+		return nil, err
+	}
+	return &announcement, nil
+}
+
+// courseAnnouncements returns the announcements of the given courses, newest
+// first, leaving out those scheduled after now when publishedOnly is set.
+func (s *Server) courseAnnouncements(courseIDs []int, publishedOnly bool, now time.Time) ([]models.AnnouncementDto, error) {
+	if len(courseIDs) == 0 {
+		return []models.AnnouncementDto{}, nil
+	}
+	ids := make([]string, len(courseIDs))
+	for i, id := range courseIDs {
+		ids[i] = strconv.Itoa(id)
+	}
+	var rows []models.Announcement
+	query := s.sb.DB.From("announcements").Select("*")
+	query.In("course_id", ids)
+	if publishedOnly {
+		query.Lte("publish_at", now.UTC().Format(time.RFC3339))
+	}
+	if err := query.Execute(&rows); err != nil {
+		return nil, err
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].PublishAt.After(rows[j].PublishAt) })
+	return s.announcementDtos(rows, now)
+}
+
+func (s *Server) announcementDtos(announcements []models.Announcement, now time.Time) ([]models.AnnouncementDto, error) {
+	authors := map[int]bool{}
+	for _, announcement := range announcements {
+		authors[announcement.AuthorID] = true
+	}
+	names, err := s.userNames(authors)
+	if err != nil {
+		return nil, err
+	}
+	dtos := make([]models.AnnouncementDto, len(announcements))
+	for i, announcement := range announcements {
+		dtos[i] = models.AnnouncementDto{
+			Announcement: announcement,
+			AuthorName:   names[announcement.AuthorID],
+			BodyHTML:     renderMarkdown(announcement.Body),
+			Published:    !announcement.PublishAt.After(now),
+		}
+	}
+	return dtos, nil
+}
+
+// getCourseFeed serves a course's published announcements as Atom, or RSS
+// with ?format=rss. Feeds of public courses are open to anyone; others need
+// the course's feed token as ?token=, or a signed-in member.
+func (s *Server) getCourseFeed(c *fiber.Ctx) error {
+	course, err := s.getCourseByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying course: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Course not found"})
+	}
+	if !course.Public {
+		if token := c.Query("token"); token != "" {
+			feedToken, err := s.findCourseFeedToken(course.ID)
+			if err != nil {
+				log.Printf("Error querying feed token: %v", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+			}
+			if feedToken == nil || subtle.ConstantTimeCompare([]byte(token), []byte(feedToken.Token)) != 1 {
+				return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Message: "Invalid feed token"})
+			}
+		} else if _, _, err := s.requireCourseMember(c, course.ID); err != nil {
+			return authErrorResponse(c, err)
+		}
+	}
+
+	announcements, err := s.courseAnnouncements([]int{course.ID}, true, time.Now())
+	if err != nil {
+		log.Printf("Error querying announcements: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	base := c.BaseURL()
+	return sendFeed(c, feed.Feed{
+		Title:       course.Title,
+		Description: fmt.Sprintf("Announcements for %s", course.Title),
+		Link:        fmt.Sprintf("%s/courses/%d", base, course.ID),
+		Self:        base + c.Path(),
+		Items:       announcementItems(announcements, base, nil),
+	})
+}
+
+// getPublicFeed serves the announcements of all public courses together.
+func (s *Server) getPublicFeed(c *fiber.Ctx) error {
+	var courses []models.Course
+	if err := s.sb.DB.From("courses").Select("*").Eq("public", "true").Execute(&courses); err != nil {
+		log.Printf("Error querying public courses: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	ids := make([]int, len(courses))
+	titles := map[int]string{}
+	for i, course := range courses {
+		ids[i] = course.ID
+		titles[course.ID] = course.Title
+	}
+
+	announcements, err := s.courseAnnouncements(ids, true, time.Now())
+	if err != nil {
+		log.Printf("Error querying announcements: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	base := c.BaseURL()
+	return sendFeed(c, feed.Feed{
+		Title:       "Course announcements",
+		Description: "Announcements from all public courses",
+		Link:        base + "/courses",
+		Self:        base + c.Path(),
+		Items:       announcementItems(announcements, base, titles),
+	})
+}
+
+// announcementItems turns announcements into feed items, prefixing their
+// titles with the course's when courseTitles is given.
+func announcementItems(announcements []models.AnnouncementDto, base string, courseTitles map[int]string) []feed.Item {
+	if len(announcements) > feedSize {
+		announcements = announcements[:feedSize]
+	}
+	items := make([]feed.Item, len(announcements))
+	for i, announcement := range announcements {
+		title := announcement.Title
+		if courseTitles != nil {
+			title = fmt.Sprintf("%s: %s", courseTitles[announcement.CourseID], title)
+		}
+		link := fmt.Sprintf("%s/announcements/%d", base, announcement.ID)
+		items[i] = feed.Item{
+			ID:          link,
+			Title:       title,
+			Link:        link,
+			AuthorName:  announcement.AuthorName,
+			Published:   announcement.PublishAt,
+			Updated:     announcement.UpdatedAt,
+			ContentHTML: announcement.BodyHTML,
+		}
+	}
+	return items
+}
+
+func sendFeed(c *fiber.Ctx, f feed.Feed) error {
+	// A feed is as fresh as its newest item
+	for _, item := range f.Items {
+		if item.Updated.After(f.Updated) {
+			f.Updated = item.Updated
+		}
+	}
+	if f.Updated.IsZero() {
+		f.Updated = time.Now()
+	}
+
+	var data []byte
+	var err error
+	switch c.Query("format", "atom") {
+	case "atom":
+		data, err = feed.Atom(f)
+		c.Set(fiber.HeaderContentType, "application/atom+xml; charset=utf-8")
+	case "rss":
+		f.Self += "?format=rss"
+		data, err = feed.RSS(f)
+		c.Set(fiber.HeaderContentType, "application/rss+xml; charset=utf-8")
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "format must be atom or rss"})
+	}
+	if err != nil {
+		log.Printf("Error rendering feed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	return c.Send(data)
+}
+
+// getCourseFeedToken gives a course member the course's feed token and the
+// feed URL to subscribe to, creating the token on first use.
+func (s *Server) getCourseFeedToken(c *fiber.Ctx) error {
+	courseID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid course ID"})
+	}
+	if _, _, err := s.requireCourseMember(c, courseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	feedToken, err := s.findCourseFeedToken(courseID)
+	if err != nil {
+		log.Printf("Error querying feed token: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if feedToken == nil {
+		feedToken, err = s.saveCourseFeedToken(courseID)
+		if err != nil {
+			log.Printf("Error creating feed token: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+	}
+	return c.JSON(courseFeedTokenDto(c, feedToken))
+}
+
+// rotateCourseFeedToken replaces a course's feed token, cutting off feed
+// readers subscribed with the old one.
+func (s *Server) rotateCourseFeedToken(c *fiber.Ctx) error {
+	courseID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid course ID"})
+	}
+	if _, err := s.requireFacilitator(c, courseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	feedToken, err := s.saveCourseFeedToken(courseID)
+	if err != nil {
+		log.Printf("Error rotating feed token: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	log.Printf("Rotated feed token of course %d", courseID)
+	return c.JSON(courseFeedTokenDto(c, feedToken))
+}
+
+func (s *Server) findCourseFeedToken(courseID int) (*models.CourseFeedToken, error) {
+	var tokens []models.CourseFeedToken
+	err := s.sb.DB.From("course_feed_tokens").
+		Select("*").
+		Eq("course_id", strconv.Itoa(courseID)).
+		Execute(&tokens)
+	if err != nil || len(tokens) == 0 {
+		return nil, err
+	}
+	return &tokens[0], nil
+}
+
+// saveCourseFeedToken stores a new random token for a course, replacing any
+// previous one.
+func (s *Server) saveCourseFeedToken(courseID int) (*models.CourseFeedToken, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	feedToken := models.CourseFeedToken{
+		CourseID:  courseID,
+		Token:     base64.RawURLEncoding.EncodeToString(raw),
+		CreatedAt: time.Now().UTC(),
+	}
+	var saved []models.CourseFeedToken
+	if err := s.sb.DB.From("course_feed_tokens").Upsert(feedToken).Execute(&saved); err != nil {
+		return nil, err
+	}
+	if len(saved) > 0 {
+		feedToken = saved[0]
+	}
+	return &feedToken, nil
+}
+
+func courseFeedTokenDto(c *fiber.Ctx, feedToken *models.CourseFeedToken) models.CourseFeedTokenDto {
+	return models.CourseFeedTokenDto{
+		Token:   feedToken.Token,
+		FeedURL: fmt.Sprintf("%s/courses/%d/announcements/feed?token=%s", c.BaseURL(), feedToken.CourseID, feedToken.Token),
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	supa "github.com/nedpals/supabase-go"
+)
+
+func TestCourseFeedToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		public     bool
+		query      string
+		wantStatus int
+	}{
+		{name: "public course needs nothing", public: true, wantStatus: fiber.StatusOK},
+		{name: "matching token", query: "?token=s3cret", wantStatus: fiber.StatusOK},
+		{name: "wrong token", query: "?token=guess", wantStatus: fiber.StatusUnauthorized},
+		{name: "sign-in token is not accepted in the URL", query: "?access_token=jwt", wantStatus: fiber.StatusUnauthorized},
+		{name: "nothing", wantStatus: fiber.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.HasSuffix(r.URL.Path, "/courses"):
+					// Fetched with Single, so as an object
+					if tt.public {
+						w.Write([]byte(`{"id": 3, "title": "Plato", "public": true}`))
+					} else {
+						w.Write([]byte(`{"id": 3, "title": "Plato", "public": false}`))
+					}
+				case strings.HasSuffix(r.URL.Path, "/course_feed_tokens"):
+					w.Write([]byte(`[{"course_id": 3, "token": "s3cret"}]`))
+				default:
+					w.Write([]byte(`[]`))
+				}
+			}))
+			defer db.Close()
+
+			s := &Server{sb: supa.CreateClient(db.URL, "service-key")}
+			app := fiber.New()
+			app.Get("/courses/:id/announcements/feed", s.getCourseFeed)
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/courses/3/announcements/feed"+tt.query, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,367 @@
+package server
+
+import (
+	"fmt"
+	"hippias-fiber/internal/models"
+	"hippias-fiber/internal/rotation"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const defaultGroupSize = 4
+
+// getAssignments returns a discussion's roles and breakout groups.
+// Participants only see them once published.
+func (s *Server) getAssignments(c *fiber.Ctx) error {
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	_, facilitator, err := s.requireCourseMember(c, discussion.CourseID)
+	if err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	assignments, err := s.findAssignments(discussion.ID)
+	if err != nil {
+		log.Printf("Error querying assignments: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if assignments == nil || (!assignments.Published && !facilitator) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "No assignments have been published for this discussion"})
+	}
+	return c.JSON(assignments)
+}
+
+// generateAssignments draws up roles and breakout groups as an unpublished
+// draft. Participants who RSVPed that they are not going are left out.
+func (s *Server) generateAssignments(c *fiber.Ctx) error {
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	if _, err := s.requireFacilitator(c, discussion.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var body models.GenerateAssignmentsRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			log.Printf("Error parsing assignments request: %v", err)
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+		}
+	}
+	roles := models.DefaultRoles
+	if len(body.Roles) > 0 {
+		roles = nil
+		for _, role := range body.Roles {
+			if role = strings.TrimSpace(role); role != "" {
+				roles = append(roles, role)
+			}
+		}
+	}
+	groupSize := body.GroupSize
+	if groupSize == 0 {
+		groupSize = defaultGroupSize
+	}
+	if groupSize < -1 || groupSize == 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "group_size must be at least 2, or -1 for no groups"})
+	}
+
+	candidates, err := s.assignmentCandidates(discussion)
+	if err != nil {
+		log.Printf("Error querying participants: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	history, err := s.assignmentHistory(discussion)
+	if err != nil {
+		log.Printf("Error querying assignment history: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	assignments := models.DiscussionAssignments{
+		DiscussionID: discussion.ID,
+		Roles:        []models.RoleAssignment{},
+		Groups:       []models.BreakoutGroup{},
+	}
+	for _, assignment := range rotation.Roles(roles, candidates, history) {
+		assignments.Roles = append(assignments.Roles, models.RoleAssignment{Role: assignment.Role, UserID: assignment.UserID})
+	}
+	userIDs := make([]int, len(candidates))
+	for i, candidate := range candidates {
+		userIDs[i] = candidate.UserID
+	}
+	for i, group := range rotation.Groups(userIDs, groupSize, history.Pairings) {
+		assignments.Groups = append(assignments.Groups, models.BreakoutGroup{Number: i + 1, UserIDs: group})
+	}
+
+	saved, err := s.saveAssignments(assignments)
+	if err != nil {
+		log.Printf("Error saving assignments: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	return c.JSON(saved)
+}
+
+// updateAssignments replaces the roles and groups with the facilitator's
+// choice. The result is a draft again until it is published.
+func (s *Server) updateAssignments(c *fiber.Ctx) error {
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	if _, err := s.requireFacilitator(c, discussion.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var body models.AssignmentsRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Printf("Error parsing assignments: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	participants, err := s.courseParticipantIDs(discussion.CourseID)
+	if err != nil {
+		log.Printf("Error querying course participants: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	assignments, err := validateAssignments(body, participants)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	assignments.DiscussionID = discussion.ID
+
+	saved, err := s.saveAssignments(assignments)
+	if err != nil {
+		log.Printf("Error saving assignments: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	return c.JSON(saved)
+}
+
+func validateAssignments(body models.AssignmentsRequest, participantIDs []int) (models.DiscussionAssignments, error) {
+	enrolled := map[int]bool{}
+	for _, id := range participantIDs {
+		enrolled[id] = true
+	}
+	assignments := models.DiscussionAssignments{Roles: []models.RoleAssignment{}, Groups: []models.BreakoutGroup{}}
+
+	for _, assignment := range body.Roles {
+		assignment.Role = strings.TrimSpace(assignment.Role)
+		if assignment.Role == "" {
+			return assignments, fmt.Errorf("each role needs a name")
+		}
+		if !enrolled[assignment.UserID] {
+			return assignments, fmt.Errorf("user %d is not enrolled in this course", assignment.UserID)
+		}
+		assignments.Roles = append(assignments.Roles, assignment)
+	}
+
+	grouped := map[int]bool{}
+	for _, group := range body.Groups {
+		if len(group.UserIDs) == 0 {
+			continue
+		}
+		for _, id := range group.UserIDs {
+			if !enrolled[id] {
+				return assignments, fmt.Errorf("user %d is not enrolled in this course", id)
+			}
+			if grouped[id] {
+				return assignments, fmt.Errorf("user %d is in more than one group", id)
+			}
+			grouped[id] = true
+		}
+		assignments.Groups = append(assignments.Groups, models.BreakoutGroup{
+			Number:  len(assignments.Groups) + 1,
+			UserIDs: group.UserIDs,
+		})
+	}
+	return assignments, nil
+}
+
+// publishAssignments makes the current roles and groups visible to participants.
+func (s *Server) publishAssignments(c *fiber.Ctx) error {
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	if _, err := s.requireFacilitator(c, discussion.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	assignments, err := s.findAssignments(discussion.ID)
+	if err != nil {
+		log.Printf("Error querying assignments: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if assignments == nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Generate or set assignments before publishing them"})
+	}
+
+	now := time.Now().UTC()
+	assignments.Published = true
+	assignments.PublishedAt = &now
+	assignments.UpdatedAt = now
+	err = s.sb.DB.From("discussion_assignments").Update(assignments).Eq("id", strconv.Itoa(assignments.ID)).Execute(nil)
+	if err != nil {
+		log.Printf("Error publishing assignments: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	log.Printf("Published assignments for discussion %d", discussion.ID)
+	return c.JSON(assignments)
+}
+
+func (s *Server) findAssignments(discussionID int) (*models.DiscussionAssignments, error) {
+	var rows []models.DiscussionAssignments
+	err := s.sb.DB.From("discussion_assignments").Select("*").Eq("discussion_id", strconv.Itoa(discussionID)).Execute(&rows)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return &rows[0], nil
+}
+
+// saveAssignments stores assignments as the discussion's unpublished draft.
+func (s *Server) saveAssignments(assignments models.DiscussionAssignments) (models.DiscussionAssignments, error) {
+	existing, err := s.findAssignments(assignments.DiscussionID)
+	if err != nil {
+		return assignments, err
+	}
+	assignments.Published = false
+	assignments.PublishedAt = nil
+	assignments.UpdatedAt = time.Now().UTC()
+
+	var saved []models.DiscussionAssignments
+	if existing != nil {
+		assignments.ID = existing.ID
+		err = s.sb.DB.From("discussion_assignments").Update(assignments).Eq("id", strconv.Itoa(existing.ID)).Execute(&saved)
+	} else {
+		assignments.ID = 0
+		err = s.sb.DB.From("discussion_assignments").Insert(assignments).Execute(&saved)
+	}
+	if err != nil {
+		return assignments, err
+	}
+	if len(saved) > 0 {
+		assignments = saved[0]
+	}
+	return assignments, nil
+}
+
+// assignmentCandidates returns the course's participants, leaving out those
+// who RSVPed that they are not going to the discussion.
+func (s *Server) assignmentCandidates(discussion *models.Discussion) ([]rotation.Candidate, error) {
+	participants, err := s.courseParticipantIDs(discussion.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	var rsvps []models.DiscussionRsvp
+	err = s.sb.DB.From("discussion_rsvps").Select("*").Eq("discussion_id", strconv.Itoa(discussion.ID)).Execute(&rsvps)
+	if err != nil {
+		return nil, err
+	}
+	responses := map[int]string{}
+	for _, rsvp := range rsvps {
+		responses[rsvp.UserID] = rsvp.Response
+	}
+
+	var candidates []rotation.Candidate
+	for _, id := range participants {
+		if responses[id] == models.RsvpNotGoing {
+			continue
+		}
+		candidates = append(candidates, rotation.Candidate{UserID: id, Going: responses[id] == models.RsvpGoing})
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].UserID < candidates[j].UserID })
+	return candidates, nil
+}
+
+// assignmentHistory collects the published roles and groups of the course's
+// earlier discussions. Where attendance was taken, only those present count
+// as having had their role or met their group.
+func (s *Server) assignmentHistory(discussion *models.Discussion) (rotation.History, error) {
+	history := rotation.History{
+		Roles:    map[int]map[string]int{},
+		LastRole: map[int]int{},
+		Pairings: map[[2]int]int{},
+	}
+
+	var discussions []models.Discussion
+	err := s.sb.DB.From("discussions").Select("*").Eq("course_id", strconv.Itoa(discussion.CourseID)).Execute(&discussions)
+	if err != nil {
+		return history, err
+	}
+	var earlier []models.Discussion
+	for _, other := range discussions {
+		if other.ID != discussion.ID && other.DateTime.Before(discussion.DateTime) {
+			earlier = append(earlier, other)
+		}
+	}
+	if len(earlier) == 0 {
+		return history, nil
+	}
+	sort.Slice(earlier, func(i, j int) bool { return earlier[i].DateTime.Before(earlier[j].DateTime) })
+	ids := make([]string, len(earlier))
+	position := map[int]int{}
+	for i, other := range earlier {
+		ids[i] = strconv.Itoa(other.ID)
+		position[other.ID] = i + 1
+	}
+
+	var assignments []models.DiscussionAssignments
+	err = s.sb.DB.From("discussion_assignments").Select("*").In("discussion_id", ids).Execute(&assignments)
+	if err != nil {
+		return history, err
+	}
+	var attendance []models.DiscussionAttendance
+	err = s.sb.DB.From("discussion_attendance").Select("*").In("discussion_id", ids).Execute(&attendance)
+	if err != nil {
+		return history, err
+	}
+	recorded := map[int]bool{}
+	present := map[int]map[int]bool{}
+	for _, row := range attendance {
+		recorded[row.DiscussionID] = true
+		if present[row.DiscussionID] == nil {
+			present[row.DiscussionID] = map[int]bool{}
+		}
+		present[row.DiscussionID][row.UserID] = row.Status == models.AttendancePresent
+	}
+	attended := func(discussionID, userID int) bool {
+		return !recorded[discussionID] || present[discussionID][userID]
+	}
+
+	for _, past := range assignments {
+		if !past.Published {
+			continue
+		}
+		for _, role := range past.Roles {
+			if !attended(past.DiscussionID, role.UserID) {
+				continue
+			}
+			if history.Roles[role.UserID] == nil {
+				history.Roles[role.UserID] = map[string]int{}
+			}
+			history.Roles[role.UserID][role.Role]++
+			history.LastRole[role.UserID] = max(history.LastRole[role.UserID], position[past.DiscussionID])
+		}
+		for _, group := range past.Groups {
+			for i, a := range group.UserIDs {
+				for _, b := range group.UserIDs[i+1:] {
+					if attended(past.DiscussionID, a) && attended(past.DiscussionID, b) {
+						history.Pairings[rotation.Pair(a, b)]++
+					}
+				}
+			}
+		}
+	}
+	return history, nil
+}
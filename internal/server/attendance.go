@@ -0,0 +1,279 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hippias-fiber/internal/models"
+	"hippias-fiber/internal/rpc"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	defaultCheckInTTL = 10 * time.Minute
+	maxCheckInTTL     = 2 * time.Hour
+)
+
+var errInvalidCheckInToken = errors.New("invalid or expired check-in token")
+
+func validAttendanceStatus(status string) bool {
+	switch status {
+	case models.AttendancePresent, models.AttendanceAbsent, models.AttendanceExcused:
+		return true
+	}
+	return false
+}
+
+// recordBulkAttendance lets the facilitator submit the whole roster for a
+// discussion at once. Existing rows for a user are updated in place, and the
+// last record wins when a user appears twice. Either every record is saved or
+// none is.
+func (s *Server) recordBulkAttendance(c *fiber.Ctx) error {
+	discussionID := c.Params("id")
+
+	discussion, err := s.getDiscussionByID(discussionID)
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	if _, err := s.requireFacilitator(c, discussion.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var body models.BulkAttendanceRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Printf("Error parsing bulk attendance: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(body.Records) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "records must not be empty"})
+	}
+
+	var participants []models.CourseParticipant
+	err = s.sb.DB.From("course_participants").
+		Select("*").
+		Eq("course_id", strconv.Itoa(discussion.CourseID)).
+		Execute(&participants)
+	if err != nil {
+		log.Printf("Error querying course participants: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	enrolled := map[int]bool{}
+	for _, participant := range participants {
+		enrolled[participant.UserID] = true
+	}
+
+	records := dedupeAttendanceRecords(body.Records)
+	for _, record := range records {
+		if !validAttendanceStatus(record.Status) {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: fmt.Sprintf("invalid status %q for user %d", record.Status, record.UserID)})
+		}
+		if !enrolled[record.UserID] {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: fmt.Sprintf("user %d is not enrolled in this course", record.UserID)})
+		}
+	}
+
+	saved, err := s.recordAttendance(discussion.ID, records)
+	if err != nil {
+		log.Printf("Error recording discussion attendance: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	for _, row := range saved {
+		s.publishCourseEvent(discussion.CourseID, models.EventAttendanceRecorded, row)
+	}
+
+	log.Printf("Recorded attendance for %d participants in discussion %s", len(saved), discussionID)
+	return c.JSON(saved)
+}
+
+// dedupeAttendanceRecords keeps the last record for each user, in the order
+// users first appear.
+func dedupeAttendanceRecords(records []models.AttendanceRecord) []models.AttendanceRecord {
+	index := map[int]int{}
+	var deduped []models.AttendanceRecord
+	for _, record := range records {
+		if i, ok := index[record.UserID]; ok {
+			deduped[i] = record
+			continue
+		}
+		index[record.UserID] = len(deduped)
+		deduped = append(deduped, record)
+	}
+	return deduped
+}
+
+// recordAttendance upserts attendance rows for a discussion in one call to the
+// record_attendance function in sql/record_attendance.sql.
+func (s *Server) recordAttendance(discussionID int, records []models.AttendanceRecord) ([]models.DiscussionAttendance, error) {
+	var saved []models.DiscussionAttendance
+	params := map[string]interface{}{"discussion_id": discussionID, "records": records}
+	if err := rpc.Call(s.sb, "record_attendance", params, &saved); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// recordCheckIn marks a participant present through the same function as
+// recordAttendance, stamping the row with the check-in time.
+func (s *Server) recordCheckIn(discussionID, userID int, at time.Time) ([]models.DiscussionAttendance, error) {
+	var saved []models.DiscussionAttendance
+	records := []models.AttendanceRecord{{UserID: userID, Status: models.AttendancePresent}}
+	params := map[string]interface{}{"discussion_id": discussionID, "records": records, "checked_in_at": at}
+	if err := rpc.Call(s.sb, "record_attendance", params, &saved); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// createCheckInToken issues a signed token for a discussion which the facilitator
+// displays as a QR code; participants redeem it through /check-in before it expires.
+func (s *Server) createCheckInToken(c *fiber.Ctx) error {
+	if len(s.checkInSecret) == 0 {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(ErrorResponse{Message: "Self check-in is not configured"})
+	}
+
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	if _, err := s.requireFacilitator(c, discussion.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	ttl := defaultCheckInTTL
+	if minutes := c.QueryInt("ttl_minutes"); minutes > 0 {
+		ttl = time.Duration(minutes) * time.Minute
+	}
+	if ttl > maxCheckInTTL {
+		ttl = maxCheckInTTL
+	}
+	expiresAt := time.Now().UTC().Add(ttl).Truncate(time.Second)
+
+	token, err := s.signCheckInToken(discussion.ID, expiresAt)
+	if err != nil {
+		log.Printf("Error signing check-in token: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	png, err := qrcode.Encode(token, qrcode.Medium, 256)
+	if err != nil {
+		log.Printf("Error encoding check-in QR code: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	return c.JSON(models.CheckInToken{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		QRCode:    "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	})
+}
+
+// redeemCheckInToken marks the authenticated participant present for the
+// discussion the token was issued for.
+func (s *Server) redeemCheckInToken(c *fiber.Ctx) error {
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	user, err := s.currentUser(c)
+	if err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	discussionID, err := s.verifyCheckInToken(body.Token, time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	discussion, err := s.getDiscussionByID(strconv.Itoa(discussionID))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	enrolled, err := s.isCourseParticipant(discussion.CourseID, user.ID)
+	if err != nil {
+		log.Printf("Error querying course participants: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if !enrolled {
+		return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{Message: "You are not enrolled in this course"})
+	}
+
+	saved, err := s.recordCheckIn(discussionID, user.ID, time.Now().UTC())
+	if err != nil {
+		log.Printf("Error saving check-in: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	log.Printf("User %d checked in to discussion %d", user.ID, discussionID)
+	var attendance models.DiscussionAttendance
+	if len(saved) > 0 {
+		attendance = saved[0]
+	}
+	s.publishCourseEvent(discussion.CourseID, models.EventAttendanceRecorded, attendance)
+	return c.JSON(attendance)
+}
+
+// Check-in tokens are "<payload>.<mac>", both base64url encoded, where payload is
+// "<discussion id>:<expiry unix seconds>:<nonce>".
+func (s *Server) signCheckInToken(discussionID int, expiresAt time.Time) (string, error) {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	payload := fmt.Sprintf("%d:%d:%x", discussionID, expiresAt.Unix(), nonce)
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.checkInMAC(encoded)), nil
+}
+
+func (s *Server) verifyCheckInToken(token string, now time.Time) (int, error) {
+	if len(s.checkInSecret) == 0 {
+		return 0, errInvalidCheckInToken
+	}
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, errInvalidCheckInToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, s.checkInMAC(encoded)) {
+		return 0, errInvalidCheckInToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, errInvalidCheckInToken
+	}
+
+	parts := strings.Split(string(payload), ":")
+	if len(parts) != 3 {
+		return 0, errInvalidCheckInToken
+	}
+	discussionID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, errInvalidCheckInToken
+	}
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.Unix() > expiry {
+		return 0, errInvalidCheckInToken
+	}
+	return discussionID, nil
+}
+
+func (s *Server) checkInMAC(payload string) []byte {
+	mac := hmac.New(sha256.New, s.checkInSecret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
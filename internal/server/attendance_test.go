@@ -0,0 +1,36 @@
+package server
+
+import (
+	"hippias-fiber/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestDedupeAttendanceRecords(t *testing.T) {
+	present := models.AttendancePresent
+	absent := models.AttendanceAbsent
+	tests := []struct {
+		name    string
+		records []models.AttendanceRecord
+		want    []models.AttendanceRecord
+	}{
+		{"empty", nil, nil},
+		{
+			name:    "no duplicates",
+			records: []models.AttendanceRecord{{UserID: 1, Status: present}, {UserID: 2, Status: absent}},
+			want:    []models.AttendanceRecord{{UserID: 1, Status: present}, {UserID: 2, Status: absent}},
+		},
+		{
+			name:    "last record wins in first position",
+			records: []models.AttendanceRecord{{UserID: 1, Status: absent}, {UserID: 2, Status: absent}, {UserID: 1, Status: present}},
+			want:    []models.AttendanceRecord{{UserID: 1, Status: present}, {UserID: 2, Status: absent}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dedupeAttendanceRecords(tt.records); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dedupeAttendanceRecords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
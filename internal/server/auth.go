@@ -0,0 +1,162 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"hippias-fiber/internal/models"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var errUnauthenticated = errors.New("missing or invalid authorization token")
+
+// currentUser resolves the bearer token on the request to its row in the users table.
+func (s *Server) currentUser(c *fiber.Ctx) (*models.User, error) {
+	token := strings.TrimSpace(strings.TrimPrefix(c.Get("Authorization"), "Bearer "))
+	if token == "" {
+		return nil, errUnauthenticated
+	}
+
+	authUser, err := s.sb.Auth.User(c.Context(), token)
+	if err != nil {
+		log.Printf("Error resolving auth user: %v", err)
+		return nil, errUnauthenticated
+	}
+
+	var userResult json.RawMessage
+	err = s.sb.DB.From("users").
+		Select("*").
+		Single().
+		Eq("email", authUser.Email).
+		Execute(&userResult)
+	if err != nil {
+		log.Printf("Error querying user for %s: %v", authUser.Email, err)
+		return nil, errUnauthenticated
+	}
+
+	var user models.User
+	if err := json.Unmarshal(userResult, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// acceptQueryToken lets streaming clients such as EventSource and WebSocket,
+// which cannot set request headers, pass their token as ?access_token=.
+func acceptQueryToken(c *fiber.Ctx) {
+	if token := c.Query("access_token"); token != "" && c.Get("Authorization") == "" {
+		c.Request().Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+var errForbidden = errors.New("you are not allowed to do that")
+
+// requireFacilitator returns the authenticated user if they are the facilitator of
+// the given course, matched by email against the facilitators table.
+func (s *Server) requireFacilitator(c *fiber.Ctx, courseID int) (*models.User, error) {
+	user, err := s.currentUser(c)
+	if err != nil {
+		return nil, err
+	}
+	facilitator, err := s.isCourseFacilitator(courseID, user)
+	if err != nil {
+		return nil, err
+	}
+	if !facilitator {
+		return nil, errForbidden
+	}
+	return user, nil
+}
+
+// isCourseFacilitator reports whether user is the facilitator of courseID.
+func (s *Server) isCourseFacilitator(courseID int, user *models.User) (bool, error) {
+	course, err := s.getCourseByID(strconv.Itoa(courseID))
+	if err != nil {
+		return false, err
+	}
+
+	var facilitatorResult json.RawMessage
+	err = s.sb.DB.From("facilitators").
+		Select("*").
+		Single().
+		Eq("id", strconv.Itoa(course.FacilitatorID)).
+		Execute(&facilitatorResult)
+	if err != nil {
+		return false, err
+	}
+
+	var facilitator models.Facilitator
+	if err := json.Unmarshal(facilitatorResult, &facilitator); err != nil {
+		return false, err
+	}
+	return strings.EqualFold(facilitator.Email, user.Email), nil
+}
+
+// requireCourseMember returns the authenticated user if they are enrolled in or
+// facilitate the course, and whether they are its facilitator.
+func (s *Server) requireCourseMember(c *fiber.Ctx, courseID int) (*models.User, bool, error) {
+	user, err := s.currentUser(c)
+	if err != nil {
+		return nil, false, err
+	}
+	facilitator, err := s.isCourseFacilitator(courseID, user)
+	if err != nil {
+		return nil, false, err
+	}
+	if facilitator {
+		return user, true, nil
+	}
+	enrolled, err := s.isCourseParticipant(courseID, user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !enrolled {
+		return nil, false, errForbidden
+	}
+	return user, false, nil
+}
+
+// isCourseParticipant reports whether userID is enrolled in courseID.
+func (s *Server) isCourseParticipant(courseID, userID int) (bool, error) {
+	var participants []models.CourseParticipant
+	err := s.sb.DB.From("course_participants").
+		Select("*").
+		Eq("course_id", strconv.Itoa(courseID)).
+		Eq("user_id", strconv.Itoa(userID)).
+		Execute(&participants)
+	if err != nil {
+		return false, err
+	}
+	return len(participants) > 0, nil
+}
+
+// authErrorResponse maps errors from the helpers above onto HTTP responses.
+func authErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errUnauthenticated):
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Message: err.Error()})
+	case errors.Is(err, errForbidden):
+		return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{Message: err.Error()})
+	default:
+		log.Printf("Error checking permissions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+}
+
+// requireAdmin returns the authenticated user if their email is listed in
+// ADMIN_EMAILS.
+func (s *Server) requireAdmin(c *fiber.Ctx) (*models.User, error) {
+	user, err := s.currentUser(c)
+	if err != nil {
+		return nil, err
+	}
+	for _, email := range s.adminEmails {
+		if strings.EqualFold(strings.TrimSpace(email), user.Email) {
+			return user, nil
+		}
+	}
+	return nil, errForbidden
+}
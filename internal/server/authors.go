@@ -0,0 +1,418 @@
+package server
+
+import (
+	"fmt"
+	"hippias-fiber/internal/models"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxNeighborhoodDepth = 3
+
+var (
+	authorDatePattern = regexp.MustCompile(`^(-?\d{1,4})(-(0[1-9]|1[0-2])(-(0[1-9]|[12]\d|3[01]))?)?$`)
+	languageCode      = regexp.MustCompile(`^[a-z]{2,3}$`)
+)
+
+// validateAuthor checks and tidies the metadata fields of an author.
+func validateAuthor(author *models.Author) error {
+	author.Name = strings.TrimSpace(author.Name)
+	if author.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	birth, err := parseAuthorDate("birthDate", author.BirthDate)
+	if err != nil {
+		return err
+	}
+	death, err := parseAuthorDate("deathDate", author.DeathDate)
+	if err != nil {
+		return err
+	}
+	if birth != nil && death != nil && death.before(*birth) {
+		return fmt.Errorf("deathDate must not be before birthDate")
+	}
+
+	schools := []string{}
+	seen := map[string]bool{}
+	for _, school := range author.Schools {
+		school = strings.TrimSpace(school)
+		if school == "" || seen[strings.ToLower(school)] {
+			continue
+		}
+		seen[strings.ToLower(school)] = true
+		schools = append(schools, school)
+	}
+	author.Schools = schools
+
+	author.PrimaryLanguage = strings.ToLower(strings.TrimSpace(author.PrimaryLanguage))
+	if author.PrimaryLanguage != "" && !languageCode.MatchString(author.PrimaryLanguage) {
+		return fmt.Errorf("primaryLanguage must be an ISO 639 language code")
+	}
+
+	if author.Links == nil {
+		author.Links = []models.AuthorLink{}
+	}
+	for i, link := range author.Links {
+		if err := validateReadingURL(fmt.Sprintf("links[%d].url", i), link.URL); err != nil {
+			return err
+		}
+		if strings.TrimSpace(link.Label) == "" {
+			return fmt.Errorf("links[%d].label is required", i)
+		}
+	}
+	return nil
+}
+
+// authorDate is a YYYY[-MM[-DD]] date; month and day are 0 when not given.
+type authorDate struct {
+	year, month, day int
+}
+
+// parseAuthorDate parses an author's birth or death date, or returns nil for "".
+func parseAuthorDate(field, date string) (*authorDate, error) {
+	if date == "" {
+		return nil, nil
+	}
+	m := authorDatePattern.FindStringSubmatch(date)
+	if m == nil {
+		return nil, fmt.Errorf("%s must be YYYY, YYYY-MM or YYYY-MM-DD (negative years for BCE)", field)
+	}
+	var d authorDate
+	d.year, _ = strconv.Atoi(m[1])
+	d.month, _ = strconv.Atoi(m[3])
+	d.day, _ = strconv.Atoi(m[5])
+	if d.day > 0 && time.Date(d.year, time.Month(d.month), d.day, 0, 0, 0, 0, time.UTC).Day() != d.day {
+		return nil, fmt.Errorf("%s is not a valid date", field)
+	}
+	return &d, nil
+}
+
+// before reports whether d is known to fall before other, comparing as far as
+// both dates are precise: 1900-08 is not before 1900.
+func (d authorDate) before(other authorDate) bool {
+	if d.year != other.year {
+		return d.year < other.year
+	}
+	if d.month == 0 || other.month == 0 || d.month != other.month {
+		return d.month != 0 && other.month != 0 && d.month < other.month
+	}
+	return d.day != 0 && other.day != 0 && d.day < other.day
+}
+
+// authorYear returns the year of an author's date, or nil when it is empty or
+// does not parse.
+func authorYear(date string) *int {
+	d, err := parseAuthorDate("", date)
+	if err != nil || d == nil {
+		return nil
+	}
+	return &d.year
+}
+
+// listAuthorRelations returns the relations in both directions that involve
+// an author.
+func (s *Server) listAuthorRelations(c *fiber.Ctx) error {
+	relations, err := s.authorRelations([]string{c.Params("id")})
+	if err != nil {
+		log.Printf("Error querying author relations: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	return c.JSON(relations)
+}
+
+// authorRelations returns every relation starting or ending at one of the authors.
+func (s *Server) authorRelations(authorIDs []string) ([]models.AuthorRelation, error) {
+	var outgoing, incoming []models.AuthorRelation
+	err := s.sb.DB.From("author_relations").Select("*").In("from_author_id", authorIDs).Execute(&outgoing)
+	if err != nil {
+		return nil, err
+	}
+	err = s.sb.DB.From("author_relations").Select("*").In("to_author_id", authorIDs).Execute(&incoming)
+	if err != nil {
+		return nil, err
+	}
+
+	relations := []models.AuthorRelation{}
+	seen := map[int]bool{}
+	for _, relation := range append(outgoing, incoming...) {
+		if !seen[relation.ID] {
+			seen[relation.ID] = true
+			relations = append(relations, relation)
+		}
+	}
+	sort.Slice(relations, func(i, j int) bool { return relations[i].ID < relations[j].ID })
+	return relations, nil
+}
+
+// createAuthorRelation records that the author in the path was influenced by,
+// or responded to, to_author_id.
+func (s *Server) createAuthorRelation(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	author, err := s.getAuthorByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying author: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Author not found"})
+	}
+
+	var relation models.AuthorRelation
+	if err := c.BodyParser(&relation); err != nil {
+		log.Printf("Error parsing author relation: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if relation.Kind != models.RelationInfluencedBy && relation.Kind != models.RelationRespondedTo {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Message: fmt.Sprintf("kind must be %s or %s", models.RelationInfluencedBy, models.RelationRespondedTo),
+		})
+	}
+	if relation.ToAuthorID == author.ID {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "an author cannot be related to themselves"})
+	}
+	if _, err := s.getAuthorByID(strconv.Itoa(relation.ToAuthorID)); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "to_author_id: author not found"})
+	}
+
+	var existing []models.AuthorRelation
+	err = s.sb.DB.From("author_relations").Select("id").
+		Eq("from_author_id", strconv.Itoa(author.ID)).
+		Eq("to_author_id", strconv.Itoa(relation.ToAuthorID)).
+		Eq("kind", relation.Kind).
+		Execute(&existing)
+	if err != nil {
+		log.Printf("Error querying author relations: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(existing) > 0 {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{Message: "Relation already exists"})
+	}
+
+	relation.ID = 0
+	relation.FromAuthorID = author.ID
+	relation.CreatedAt = time.Now().UTC()
+
+	var created []models.AuthorRelation
+	err = s.sb.DB.From("author_relations").Insert(relation).Execute(&created)
+	if err != nil {
+		log.Printf("Error inserting author relation: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(created) > 0 {
+		relation = created[0]
+	}
+
+	log.Printf("Created author relation: %+v", relation)
+	return c.Status(fiber.StatusCreated).JSON(relation)
+}
+
+func (s *Server) deleteAuthorRelation(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var existing []models.AuthorRelation
+	err := s.sb.DB.From("author_relations").Select("id").
+		Eq("id", c.Params("relationId")).
+		Eq("from_author_id", c.Params("id")).
+		Execute(&existing)
+	if err != nil {
+		log.Printf("Error querying author relation: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(existing) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Relation not found"})
+	}
+
+	err = s.sb.DB.From("author_relations").Delete().
+		Eq("id", c.Params("relationId")).
+		Eq("from_author_id", c.Params("id")).
+		Execute(nil)
+	if err != nil {
+		log.Printf("Error deleting author relation: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// getAuthorNeighborhood returns the authors and relations reachable from an
+// author within ?depth= steps (default 1, at most 3), following relations in
+// either direction.
+func (s *Server) getAuthorNeighborhood(c *fiber.Ctx) error {
+	author, err := s.getAuthorByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying author: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Author not found"})
+	}
+	depth := c.QueryInt("depth", 1)
+	if depth < 1 || depth > maxNeighborhoodDepth {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: fmt.Sprintf("depth must be between 1 and %d", maxNeighborhoodDepth)})
+	}
+
+	visited := map[int]bool{author.ID: true}
+	frontier := []string{strconv.Itoa(author.ID)}
+	edges := []models.AuthorRelation{}
+	seenEdges := map[int]bool{}
+	for step := 0; step < depth && len(frontier) > 0; step++ {
+		relations, err := s.authorRelations(frontier)
+		if err != nil {
+			log.Printf("Error querying author relations: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+		frontier = nil
+		for _, relation := range relations {
+			if seenEdges[relation.ID] {
+				continue
+			}
+			seenEdges[relation.ID] = true
+			edges = append(edges, relation)
+			for _, id := range []int{relation.FromAuthorID, relation.ToAuthorID} {
+				if !visited[id] {
+					visited[id] = true
+					frontier = append(frontier, strconv.Itoa(id))
+				}
+			}
+		}
+	}
+
+	// Authors reached in the last step are included, though their own
+	// relations are not followed
+	var ids []string
+	for id := range visited {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	var authors []models.Author
+	err = s.sb.DB.From("authors").Select("*").In("id", ids).Execute(&authors)
+	if err != nil {
+		log.Printf("Error querying authors: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	sort.Slice(authors, func(i, j int) bool { return authors[i].ID < authors[j].ID })
+	sort.Slice(edges, func(i, j int) bool { return edges[i].ID < edges[j].ID })
+
+	return c.JSON(models.AuthorNeighborhood{
+		AuthorID: author.ID,
+		Depth:    depth,
+		Authors:  authors,
+		Edges:    edges,
+	})
+}
+
+// getCourseTimeline lists the authors of a course's books and book readings in
+// order of birth. Authors with no known dates come last.
+func (s *Server) getCourseTimeline(c *fiber.Ctx) error {
+	courseID := c.Params("id")
+	if _, err := s.getCourseByID(courseID); err != nil {
+		log.Printf("Error querying course: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Course not found"})
+	}
+
+	books, err := s.courseReadingListBooks(courseID)
+	if err != nil {
+		log.Printf("Error querying course books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	byAuthor := map[int][]models.Book{}
+	var authorIDs []string
+	for _, book := range books {
+		if book.AuthorID == 0 {
+			continue
+		}
+		if _, ok := byAuthor[book.AuthorID]; !ok {
+			authorIDs = append(authorIDs, strconv.Itoa(book.AuthorID))
+		}
+		byAuthor[book.AuthorID] = append(byAuthor[book.AuthorID], book)
+	}
+
+	timeline := []models.TimelineEntry{}
+	if len(authorIDs) > 0 {
+		var authors []models.Author
+		err = s.sb.DB.From("authors").Select("*").In("id", authorIDs).Execute(&authors)
+		if err != nil {
+			log.Printf("Error querying authors: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+		for _, author := range authors {
+			// Dates were validated when saved; older rows may not parse
+			timeline = append(timeline, models.TimelineEntry{
+				Author:    author,
+				BirthYear: authorYear(author.BirthDate),
+				DeathYear: authorYear(author.DeathDate),
+				Books:     byAuthor[author.ID],
+			})
+		}
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		a, b := timelineYear(timeline[i]), timelineYear(timeline[j])
+		if (a == nil) != (b == nil) {
+			return a != nil
+		}
+		if a != nil && *a != *b {
+			return *a < *b
+		}
+		return timeline[i].Author.Name < timeline[j].Author.Name
+	})
+	return c.JSON(timeline)
+}
+
+// timelineYear places an author by birth, or by death when birth is unknown.
+func timelineYear(entry models.TimelineEntry) *int {
+	if entry.BirthYear != nil {
+		return entry.BirthYear
+	}
+	return entry.DeathYear
+}
+
+// courseReadingListBooks returns the course's books followed by any other
+// books assigned in its discussions' readings.
+func (s *Server) courseReadingListBooks(courseID string) ([]models.Book, error) {
+	courseBooks, err := s.listCourseBooks(courseID)
+	if err != nil {
+		return nil, err
+	}
+	var books []models.Book
+	seen := map[int]bool{}
+	for _, courseBook := range courseBooks {
+		seen[courseBook.ID] = true
+		books = append(books, courseBook.Book)
+	}
+
+	var discussions []models.Discussion
+	err = s.sb.DB.From("discussions").Select("id").Eq("course_id", courseID).Execute(&discussions)
+	if err != nil || len(discussions) == 0 {
+		return books, err
+	}
+	var discussionIDs []string
+	for _, discussion := range discussions {
+		discussionIDs = append(discussionIDs, strconv.Itoa(discussion.ID))
+	}
+	var readings []models.Reading
+	err = s.sb.DB.From("readings").Select("book_id").In("discussion_id", discussionIDs).Execute(&readings)
+	if err != nil {
+		return nil, err
+	}
+	var extra []string
+	for _, reading := range readings {
+		if reading.BookID != 0 && !seen[reading.BookID] {
+			seen[reading.BookID] = true
+			extra = append(extra, strconv.Itoa(reading.BookID))
+		}
+	}
+	if len(extra) == 0 {
+		return books, nil
+	}
+	var more []models.Book
+	if err := s.sb.DB.From("books").Select("*").In("id", extra).Execute(&more); err != nil {
+		return nil, err
+	}
+	return append(books, more...), nil
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"hippias-fiber/internal/models"
+	"testing"
+)
+
+func TestValidateAuthorDates(t *testing.T) {
+	tests := []struct {
+		name    string
+		birth   string
+		death   string
+		wantErr bool
+	}{
+		{"no dates", "", "", false},
+		{"years in order", "1844", "1900", false},
+		{"years reversed", "1900", "1844", true},
+		{"same year, death month before birth month", "1900-08", "1900-03", true},
+		{"same month, death day before birth day", "1900-08-25", "1900-08-15", true},
+		{"same day", "1900-08-25", "1900-08-25", false},
+		{"full dates in order", "1844-10-15", "1900-08-25", false},
+		{"mixed precision in the same year", "1900-08-25", "1900", false},
+		{"mixed precision in the same month", "1900-08", "1900-08-01", false},
+		{"BCE years", "-470", "-399", false},
+		{"BCE years reversed", "-399", "-470", true},
+		{"day past the end of the month", "1900-02-30", "", true},
+		{"leap day", "1896-02-29", "", false},
+		{"not a date", "15 Oct 1844", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			author := models.Author{Name: "Someone", BirthDate: tt.birth, DeathDate: tt.death}
+			if err := validateAuthor(&author); (err != nil) != tt.wantErr {
+				t.Errorf("validateAuthor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
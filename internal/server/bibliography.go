@@ -0,0 +1,222 @@
+package server
+
+import (
+	"fmt"
+	"hippias-fiber/internal/bibliography"
+	"hippias-fiber/internal/models"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// bibliographyFormats maps the ?format= values to the media types used for
+// content negotiation.
+var bibliographyFormats = []struct{ format, mediaType string }{
+	{"bibtex", "application/x-bibtex"},
+	{"ris", "application/x-research-info-systems"},
+	{"csl-json", "application/vnd.citationstyles.csl+json"},
+	{"html", "text/html"},
+	{"markdown", "text/markdown"},
+}
+
+// getCourseBibliography lists every book and reading used by a course. The
+// format comes from ?format= or the Accept header; the html and markdown
+// renderings take ?style=chicago|mla.
+func (s *Server) getCourseBibliography(c *fiber.Ctx) error {
+	courseID := c.Params("id")
+
+	format := c.Query("format")
+	if format == "" {
+		offers := make([]string, len(bibliographyFormats))
+		for i, f := range bibliographyFormats {
+			offers[i] = f.mediaType
+		}
+		accepted := c.Accepts(offers...)
+		format = "csl-json"
+		for _, f := range bibliographyFormats {
+			if f.mediaType == accepted {
+				format = f.format
+			}
+		}
+	}
+	style := c.Query("style", bibliography.StyleChicago)
+
+	entries, err := s.courseBibliography(courseID)
+	if err != nil {
+		log.Printf("Error building bibliography: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	filename := "course-" + courseID + "-bibliography"
+	switch format {
+	case "bibtex":
+		c.Attachment(filename + ".bib")
+		c.Set(fiber.HeaderContentType, "application/x-bibtex; charset=utf-8")
+		return c.SendString(bibliography.BibTeX(entries))
+	case "ris":
+		c.Attachment(filename + ".ris")
+		c.Set(fiber.HeaderContentType, "application/x-research-info-systems; charset=utf-8")
+		return c.SendString(bibliography.RIS(entries))
+	case "csl-json":
+		data, err := bibliography.CSLJSON(entries)
+		if err != nil {
+			log.Printf("Error encoding CSL-JSON: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+		c.Set(fiber.HeaderContentType, "application/vnd.citationstyles.csl+json")
+		return c.Send(data)
+	case "html", "markdown":
+		markup := bibliography.MarkupHTML
+		contentType := fiber.MIMETextHTMLCharsetUTF8
+		if format == "markdown" {
+			markup = bibliography.MarkupMarkdown
+			contentType = "text/markdown; charset=utf-8"
+		}
+		out, err := bibliography.Format(entries, style, markup)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+		}
+		c.Set(fiber.HeaderContentType, contentType)
+		return c.SendString(out)
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "format must be bibtex, ris, csl-json, html or markdown"})
+	}
+}
+
+// courseBibliography collects the course's books (from course_books, in the
+// chosen edition) and the readings of each of its discussions.
+func (s *Server) courseBibliography(courseID string) ([]bibliography.Entry, error) {
+	courseBooks, err := s.listCourseBooks(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []bibliography.Entry
+	for _, courseBook := range courseBooks {
+		entries = append(entries, bookEntry(courseBook.Book, courseBook.Edition))
+	}
+
+	var discussions []models.Discussion
+	err = s.sb.DB.From("discussions").Select("id").Eq("course_id", courseID).Execute(&discussions)
+	if err != nil {
+		return nil, err
+	}
+	var discussionIDs []string
+	for _, discussion := range discussions {
+		discussionIDs = append(discussionIDs, strconv.Itoa(discussion.ID))
+	}
+	var readings []models.Reading
+	if len(discussionIDs) > 0 {
+		err = s.sb.DB.From("readings").Select("*").In("discussion_id", discussionIDs).Execute(&readings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	books := map[int]models.Book{}
+	for _, courseBook := range courseBooks {
+		books[courseBook.ID] = courseBook.Book
+	}
+	editions := map[int]*models.Edition{}
+	for _, reading := range readings {
+		if reading.BookID != 0 {
+			if _, ok := books[reading.BookID]; !ok {
+				book, err := s.getBookByID(strconv.Itoa(reading.BookID))
+				if err != nil {
+					return nil, fmt.Errorf("book %d of reading %d: %w", reading.BookID, reading.ID, err)
+				}
+				books[reading.BookID] = *book
+			}
+		}
+		if reading.EditionID != 0 {
+			if _, ok := editions[reading.EditionID]; !ok {
+				edition, err := s.getEditionByID(strconv.Itoa(reading.EditionID))
+				if err != nil {
+					return nil, fmt.Errorf("edition %d of reading %d: %w", reading.EditionID, reading.ID, err)
+				}
+				editions[reading.EditionID] = edition
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, reading := range readings {
+		entry := readingEntry(reading, books[reading.BookID], editions[reading.EditionID])
+		// Several discussions can assign the same section; cite it once
+		key := fmt.Sprintf("%s|%s|%s|%s", entry.Kind, entry.Title, entry.Container, entry.Pages())
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		entries = append(entries, entry)
+	}
+
+	bibliography.Sort(entries)
+	bibliography.Keys(entries)
+	return entries, nil
+}
+
+func bookEntry(book models.Book, edition *models.Edition) bibliography.Entry {
+	entry := bibliography.Entry{
+		Kind:  bibliography.KindBook,
+		Title: book.Title,
+	}
+	if book.Author != "" {
+		entry.Authors = []string{book.Author}
+	}
+	applyEdition(&entry, edition)
+	return entry
+}
+
+func applyEdition(entry *bibliography.Entry, edition *models.Edition) {
+	if edition == nil {
+		return
+	}
+	entry.Translator = edition.Translator
+	entry.Publisher = edition.Publisher
+	entry.Year = edition.Year
+	entry.ISBN = edition.ISBN
+	entry.Language = edition.Language
+}
+
+func readingEntry(reading models.Reading, book models.Book, edition *models.Edition) bibliography.Entry {
+	entry := bibliography.Entry{
+		Title:     reading.Title,
+		URL:       reading.URL,
+		Container: reading.Source,
+		PageStart: reading.PageStart,
+		PageEnd:   reading.PageEnd,
+		Chapter:   reading.Chapter,
+	}
+
+	// Unmigrated rows may still carry legacy free-text types
+	switch readingKind(&reading) {
+	case models.ReadingBookSection:
+		entry.Kind = bibliography.KindChapter
+		entry.Container = book.Title
+		if book.Author != "" {
+			entry.Authors = []string{book.Author}
+		}
+		if strings.TrimSpace(entry.Title) == "" {
+			entry.Title = book.Title
+			if reading.Chapter != "" {
+				entry.Title += ", chapter " + reading.Chapter
+			}
+		}
+		applyEdition(&entry, edition)
+	case models.ReadingArticle:
+		entry.Kind = bibliography.KindArticle
+	case models.ReadingVideo:
+		entry.Kind = bibliography.KindVideo
+		if reading.VideoURL != "" {
+			entry.URL = reading.VideoURL
+		}
+	case models.ReadingAudio:
+		entry.Kind = bibliography.KindAudio
+	default:
+		entry.Kind = bibliography.KindWebPage
+	}
+	return entry
+}
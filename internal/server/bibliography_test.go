@@ -0,0 +1,31 @@
+package server
+
+import (
+	"hippias-fiber/internal/bibliography"
+	"hippias-fiber/internal/models"
+	"testing"
+)
+
+func TestReadingEntryKind(t *testing.T) {
+	book := models.Book{ID: 3, Title: "Five Dialogues", Author: "Plato"}
+	tests := []struct {
+		name    string
+		reading models.Reading
+		want    string
+	}{
+		{"book section", models.Reading{Type: models.ReadingBookSection, BookID: 3}, bibliography.KindChapter},
+		{"legacy book", models.Reading{Type: "Book", BookID: 3}, bibliography.KindChapter},
+		{"legacy book chapter", models.Reading{Type: "book chapter", BookID: 3}, bibliography.KindChapter},
+		{"legacy paper", models.Reading{Type: "Paper"}, bibliography.KindArticle},
+		{"legacy podcast", models.Reading{Type: "Podcast"}, bibliography.KindAudio},
+		{"untyped video", models.Reading{VideoURL: "https://example.com/v"}, bibliography.KindVideo},
+		{"web page", models.Reading{Type: models.ReadingWebPage, URL: "https://example.com"}, bibliography.KindWebPage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readingEntry(tt.reading, book, nil).Kind; got != tt.want {
+				t.Errorf("readingEntry(%q).Kind = %q, want %q", tt.reading.Type, got, tt.want)
+			}
+		})
+	}
+}
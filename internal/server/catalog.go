@@ -0,0 +1,489 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hippias-fiber/internal/models"
+	"hippias-fiber/internal/rpc"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BOOKS
+
+// listBooks takes ?tag= (an ID or slug) to list only books under that tag.
+func (s *Server) listBooks(c *fiber.Ctx) error {
+	tagged, err := s.tagFilter(c, models.TagBook)
+	if err != nil {
+		log.Printf("Error querying tags: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	query := s.sb.DB.From("books").Select("*")
+	if tagged != nil {
+		if len(tagged) == 0 {
+			return c.JSON([]models.Book{})
+		}
+		query.In("id", tagged)
+	}
+
+	var jsonResult json.RawMessage
+	err = query.Execute(&jsonResult)
+	if err != nil {
+		log.Printf("Error querying books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	var books []models.Book
+	err = json.Unmarshal(jsonResult, &books)
+	if err != nil {
+		log.Printf("Error unmarshaling books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	return c.JSON(books)
+}
+
+// getBook redirects IDs of books merged into another to the surviving book.
+func (s *Server) getBook(c *fiber.Ctx) error {
+	book, err := s.getBookByID(c.Params("id"))
+	if err != nil {
+		if newID, ok := s.redirectedID("book_redirects", c.Params("id")); ok {
+			return c.Redirect("/books/"+strconv.Itoa(newID), fiber.StatusMovedPermanently)
+		}
+		log.Printf("Error querying book: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Book not found"})
+	}
+
+	return c.JSON(book)
+}
+
+func (s *Server) getBookByID(bookID string) (*models.Book, error) {
+	var jsonResult json.RawMessage
+	err := s.sb.DB.From("books").
+		Select("*").
+		Single().
+		Eq("id", bookID).
+		Execute(&jsonResult)
+	if err != nil {
+		return nil, err
+	}
+
+	var book models.Book
+	if err := json.Unmarshal(jsonResult, &book); err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
+
+func (s *Server) getBooksByAuthorID(c *fiber.Ctx) error {
+	authorID := c.Params("id")
+	if authorID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Missing author ID"})
+	}
+
+	var jsonResult json.RawMessage
+	err := s.sb.DB.From("books").
+		Select("*").
+		Eq("authorId", authorID).
+		Execute(&jsonResult)
+	if err != nil {
+		log.Printf("Error querying books by author: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	var books []models.Book
+	err = json.Unmarshal(jsonResult, &books)
+	if err != nil {
+		log.Printf("Error unmarshaling books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	return c.JSON(books)
+}
+
+func (s *Server) createBook(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var book models.Book
+	if err := c.BodyParser(&book); err != nil {
+		log.Printf("Error parsing book: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if strings.TrimSpace(book.Title) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "title is required"})
+	}
+	if err := s.reconcileBookAuthor(&book); err != nil {
+		return catalogErrorResponse(c, err)
+	}
+
+	now := time.Now().UTC()
+	book.ID = 0
+	book.CreatedAt = now
+	book.UpdatedAt = now
+
+	var created []models.Book
+	err := s.sb.DB.From("books").Insert(book).Execute(&created)
+	if err != nil {
+		log.Printf("Error inserting book: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(created) > 0 {
+		book = created[0]
+	}
+
+	log.Printf("Created book: %+v", book)
+	return c.Status(fiber.StatusCreated).JSON(book)
+}
+
+func (s *Server) updateBook(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	bookID := c.Params("id")
+
+	existing, err := s.getBookByID(bookID)
+	if err != nil {
+		log.Printf("Error querying book: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Book not found"})
+	}
+
+	var book models.Book
+	if err := c.BodyParser(&book); err != nil {
+		log.Printf("Error parsing book: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if strings.TrimSpace(book.Title) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "title is required"})
+	}
+	if err := s.reconcileBookAuthor(&book); err != nil {
+		return catalogErrorResponse(c, err)
+	}
+
+	book.ID = existing.ID
+	book.CreatedAt = existing.CreatedAt
+	book.UpdatedAt = time.Now().UTC()
+
+	var updated []models.Book
+	err = s.sb.DB.From("books").Update(book).Eq("id", bookID).Execute(&updated)
+	if err != nil {
+		log.Printf("Error updating book: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(updated) > 0 {
+		book = updated[0]
+	}
+
+	log.Printf("Updated book: %+v", book)
+	return c.JSON(book)
+}
+
+// deleteBook refuses to remove a book that a course or reading still refers to.
+// The book goes together with its editions and tags.
+func (s *Server) deleteBook(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	bookID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid book ID"})
+	}
+
+	var result catalogDeletion
+	if err := rpc.Call(s.sb, "delete_book", map[string]int{"book_id": bookID}, &result); err != nil {
+		log.Printf("Error deleting book: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	switch {
+	case !result.Found:
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Book not found"})
+	case !result.Deleted:
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{Message: fmt.Sprintf("Book is used by %d courses and %d readings", result.CourseBooks, result.Readings)})
+	}
+
+	log.Printf("Deleted book with ID: %d", bookID)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// catalogDeletion is what the functions in sql/delete_records.sql return.
+type catalogDeletion struct {
+	Found   bool `json:"found"`
+	Deleted bool `json:"deleted"`
+	// What still uses the record when it was not deleted
+	CourseBooks int `json:"course_books"`
+	Readings    int `json:"readings"`
+	Books       int `json:"books"`
+}
+
+var errAuthorNotFound = errors.New("author not found")
+
+// likeEscaper escapes LIKE wildcards. PostgREST also reads * as %, and a
+// backslash doesn't protect it, so it becomes a single-character wildcard
+// that callers confirm with strings.EqualFold.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `*`, `_`)
+
+// likePattern returns an ILIKE pattern matching value literally.
+func likePattern(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// reconcileBookAuthor makes Book.Author and Book.AuthorID agree. AuthorID wins
+// when set; otherwise the free-text name is matched case-insensitively against
+// existing authors, and a new author is created if none matches.
+func (s *Server) reconcileBookAuthor(book *models.Book) error {
+	if book.AuthorID != 0 {
+		author, err := s.getAuthorByID(strconv.Itoa(book.AuthorID))
+		if err != nil {
+			log.Printf("Error querying author %d: %v", book.AuthorID, err)
+			return errAuthorNotFound
+		}
+		book.Author = author.Name
+		return nil
+	}
+
+	name := strings.TrimSpace(book.Author)
+	if name == "" {
+		return nil
+	}
+
+	var matches []models.Author
+	err := s.sb.DB.From("authors").Select("*").Ilike("name", likePattern(name)).Execute(&matches)
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		if strings.EqualFold(match.Name, name) {
+			book.AuthorID = match.ID
+			book.Author = match.Name
+			return nil
+		}
+	}
+
+	now := time.Now().UTC()
+	var created []models.Author
+	err = s.sb.DB.From("authors").
+		Insert(models.Author{Name: name, CreatedAt: now, UpdatedAt: now}).
+		Execute(&created)
+	if err != nil {
+		return err
+	}
+	if len(created) == 0 {
+		return errors.New("author insert returned no rows")
+	}
+	log.Printf("Created author %q for book %q", name, book.Title)
+	book.AuthorID = created[0].ID
+	book.Author = created[0].Name
+	return nil
+}
+
+func catalogErrorResponse(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errAuthorNotFound) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	log.Printf("Error resolving book author: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+}
+
+// AUTHORS
+
+// listAuthors takes ?tag= (an ID or slug) to list only authors under that tag.
+func (s *Server) listAuthors(c *fiber.Ctx) error {
+	tagged, err := s.tagFilter(c, models.TagAuthor)
+	if err != nil {
+		log.Printf("Error querying tags: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	query := s.sb.DB.From("authors").Select("*")
+	if tagged != nil {
+		if len(tagged) == 0 {
+			return c.JSON([]models.Author{})
+		}
+		query.In("id", tagged)
+	}
+
+	var jsonResult json.RawMessage
+	err = query.Execute(&jsonResult)
+	if err != nil {
+		log.Printf("Error querying authors: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	var authors []models.Author
+	err = json.Unmarshal(jsonResult, &authors)
+	if err != nil {
+		log.Printf("Error unmarshaling authors: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	return c.JSON(authors)
+}
+
+// getAuthor redirects IDs of authors merged into another to the surviving author.
+func (s *Server) getAuthor(c *fiber.Ctx) error {
+	author, err := s.getAuthorByID(c.Params("id"))
+	if err != nil {
+		if newID, ok := s.redirectedID("author_redirects", c.Params("id")); ok {
+			return c.Redirect("/authors/"+strconv.Itoa(newID), fiber.StatusMovedPermanently)
+		}
+		log.Printf("Error querying author: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Author not found"})
+	}
+
+	return c.JSON(author)
+}
+
+func (s *Server) getAuthorByID(authorID string) (*models.Author, error) {
+	var jsonResult json.RawMessage
+	err := s.sb.DB.From("authors").
+		Select("*").
+		Single().
+		Eq("id", authorID).
+		Execute(&jsonResult)
+	if err != nil {
+		return nil, err
+	}
+
+	var author models.Author
+	if err := json.Unmarshal(jsonResult, &author); err != nil {
+		return nil, err
+	}
+	return &author, nil
+}
+
+func (s *Server) createAuthor(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var author models.Author
+	if err := c.BodyParser(&author); err != nil {
+		log.Printf("Error parsing author: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if err := validateAuthor(&author); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	now := time.Now().UTC()
+	author.ID = 0
+	author.CreatedAt = now
+	author.UpdatedAt = now
+
+	var created []models.Author
+	err := s.sb.DB.From("authors").Insert(author).Execute(&created)
+	if err != nil {
+		log.Printf("Error inserting author: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(created) > 0 {
+		author = created[0]
+	}
+
+	log.Printf("Created author: %+v", author)
+	return c.Status(fiber.StatusCreated).JSON(author)
+}
+
+// updateAuthor also rewrites the denormalized Book.Author of the author's books
+// when the name changes.
+func (s *Server) updateAuthor(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	authorID := c.Params("id")
+
+	existing, err := s.getAuthorByID(authorID)
+	if err != nil {
+		log.Printf("Error querying author: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Author not found"})
+	}
+
+	var author models.Author
+	if err := c.BodyParser(&author); err != nil {
+		log.Printf("Error parsing author: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if err := validateAuthor(&author); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	author.ID = existing.ID
+	author.CreatedAt = existing.CreatedAt
+	author.UpdatedAt = time.Now().UTC()
+
+	// Empty schools and links are left out of the JSON, but this is a full
+	// replace, so write them explicitly to clear them
+	data, err := json.Marshal(author)
+	if err != nil {
+		log.Printf("Error marshaling author: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	var changes map[string]interface{}
+	if err := json.Unmarshal(data, &changes); err != nil {
+		log.Printf("Error marshaling author: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	changes["schools"] = author.Schools
+	changes["links"] = author.Links
+
+	var updated []models.Author
+	err = s.sb.DB.From("authors").Update(changes).Eq("id", authorID).Execute(&updated)
+	if err != nil {
+		log.Printf("Error updating author: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(updated) > 0 {
+		author = updated[0]
+	}
+
+	if author.Name != existing.Name {
+		var jsonResult json.RawMessage
+		err = s.sb.DB.From("books").
+			Update(map[string]interface{}{"author": author.Name, "updatedAt": author.UpdatedAt}).
+			Eq("authorId", authorID).
+			Execute(&jsonResult)
+		if err != nil {
+			log.Printf("Error renaming author on books: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+	}
+
+	log.Printf("Updated author: %+v", author)
+	return c.JSON(author)
+}
+
+// deleteAuthor refuses to remove an author who still has books. Their
+// relations to other authors and their tags are removed with them.
+func (s *Server) deleteAuthor(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	authorID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid author ID"})
+	}
+
+	var result catalogDeletion
+	if err := rpc.Call(s.sb, "delete_author", map[string]int{"author_id": authorID}, &result); err != nil {
+		log.Printf("Error deleting author: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	switch {
+	case !result.Found:
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Author not found"})
+	case !result.Deleted:
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{Message: fmt.Sprintf("Author still has %d books", result.Books)})
+	}
+
+	log.Printf("Deleted author with ID: %d", authorID)
+	return c.SendStatus(fiber.StatusNoContent)
+}
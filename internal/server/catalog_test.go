@@ -0,0 +1,21 @@
+package server
+
+import "testing"
+
+func TestLikePattern(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Plato", "Plato"},
+		{"100% Plato", `100\% Plato`},
+		{"de_Beauvoir", `de\_Beauvoir`},
+		{`C:\Plato`, `C:\\Plato`},
+		{"Pl*to", "Pl_to"},
+	}
+	for _, tt := range tests {
+		if got := likePattern(tt.value); got != tt.want {
+			t.Errorf("likePattern(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"hippias-fiber/internal/models"
+	"hippias-fiber/internal/rpc"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Verification codes avoid characters that are easily misread when typed from paper.
+const certificateCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func (s *Server) getCompletionCriteria(c *fiber.Ctx) error {
+	courseID := c.Params("id")
+
+	criteria, err := s.findCompletionCriteria(courseID)
+	if err != nil {
+		log.Printf("Error querying completion criteria: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if criteria == nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Completion criteria not set for this course"})
+	}
+	return c.JSON(criteria)
+}
+
+func (s *Server) setCompletionCriteria(c *fiber.Ctx) error {
+	courseID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid course ID"})
+	}
+	if _, err := s.requireFacilitator(c, courseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var criteria models.CompletionCriteria
+	if err := c.BodyParser(&criteria); err != nil {
+		log.Printf("Error parsing completion criteria: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if criteria.MinAttendancePercent < 0 || criteria.MinAttendancePercent > 100 || criteria.MinReadingsRated < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "min_attendance_percent must be 0-100 and min_readings_rated non-negative"})
+	}
+	criteria.CourseID = courseID
+	criteria.UpdatedAt = time.Now().UTC()
+
+	existing, err := s.findCompletionCriteria(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying completion criteria: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	var saved []models.CompletionCriteria
+	if existing != nil {
+		criteria.ID = existing.ID
+		err = s.sb.DB.From("course_completion_criteria").
+			Update(criteria).
+			Eq("id", strconv.Itoa(existing.ID)).
+			Execute(&saved)
+	} else {
+		err = s.sb.DB.From("course_completion_criteria").Insert(criteria).Execute(&saved)
+	}
+	if err != nil {
+		log.Printf("Error saving completion criteria: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	log.Printf("Set completion criteria for course %d: %+v", courseID, criteria)
+	if len(saved) > 0 {
+		criteria = saved[0]
+	}
+	return c.JSON(criteria)
+}
+
+func (s *Server) findCompletionCriteria(courseID string) (*models.CompletionCriteria, error) {
+	var criteria []models.CompletionCriteria
+	err := s.sb.DB.From("course_completion_criteria").
+		Select("*").
+		Eq("course_id", courseID).
+		Execute(&criteria)
+	if err != nil || len(criteria) == 0 {
+		return nil, err
+	}
+	return &criteria[0], nil
+}
+
+// issueCertificates evaluates every participant against the course's completion
+// criteria using the participation report, and issues a certificate to each one
+// who meets them once every discussion has been held. Participants who already
+// hold a certificate keep it.
+func (s *Server) issueCertificates(c *fiber.Ctx) error {
+	courseID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid course ID"})
+	}
+	if _, err := s.requireFacilitator(c, courseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	criteria, err := s.findCompletionCriteria(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying completion criteria: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if criteria == nil {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{Message: "Set completion criteria for this course first"})
+	}
+
+	report, err := s.buildCourseReport(courseID, defaultLowTurnoutThreshold, time.Now())
+	if err != nil {
+		log.Printf("Error building course report: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	var issued []models.Certificate
+	err = s.sb.DB.From("certificates").
+		Select("*").
+		Eq("course_id", c.Params("id")).
+		Execute(&issued)
+	if err != nil {
+		log.Printf("Error querying certificates: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	issuedByUser := map[int]models.Certificate{}
+	for _, certificate := range issued {
+		issuedByUser[certificate.UserID] = certificate
+	}
+
+	// Certificates are permanent, so nobody completes a course until all of
+	// its discussions have been held
+	finished := courseFinished(report.Discussions)
+
+	dryRun := c.QueryBool("dry_run")
+	var evaluations []models.CompletionEvaluation
+	for _, participant := range report.Participants {
+		evaluation := models.CompletionEvaluation{
+			UserID:            participant.UserID,
+			Name:              participant.Name,
+			AttendancePercent: participant.AttendancePercent,
+			ReadingsRated:     participant.RatingsSubmitted,
+			Completed: finished &&
+				participant.AttendancePercent >= criteria.MinAttendancePercent &&
+				participant.RatingsSubmitted >= criteria.MinReadingsRated,
+		}
+
+		if certificate, ok := issuedByUser[participant.UserID]; ok {
+			evaluation.Certificate = &certificate
+		} else if evaluation.Completed && !dryRun {
+			code, err := newCertificateCode()
+			if err != nil {
+				log.Printf("Error generating certificate code: %v", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+			}
+			certificate := models.Certificate{
+				Code:              code,
+				CourseID:          courseID,
+				UserID:            participant.UserID,
+				RecipientName:     participant.Name,
+				CourseTitle:       report.Course.Title,
+				AttendancePercent: participant.AttendancePercent,
+				ReadingsRated:     participant.RatingsSubmitted,
+				IssuedAt:          time.Now().UTC(),
+			}
+			issued, err := s.issueCertificate(certificate)
+			if err != nil {
+				log.Printf("Error inserting certificate: %v", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+			}
+			if issued.Code == certificate.Code {
+				log.Printf("Issued certificate %s to user %d for course %d", issued.Code, participant.UserID, courseID)
+			}
+			certificate = *issued
+			evaluation.Certificate = &certificate
+		}
+		evaluations = append(evaluations, evaluation)
+	}
+
+	return c.JSON(evaluations)
+}
+
+// courseFinished reports whether a course has discussions and all of them
+// have been held.
+func courseFinished(discussions []models.DiscussionTurnout) bool {
+	for _, discussion := range discussions {
+		if !discussion.Held {
+			return false
+		}
+	}
+	return len(discussions) > 0
+}
+
+// issueCertificate inserts a certificate through the issue_certificate
+// function in sql/certificates.sql. A participant who was issued one in the
+// meantime keeps it, and that certificate is returned instead.
+func (s *Server) issueCertificate(certificate models.Certificate) (*models.Certificate, error) {
+	var issued models.Certificate
+	err := rpc.Call(s.sb, "issue_certificate", map[string]interface{}{"certificate": certificate}, &issued)
+	if err != nil {
+		return nil, err
+	}
+	return &issued, nil
+}
+
+// verifyCertificate is public: anyone holding a verification code can confirm
+// the certificate was issued by us.
+func (s *Server) verifyCertificate(c *fiber.Ctx) error {
+	certificate, err := s.findCertificate(c.Params("code"))
+	if err != nil {
+		log.Printf("Error querying certificate: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if certificate == nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.CertificateVerification{Valid: false})
+	}
+
+	return c.JSON(models.CertificateVerification{
+		Valid:         true,
+		RecipientName: certificate.RecipientName,
+		CourseTitle:   certificate.CourseTitle,
+		IssuedAt:      certificate.IssuedAt,
+	})
+}
+
+func (s *Server) getCertificatePDF(c *fiber.Ctx) error {
+	certificate, err := s.findCertificate(c.Params("code"))
+	if err != nil {
+		log.Printf("Error querying certificate: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if certificate == nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Certificate not found"})
+	}
+
+	data, err := renderCertificatePDF(certificate, c.BaseURL())
+	if err != nil {
+		log.Printf("Error rendering certificate PDF: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Attachment(fmt.Sprintf("certificate-%s.pdf", certificate.Code))
+	return c.Send(data)
+}
+
+func (s *Server) findCertificate(code string) (*models.Certificate, error) {
+	var certificates []models.Certificate
+	err := s.sb.DB.From("certificates").
+		Select("*").
+		Eq("code", strings.ToUpper(code)).
+		Execute(&certificates)
+	if err != nil || len(certificates) == 0 {
+		return nil, err
+	}
+	return &certificates[0], nil
+}
+
+// newCertificateCode returns a random code of the form XXXX-XXXX-XXXX.
+func newCertificateCode() (string, error) {
+	raw := make([]byte, 12)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for i, v := range raw {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+		b.WriteByte(certificateCodeAlphabet[int(v)%len(certificateCodeAlphabet)])
+	}
+	return b.String(), nil
+}
+
+func renderCertificatePDF(certificate *models.Certificate, baseURL string) ([]byte, error) {
+	pdf := fpdf.New("L", "mm", "A4", "")
+	pdf.SetTitle("Certificate of Completion", true)
+	pdf.AddPage()
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetLineWidth(1)
+	pdf.Rect(10, 10, 277, 190, "D")
+
+	pdf.SetY(40)
+	pdf.SetFont("Times", "B", 32)
+	pdf.CellFormat(0, 16, "Certificate of Completion", "", 1, "C", false, 0, "")
+
+	pdf.Ln(8)
+	pdf.SetFont("Times", "", 16)
+	pdf.CellFormat(0, 10, "This certifies that", "", 1, "C", false, 0, "")
+	pdf.SetFont("Times", "B", 26)
+	pdf.CellFormat(0, 16, tr(certificate.RecipientName), "", 1, "C", false, 0, "")
+	pdf.SetFont("Times", "", 16)
+	pdf.CellFormat(0, 10, "has completed the course", "", 1, "C", false, 0, "")
+	pdf.SetFont("Times", "I", 22)
+	pdf.CellFormat(0, 14, tr(certificate.CourseTitle), "", 1, "C", false, 0, "")
+
+	pdf.Ln(6)
+	pdf.SetFont("Times", "", 12)
+	pdf.CellFormat(0, 8, fmt.Sprintf("Attendance %.1f%%, %d readings rated. Issued %s.",
+		certificate.AttendancePercent, certificate.ReadingsRated, certificate.IssuedAt.Format("January 2, 2006")), "", 1, "C", false, 0, "")
+
+	pdf.SetY(175)
+	pdf.SetFont("Courier", "", 11)
+	pdf.CellFormat(0, 6, "Verification code: "+certificate.Code, "", 1, "C", false, 0, "")
+	pdf.CellFormat(0, 6, baseURL+"/certificates/"+certificate.Code, "", 1, "C", false, 0, "")
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
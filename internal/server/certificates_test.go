@@ -0,0 +1,30 @@
+package server
+
+import (
+	"hippias-fiber/internal/models"
+	"testing"
+)
+
+func TestCourseFinished(t *testing.T) {
+	tests := []struct {
+		name string
+		held []bool
+		want bool
+	}{
+		{"no discussions", nil, false},
+		{"nothing held yet", []bool{false, false}, false},
+		{"first session held", []bool{true, false, false}, false},
+		{"all held", []bool{true, true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var discussions []models.DiscussionTurnout
+			for i, held := range tt.held {
+				discussions = append(discussions, models.DiscussionTurnout{DiscussionID: i + 1, Held: held})
+			}
+			if got := courseFinished(discussions); got != tt.want {
+				t.Errorf("courseFinished() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
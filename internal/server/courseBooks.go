@@ -0,0 +1,353 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"hippias-fiber/internal/models"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// listCourseBooks returns the books attached to a course in reading order.
+func (s *Server) listCourseBooks(courseID string) ([]models.CourseBookDto, error) {
+	courseBooks, err := s.courseBookRows(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := []models.CourseBookDto{}
+	if len(courseBooks) == 0 {
+		return dtos, nil
+	}
+
+	var bookIDs []string
+	for _, courseBook := range courseBooks {
+		bookIDs = append(bookIDs, strconv.Itoa(courseBook.BookID))
+	}
+	var books []models.Book
+	err = s.sb.DB.From("books").Select("*").In("id", bookIDs).Execute(&books)
+	if err != nil {
+		return nil, err
+	}
+	bookByID := map[int]models.Book{}
+	for _, book := range books {
+		bookByID[book.ID] = book
+	}
+
+	editionByID := map[int]models.Edition{}
+	var editionIDs []string
+	for _, courseBook := range courseBooks {
+		if courseBook.EditionID != 0 {
+			editionIDs = append(editionIDs, strconv.Itoa(courseBook.EditionID))
+		}
+	}
+	if len(editionIDs) > 0 {
+		var editions []models.Edition
+		err = s.sb.DB.From("editions").Select("*").In("id", editionIDs).Execute(&editions)
+		if err != nil {
+			return nil, err
+		}
+		for _, edition := range editions {
+			editionByID[edition.ID] = edition
+		}
+	}
+
+	for _, courseBook := range courseBooks {
+		book, ok := bookByID[courseBook.BookID]
+		if !ok {
+			log.Printf("Course %s references missing book %d", courseID, courseBook.BookID)
+			continue
+		}
+		dto := models.CourseBookDto{
+			Book:         book,
+			CourseBookID: courseBook.ID,
+			Position:     courseBook.Position,
+			Required:     courseBook.Required,
+		}
+		if edition, ok := editionByID[courseBook.EditionID]; ok {
+			dto.Edition = &edition
+		}
+		dtos = append(dtos, dto)
+	}
+	return dtos, nil
+}
+
+// courseBookRows returns the course_books rows of a course sorted by position.
+func (s *Server) courseBookRows(courseID string) ([]models.CourseBook, error) {
+	var courseBooks []models.CourseBook
+	err := s.sb.DB.From("course_books").
+		Select("*").
+		Eq("course_id", courseID).
+		Execute(&courseBooks)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(courseBooks, func(i, j int) bool {
+		return courseBooks[i].Position < courseBooks[j].Position
+	})
+	return courseBooks, nil
+}
+
+func (s *Server) getCourseBooks(c *fiber.Ctx) error {
+	books, err := s.listCourseBooks(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying course books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	return c.JSON(books)
+}
+
+// addCourseBook attaches a book to a course, at the end of the reading order
+// unless a position is given.
+func (s *Server) addCourseBook(c *fiber.Ctx) error {
+	courseID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid course ID"})
+	}
+	if _, err := s.requireFacilitator(c, courseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var body struct {
+		BookID    int  `json:"book_id"`
+		Required  bool `json:"required"`
+		Position  *int `json:"position"`
+		EditionID int  `json:"edition_id"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		log.Printf("Error parsing course book: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if _, err := s.getBookByID(strconv.Itoa(body.BookID)); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Book not found"})
+	}
+	if body.EditionID != 0 {
+		if _, err := s.checkEditionOfBook(body.EditionID, body.BookID); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+		}
+	}
+
+	courseBooks, err := s.courseBookRows(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying course books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	for _, courseBook := range courseBooks {
+		if courseBook.BookID == body.BookID {
+			return c.Status(fiber.StatusConflict).JSON(ErrorResponse{Message: "Book is already part of this course"})
+		}
+	}
+
+	position := len(courseBooks)
+	if body.Position != nil && *body.Position >= 0 && *body.Position < position {
+		position = *body.Position
+	}
+
+	now := time.Now().UTC()
+	courseBook := models.CourseBook{
+		CourseID:  courseID,
+		BookID:    body.BookID,
+		Position:  position,
+		Required:  body.Required,
+		EditionID: body.EditionID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	var created []models.CourseBook
+	err = s.sb.DB.From("course_books").Insert(courseBook).Execute(&created)
+	if err != nil {
+		log.Printf("Error inserting course book: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(created) > 0 {
+		courseBook = created[0]
+	}
+
+	// Shift the books at and after the insertion point down one place
+	order := make([]models.CourseBook, 0, len(courseBooks)+1)
+	order = append(order, courseBooks[:position]...)
+	order = append(order, courseBook)
+	order = append(order, courseBooks[position:]...)
+	if err := s.saveCourseBookPositions(order); err != nil {
+		log.Printf("Error reordering course books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	log.Printf("Added book %d to course %d at position %d", body.BookID, courseID, position)
+	return s.getCourseBooks(c)
+}
+
+// updateCourseBook marks a course's book as required or supplementary and sets
+// the edition the course uses. Only the fields present in the body change; an
+// explicit null edition_id clears the edition.
+func (s *Server) updateCourseBook(c *fiber.Ctx) error {
+	courseID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid course ID"})
+	}
+	if _, err := s.requireFacilitator(c, courseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var body struct {
+		Required  *bool `json:"required"`
+		EditionID *int  `json:"edition_id"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		log.Printf("Error parsing course book: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	// A null edition_id parses the same as a missing one, so look for the key
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(c.Body(), &fields); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	_, hasEdition := fields["edition_id"]
+	if body.Required == nil && !hasEdition {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Nothing to update; set required or edition_id"})
+	}
+
+	changes := map[string]interface{}{"updated_at": time.Now().UTC()}
+	if body.Required != nil {
+		changes["required"] = *body.Required
+	}
+	if hasEdition && (body.EditionID == nil || *body.EditionID == 0) {
+		changes["edition_id"] = nil
+	}
+	if body.EditionID != nil && *body.EditionID != 0 {
+		bookID, err := strconv.Atoi(c.Params("bookId"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid book ID"})
+		}
+		if _, err := s.checkEditionOfBook(*body.EditionID, bookID); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+		}
+		changes["edition_id"] = *body.EditionID
+	}
+
+	var updated []models.CourseBook
+	err = s.sb.DB.From("course_books").
+		Update(changes).
+		Eq("course_id", c.Params("id")).
+		Eq("book_id", c.Params("bookId")).
+		Execute(&updated)
+	if err != nil {
+		log.Printf("Error updating course book: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(updated) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Book is not part of this course"})
+	}
+
+	return c.JSON(updated[0])
+}
+
+func (s *Server) removeCourseBook(c *fiber.Ctx) error {
+	courseID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid course ID"})
+	}
+	if _, err := s.requireFacilitator(c, courseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var jsonResult json.RawMessage
+	err = s.sb.DB.From("course_books").
+		Delete().
+		Eq("course_id", c.Params("id")).
+		Eq("book_id", c.Params("bookId")).
+		Execute(&jsonResult)
+	if err != nil {
+		log.Printf("Error deleting course book: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	// Close the gap left in the reading order
+	courseBooks, err := s.courseBookRows(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying course books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if err := s.saveCourseBookPositions(courseBooks); err != nil {
+		log.Printf("Error reordering course books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	log.Printf("Removed book %s from course %d", c.Params("bookId"), courseID)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// reorderCourseBooks takes the complete list of the course's book IDs in their
+// new order.
+func (s *Server) reorderCourseBooks(c *fiber.Ctx) error {
+	courseID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid course ID"})
+	}
+	if _, err := s.requireFacilitator(c, courseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var body struct {
+		BookIDs []int `json:"book_ids"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		log.Printf("Error parsing course book order: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	courseBooks, err := s.courseBookRows(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying course books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	byBookID := map[int]models.CourseBook{}
+	for _, courseBook := range courseBooks {
+		byBookID[courseBook.BookID] = courseBook
+	}
+	if len(body.BookIDs) != len(courseBooks) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: fmt.Sprintf("book_ids must list all %d books of the course", len(courseBooks))})
+	}
+
+	order := make([]models.CourseBook, 0, len(body.BookIDs))
+	seen := map[int]bool{}
+	for _, bookID := range body.BookIDs {
+		courseBook, ok := byBookID[bookID]
+		if !ok || seen[bookID] {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: fmt.Sprintf("book %d is not part of this course or is listed twice", bookID)})
+		}
+		seen[bookID] = true
+		order = append(order, courseBook)
+	}
+
+	if err := s.saveCourseBookPositions(order); err != nil {
+		log.Printf("Error reordering course books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	return s.getCourseBooks(c)
+}
+
+// saveCourseBookPositions writes each row's index in order as its position,
+// skipping rows already in place.
+func (s *Server) saveCourseBookPositions(order []models.CourseBook) error {
+	now := time.Now().UTC()
+	for i, courseBook := range order {
+		if courseBook.Position == i {
+			continue
+		}
+		var jsonResult json.RawMessage
+		err := s.sb.DB.From("course_books").
+			Update(map[string]interface{}{"position": i, "updated_at": now}).
+			Eq("id", strconv.Itoa(courseBook.ID)).
+			Execute(&jsonResult)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
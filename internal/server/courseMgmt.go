@@ -4,10 +4,14 @@ import (
 	"encoding/json"
 	"hippias-fiber/internal/models"
 	"log"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// getCourseManagementDetails is open to course members. Anonymous raters and
+// per-participant attendance statistics are shown only to the facilitator.
 func (s *Server) getCourseManagementDetails(c *fiber.Ctx) error {
 	courseID := c.Params("id")
 	log.Printf("Fetching course management details for course %s", courseID)
@@ -46,13 +50,39 @@ func (s *Server) getCourseManagementDetails(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 	}
 
+	viewer, facilitator, err := s.requireCourseMember(c, course.ID)
+	if err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	courseLoc, err := loadLocation(course.Timezone)
+	if err != nil {
+		log.Printf("Error loading course timezone: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	loc, err := s.displayLocation(c, courseLoc)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	localizeDiscussions(discussions, loc)
+
+	participantDtos, err := s.listCourseParticipantDtos(course.ID, "id,name")
+	if err != nil {
+		log.Printf("Error querying course participants: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	participantIDs := make([]int, len(participantDtos))
+	for i, participant := range participantDtos {
+		participantIDs[i] = participant.UserID
+	}
+
 	var discussionDtos []models.DiscussionDto
 	for _, discussion := range discussions {
 		// Fetch readings for the discussion
 		var readingsResult json.RawMessage
 		err := s.sb.DB.From("readings").
 			Select("*").
-			Eq("discussion_id", string(discussion.ID)).
+			Eq("discussion_id", strconv.Itoa(discussion.ID)).
 			Execute(&readingsResult)
 		if err != nil {
 			log.Printf("Error querying readings: %v", err)
@@ -70,7 +100,7 @@ func (s *Server) getCourseManagementDetails(c *fiber.Ctx) error {
 		var ratingsResult json.RawMessage
 		err = s.sb.DB.From("reading_ratings").
 			Select("*").
-			Eq("discussion_id", string(discussion.ID)).
+			Eq("discussion_id", strconv.Itoa(discussion.ID)).
 			Execute(&ratingsResult)
 		if err != nil {
 			log.Printf("Error querying reading ratings: %v", err)
@@ -83,12 +113,13 @@ func (s *Server) getCourseManagementDetails(c *fiber.Ctx) error {
 			log.Printf("Error unmarshaling reading ratings: %v", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 		}
+		redactRatings(ratings, viewer, facilitator)
 
 		// Fetch attendance for the discussion
 		var attendanceResult json.RawMessage
 		err = s.sb.DB.From("discussion_attendance").
 			Select("*").
-			Eq("discussion_id", string(discussion.ID)).
+			Eq("discussion_id", strconv.Itoa(discussion.ID)).
 			Execute(&attendanceResult)
 		if err != nil {
 			log.Printf("Error querying discussion attendance: %v", err)
@@ -102,65 +133,102 @@ func (s *Server) getCourseManagementDetails(c *fiber.Ctx) error {
 			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 		}
 
+		progress, err := s.readingProgressFor(readings)
+		if err != nil {
+			log.Printf("Error querying reading progress: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+
+		minutes, err := s.latestMinutes(discussion.ID)
+		if err != nil {
+			log.Printf("Error querying minutes: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+
+		completion := discussionCompletion(discussion.ID, readings, participantIDs, progress)
+		// Individual progress is for the facilitator; others see the totals
+		if !facilitator {
+			progress = nil
+		}
+
 		discussionDto := models.DiscussionDto{
 			Discussion: discussion,
 			Readings:   readings,
 			Ratings:    ratings,
+			Progress:   progress,
+			Completion: completion,
 			Attendance: attendance,
+			Minutes:    minutes,
 		}
 		discussionDtos = append(discussionDtos, discussionDto)
 	}
 
-	// Fetch course participants
-	var participantsResult json.RawMessage
-	err = s.sb.DB.From("course_participants").
-		Select("*").
-		Eq("course_id", courseID).
-		Execute(&participantsResult)
-	if err != nil {
-		log.Printf("Error querying course participants: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	// No-show statistics compare RSVPs against recorded attendance; other
+	// participants' records are for the facilitator only
+	if facilitator {
+		var discussionIDs []string
+		var attendance []models.DiscussionAttendance
+		for _, dto := range discussionDtos {
+			discussionIDs = append(discussionIDs, strconv.Itoa(dto.ID))
+			attendance = append(attendance, dto.Attendance...)
+		}
+		var rsvps []models.DiscussionRsvp
+		if len(discussionIDs) > 0 {
+			err = s.sb.DB.From("discussion_rsvps").
+				Select("*").
+				In("discussion_id", discussionIDs).
+				Execute(&rsvps)
+			if err != nil {
+				log.Printf("Error querying RSVPs: %v", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+			}
+		}
+		stats := participantAttendanceStats(discussions, rsvps, attendance, time.Now())
+		for i := range participantDtos {
+			participantDtos[i].Stats = stats[participantDtos[i].UserID]
+			if participantDtos[i].Stats == nil {
+				participantDtos[i].Stats = &models.ParticipantAttendanceStats{}
+			}
+		}
+	}
+
+	courseMgmtDto := models.CourseMgmtDto{
+		Course:       course,
+		Discussions:  discussionDtos,
+		Participants: participantDtos,
 	}
 
+	return c.JSON(courseMgmtDto)
+}
+
+// listCourseParticipantDtos returns the participants of a course joined with
+// the given columns of their user records.
+func (s *Server) listCourseParticipantDtos(courseID int, userColumns string) ([]models.CourseParticipantDto, error) {
 	var participants []models.CourseParticipant
-	err = json.Unmarshal(participantsResult, &participants)
+	err := s.sb.DB.From("course_participants").
+		Select("*").
+		Eq("course_id", strconv.Itoa(courseID)).
+		Execute(&participants)
 	if err != nil {
-		log.Printf("Error unmarshaling course participants: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		return nil, err
 	}
 
 	var participantDtos []models.CourseParticipantDto
 	for _, participant := range participants {
-		var userResult json.RawMessage
+		var user models.User
 		err := s.sb.DB.From("users").
-			Select("*").
+			Select(userColumns).
 			Single().
-			Eq("id", string(participant.UserID)).
-			Execute(&userResult)
+			Eq("id", strconv.Itoa(participant.UserID)).
+			Execute(&user)
 		if err != nil {
-			log.Printf("Error querying user: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
-		}
-
-		var user models.User
-		err = json.Unmarshal(userResult, &user)
-		if err != nil {
-			log.Printf("Error unmarshaling user: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+			return nil, err
 		}
 
-		participantDto := models.CourseParticipantDto{
+		participantDtos = append(participantDtos, models.CourseParticipantDto{
 			CourseParticipant: participant,
 			User:              user,
-		}
-		participantDtos = append(participantDtos, participantDto)
+		})
 	}
-
-	courseMgmtDto := models.CourseMgmtDto{
-		Course:       course,
-		Discussions:  discussionDtos,
-		Participants: participantDtos,
-	}
-
-	return c.JSON(courseMgmtDto)
+	return participantDtos, nil
 }
@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"hippias-fiber/internal/models"
 	"log"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// GetDiscussionMgmtDetails is open to members of the discussion's course.
+// Anonymous raters are shown only to the facilitator.
 func (s *Server) GetDiscussionMgmtDetails(c *fiber.Ctx) error {
 	discussionID := c.Params("id")
 
@@ -29,10 +32,26 @@ func (s *Server) GetDiscussionMgmtDetails(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 	}
 
+	viewer, facilitator, err := s.requireCourseMember(c, discussion.CourseID)
+	if err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	courseLoc, err := s.courseLocation(discussion.CourseID)
+	if err != nil {
+		log.Printf("Error loading course timezone: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	loc, err := s.displayLocation(c, courseLoc)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	discussion.DateTime = discussion.DateTime.In(loc)
+
 	var participantsResult json.RawMessage
 	err = s.sb.DB.From("course_participants").
 		Select("*").
-		Eq("course_id", string(discussion.CourseID)).
+		Eq("course_id", strconv.Itoa(discussion.CourseID)).
 		Execute(&participantsResult)
 	if err != nil {
 		log.Printf("Error querying course participants: %v", err)
@@ -52,7 +71,7 @@ func (s *Server) GetDiscussionMgmtDetails(c *fiber.Ctx) error {
 		err := s.sb.DB.From("users").
 			Select("*").
 			Single().
-			Eq("id", string(participant.UserID)).
+			Eq("id", strconv.Itoa(participant.UserID)).
 			Execute(&userResult)
 		if err != nil {
 			log.Printf("Error querying user: %v", err)
@@ -96,7 +115,7 @@ func (s *Server) GetDiscussionMgmtDetails(c *fiber.Ctx) error {
 		var ratingsResult json.RawMessage
 		err := s.sb.DB.From("reading_ratings").
 			Select("*").
-			Eq("reading_id", string(reading.ID)).
+			Eq("reading_id", strconv.Itoa(reading.ID)).
 			Execute(&ratingsResult)
 		if err != nil {
 			log.Printf("Error querying reading ratings: %v", err)
@@ -109,6 +128,7 @@ func (s *Server) GetDiscussionMgmtDetails(c *fiber.Ctx) error {
 			log.Printf("Error unmarshaling reading ratings: %v", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 		}
+		redactRatings(ratings, viewer, facilitator)
 
 		readingDto := models.ReadingDto{
 			Reading: reading,
@@ -134,11 +154,42 @@ func (s *Server) GetDiscussionMgmtDetails(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 	}
 
+	questions, err := s.rankedQuestions(discussion.ID, viewer.ID)
+	if err != nil {
+		log.Printf("Error querying questions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	polls, err := s.discussionPolls(discussion.ID)
+	if err != nil {
+		log.Printf("Error querying polls: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	minutes, err := s.latestMinutes(discussion.ID)
+	if err != nil {
+		log.Printf("Error querying minutes: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	assignments, err := s.findAssignments(discussion.ID)
+	if err != nil {
+		log.Printf("Error querying assignments: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if assignments != nil && !assignments.Published && !facilitator {
+		assignments = nil
+	}
+
 	discussionMgmtDto := models.DiscussionMgmtDto{
 		Discussion:   discussion,
 		Participants: participantDtos,
 		Readings:     readingDtos,
 		Attendance:   attendance,
+		Questions:    questions,
+		Polls:        polls,
+		Minutes:      minutes,
+		Assignments:  assignments,
 	}
 
 	return c.JSON(discussionMgmtDto)
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"hippias-fiber/internal/models"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (s *Server) listBookEditions(c *fiber.Ctx) error {
+	var editions []models.Edition
+	err := s.sb.DB.From("editions").
+		Select("*").
+		Eq("book_id", c.Params("id")).
+		Execute(&editions)
+	if err != nil {
+		log.Printf("Error querying editions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	return c.JSON(editions)
+}
+
+func (s *Server) getEdition(c *fiber.Ctx) error {
+	edition, err := s.getEditionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying edition: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Edition not found"})
+	}
+
+	return c.JSON(edition)
+}
+
+func (s *Server) getEditionByID(editionID string) (*models.Edition, error) {
+	var jsonResult json.RawMessage
+	err := s.sb.DB.From("editions").
+		Select("*").
+		Single().
+		Eq("id", editionID).
+		Execute(&jsonResult)
+	if err != nil {
+		return nil, err
+	}
+
+	var edition models.Edition
+	if err := json.Unmarshal(jsonResult, &edition); err != nil {
+		return nil, err
+	}
+	return &edition, nil
+}
+
+func (s *Server) createEdition(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	bookID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid book ID"})
+	}
+	if _, err := s.getBookByID(c.Params("id")); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Book not found"})
+	}
+
+	var edition models.Edition
+	if err := c.BodyParser(&edition); err != nil {
+		log.Printf("Error parsing edition: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if edition.PageCount < 0 || edition.Year < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "page_count and year must not be negative"})
+	}
+
+	now := time.Now().UTC()
+	edition.ID = 0
+	edition.BookID = bookID
+	edition.CreatedAt = now
+	edition.UpdatedAt = now
+
+	var created []models.Edition
+	err = s.sb.DB.From("editions").Insert(edition).Execute(&created)
+	if err != nil {
+		log.Printf("Error inserting edition: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(created) > 0 {
+		edition = created[0]
+	}
+
+	log.Printf("Created edition: %+v", edition)
+	return c.Status(fiber.StatusCreated).JSON(edition)
+}
+
+func (s *Server) updateEdition(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	editionID := c.Params("id")
+
+	existing, err := s.getEditionByID(editionID)
+	if err != nil {
+		log.Printf("Error querying edition: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Edition not found"})
+	}
+
+	var edition models.Edition
+	if err := c.BodyParser(&edition); err != nil {
+		log.Printf("Error parsing edition: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if edition.PageCount < 0 || edition.Year < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "page_count and year must not be negative"})
+	}
+
+	edition.ID = existing.ID
+	edition.BookID = existing.BookID
+	edition.CreatedAt = existing.CreatedAt
+	edition.UpdatedAt = time.Now().UTC()
+
+	var updated []models.Edition
+	err = s.sb.DB.From("editions").Update(edition).Eq("id", editionID).Execute(&updated)
+	if err != nil {
+		log.Printf("Error updating edition: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(updated) > 0 {
+		edition = updated[0]
+	}
+
+	log.Printf("Updated edition: %+v", edition)
+	return c.JSON(edition)
+}
+
+// deleteEdition refuses to remove an edition a course or reading still uses.
+func (s *Server) deleteEdition(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	editionID := c.Params("id")
+
+	var courseBooks []models.CourseBook
+	err := s.sb.DB.From("course_books").Select("id").Eq("edition_id", editionID).Execute(&courseBooks)
+	if err != nil {
+		log.Printf("Error querying course books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	var readings []models.Reading
+	err = s.sb.DB.From("readings").Select("id").Eq("edition_id", editionID).Execute(&readings)
+	if err != nil {
+		log.Printf("Error querying readings: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(courseBooks) > 0 || len(readings) > 0 {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{Message: fmt.Sprintf("Edition is used by %d courses and %d readings", len(courseBooks), len(readings))})
+	}
+
+	var jsonResult json.RawMessage
+	err = s.sb.DB.From("editions").Delete().Eq("id", editionID).Execute(&jsonResult)
+	if err != nil {
+		log.Printf("Error deleting edition: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	log.Printf("Deleted edition with ID: %s", editionID)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// checkEditionOfBook returns the edition if it belongs to bookID.
+func (s *Server) checkEditionOfBook(editionID, bookID int) (*models.Edition, error) {
+	edition, err := s.getEditionByID(strconv.Itoa(editionID))
+	if err != nil {
+		return nil, fmt.Errorf("edition %d not found", editionID)
+	}
+	if edition.BookID != bookID {
+		return nil, fmt.Errorf("edition %d is not an edition of book %d", editionID, bookID)
+	}
+	return edition, nil
+}
+
+// validateReadingPages checks a reading's page range against its edition.
+func (s *Server) validateReadingPages(reading *models.Reading) error {
+	if reading.EditionID == 0 {
+		return nil
+	}
+	edition, err := s.checkEditionOfBook(reading.EditionID, reading.BookID)
+	if err != nil {
+		return err
+	}
+	if edition.PageCount > 0 && reading.PageEnd > edition.PageCount {
+		return fmt.Errorf("page_end %d is past the end of the edition (%d pages)", reading.PageEnd, edition.PageCount)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"hippias-fiber/internal/models"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// Events buffered per subscriber; a subscriber that falls further behind
+	// is dropped and resumes from the database when it reconnects
+	eventBufferSize = 64
+	// Missed events are replayed on reconnect in pages of this size
+	eventReplayPageSize  = 500
+	eventKeepalivePeriod = 15 * time.Second
+	// How long live events are held so ones published concurrently, which
+	// can arrive out of order, are sent in ID order
+	eventReorderDelay = 250 * time.Millisecond
+)
+
+// eventHub fans course events out to the streams subscribed to each course.
+type eventHub struct {
+	mu          sync.Mutex
+	subscribers map[int]map[chan models.CourseEvent]bool
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subscribers: map[int]map[chan models.CourseEvent]bool{}}
+}
+
+func (h *eventHub) subscribe(courseID int) chan models.CourseEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan models.CourseEvent, eventBufferSize)
+	if h.subscribers[courseID] == nil {
+		h.subscribers[courseID] = map[chan models.CourseEvent]bool{}
+	}
+	h.subscribers[courseID][ch] = true
+	return ch
+}
+
+func (h *eventHub) unsubscribe(courseID int, ch chan models.CourseEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[courseID][ch] {
+		delete(h.subscribers[courseID], ch)
+		close(ch)
+	}
+	if len(h.subscribers[courseID]) == 0 {
+		delete(h.subscribers, courseID)
+	}
+}
+
+func (h *eventHub) publish(event models.CourseEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[event.CourseID] {
+		select {
+		case ch <- event:
+		default:
+			delete(h.subscribers[event.CourseID], ch)
+			close(ch)
+		}
+	}
+}
+
+// publishCourseEvent stores an event and sends it to the course's streams.
+// Failures are logged rather than returned so they never fail the change
+// that caused the event.
+func (s *Server) publishCourseEvent(courseID int, eventType string, record interface{}) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		log.Printf("Error marshaling %s event: %v", eventType, err)
+		return
+	}
+	event := models.CourseEvent{CourseID: courseID, Type: eventType, Data: data, CreatedAt: time.Now().UTC()}
+
+	var created []models.CourseEvent
+	if err := s.sb.DB.From("course_events").Insert(event).Execute(&created); err != nil {
+		log.Printf("Error inserting %s event: %v", eventType, err)
+		return
+	}
+	if len(created) > 0 {
+		event = created[0]
+	}
+	s.events.publish(event)
+}
+
+// publishDiscussionEvent publishes an event for the course a discussion
+// belongs to.
+func (s *Server) publishDiscussionEvent(discussionID int, eventType string, record interface{}) {
+	discussion, err := s.getDiscussionByID(strconv.Itoa(discussionID))
+	if err != nil {
+		log.Printf("Error querying discussion for %s event: %v", eventType, err)
+		return
+	}
+	s.publishCourseEvent(discussion.CourseID, eventType, record)
+}
+
+// streamCourseEvents streams a course's events to members as server-sent
+// events. Clients that send Last-Event-ID (or ?last_event_id=) first receive
+// the events they missed.
+func (s *Server) streamCourseEvents(c *fiber.Ctx) error {
+	acceptQueryToken(c)
+	courseID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid course ID"})
+	}
+	if _, _, err := s.requireCourseMember(c, courseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	lastEventID := c.Get("Last-Event-ID", c.Query("last_event_id"))
+	var lastID int64
+	if lastEventID != "" {
+		lastID, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || lastID < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Last-Event-ID must be an event ID"})
+		}
+	}
+
+	// Subscribe before replaying so nothing published in between is lost
+	events := s.events.subscribe(courseID)
+	var missed []models.CourseEvent
+	if lastID > 0 {
+		missed, err = s.courseEventsSince(courseID, lastID)
+		if err != nil {
+			s.events.unsubscribe(courseID, events)
+			log.Printf("Error querying course events: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer s.events.unsubscribe(courseID, events)
+
+		// Events published while replaying arrive both ways; skip the live copy
+		replayed := map[int64]bool{}
+		for _, event := range missed {
+			if writeCourseEvent(w, event) != nil {
+				return
+			}
+			replayed[event.ID] = true
+		}
+		if w.Flush() != nil {
+			return
+		}
+
+		keepalive := time.NewTicker(eventKeepalivePeriod)
+		defer keepalive.Stop()
+		var pending []models.CourseEvent
+		var reorder <-chan time.Time
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				if event.ID <= lastID || replayed[event.ID] {
+					continue
+				}
+				if pending == nil {
+					reorder = time.After(eventReorderDelay)
+				}
+				pending = append(pending, event)
+				continue
+			case <-reorder:
+				sort.Slice(pending, func(i, j int) bool { return pending[i].ID < pending[j].ID })
+				for _, event := range pending {
+					if writeCourseEvent(w, event) != nil {
+						return
+					}
+				}
+				pending, reorder = nil, nil
+			case <-keepalive.C:
+				if _, err := w.WriteString(": keepalive\n\n"); err != nil {
+					return
+				}
+			}
+			if w.Flush() != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
+
+func writeCourseEvent(w *bufio.Writer, event models.CourseEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+// courseEventsSince returns a course's events after lastID in ID order,
+// fetching them a page at a time.
+func (s *Server) courseEventsSince(courseID int, lastID int64) ([]models.CourseEvent, error) {
+	var events []models.CourseEvent
+	for {
+		var page []models.CourseEvent
+		// The client has no Order, but Filter writes order=id.asc all the same
+		err := s.sb.DB.From("course_events").Select("*").
+			Limit(eventReplayPageSize).
+			Eq("course_id", strconv.Itoa(courseID)).
+			Gt("id", strconv.FormatInt(lastID, 10)).
+			Filter("order", "id", "asc").
+			Execute(&page)
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			return events, nil
+		}
+		sort.Slice(page, func(i, j int) bool { return page[i].ID < page[j].ID })
+		events = append(events, page...)
+		lastID = page[len(page)-1].ID
+	}
+}
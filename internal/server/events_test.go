@@ -0,0 +1,57 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	supa "github.com/nedpals/supabase-go"
+)
+
+func TestCourseEventsSincePages(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored int
+		lastID int64
+		want   int
+	}{
+		{name: "nothing missed", stored: 3, lastID: 3, want: 0},
+		{name: "less than a page", stored: 10, lastID: 4, want: 6},
+		{name: "exactly a page", stored: eventReplayPageSize, lastID: 0, want: eventReplayPageSize},
+		{name: "several pages", stored: 2*eventReplayPageSize + 7, lastID: 2, want: 2*eventReplayPageSize + 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.URL.Query().Get("order"); got != "id.asc" {
+					t.Errorf("order = %q, want id.asc", got)
+				}
+				after, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Query().Get("id"), "gt."))
+				var page []map[string]interface{}
+				// Served newest first within the page to check the sort
+				for id := min(after+eventReplayPageSize, tt.stored); id > after; id-- {
+					page = append(page, map[string]interface{}{"id": id, "course_id": 1, "type": "reading.added"})
+				}
+				json.NewEncoder(w).Encode(page)
+			}))
+			defer ts.Close()
+
+			s := &Server{sb: supa.CreateClient(ts.URL, "service-key")}
+			events, err := s.courseEventsSince(1, tt.lastID)
+			if err != nil {
+				t.Fatalf("courseEventsSince() error = %v", err)
+			}
+			if len(events) != tt.want {
+				t.Fatalf("got %d events, want %d", len(events), tt.want)
+			}
+			for i, event := range events {
+				if event.ID != tt.lastID+int64(i)+1 {
+					t.Fatalf("event %d has ID %d, want %d", i, event.ID, tt.lastID+int64(i)+1)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,542 @@
+package server
+
+import (
+	"errors"
+	"hippias-fiber/internal/models"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// listForumThreads pages through a discussion's threads, pinned first and then
+// by latest post. ?reading_id= limits it to threads about one reading.
+func (s *Server) listForumThreads(c *fiber.Ctx) error {
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	if _, _, err := s.requireCourseMember(c, discussion.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+	page, pageSize, err := pageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	query := s.sb.DB.From("forum_threads").Select("*").Eq("discussion_id", strconv.Itoa(discussion.ID))
+	if readingID := c.Query("reading_id"); readingID != "" {
+		query.Eq("reading_id", readingID)
+	}
+	var all []models.ForumThread
+	if err := query.Execute(&all); err != nil {
+		log.Printf("Error querying forum threads: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	threads := []models.ForumThread{}
+	for _, thread := range all {
+		if thread.DeletedAt == nil {
+			threads = append(threads, thread)
+		}
+	}
+	sort.SliceStable(threads, func(i, j int) bool {
+		if threads[i].Pinned != threads[j].Pinned {
+			return threads[i].Pinned
+		}
+		return threads[i].LastPostAt.After(threads[j].LastPostAt)
+	})
+	start, end := pageBounds(len(threads), page, pageSize)
+	pageThreads := threads[start:end]
+
+	dtos, err := s.forumThreadDtos(pageThreads)
+	if err != nil {
+		log.Printf("Error building forum threads: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	return c.JSON(models.ForumThreadPage{
+		Threads:  dtos,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    len(threads),
+	})
+}
+
+// forumThreadDtos adds author names and reply counts to threads.
+func (s *Server) forumThreadDtos(threads []models.ForumThread) ([]models.ForumThreadDto, error) {
+	dtos := make([]models.ForumThreadDto, 0, len(threads))
+	if len(threads) == 0 {
+		return dtos, nil
+	}
+
+	var threadIDs []string
+	userIDs := map[int]bool{}
+	for _, thread := range threads {
+		threadIDs = append(threadIDs, strconv.Itoa(thread.ID))
+		userIDs[thread.AuthorID] = true
+	}
+	var posts []models.ForumPost
+	err := s.sb.DB.From("forum_posts").Select("id,thread_id,deleted_at").In("thread_id", threadIDs).Execute(&posts)
+	if err != nil {
+		return nil, err
+	}
+	opening := map[int]int{}
+	for _, post := range posts {
+		if first, ok := opening[post.ThreadID]; !ok || post.ID < first {
+			opening[post.ThreadID] = post.ID
+		}
+	}
+	replies := map[int]int{}
+	for _, post := range posts {
+		if post.DeletedAt == nil && post.ID != opening[post.ThreadID] {
+			replies[post.ThreadID]++
+		}
+	}
+
+	names, err := s.userNames(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, thread := range threads {
+		dtos = append(dtos, models.ForumThreadDto{
+			ForumThread: thread,
+			AuthorName:  names[thread.AuthorID],
+			ReplyCount:  replies[thread.ID],
+		})
+	}
+	return dtos, nil
+}
+
+// userNames looks up the display names of users by ID.
+func (s *Server) userNames(userIDs map[int]bool) (map[int]string, error) {
+	names := map[int]string{}
+	if len(userIDs) == 0 {
+		return names, nil
+	}
+	var ids []string
+	for id := range userIDs {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	var users []models.User
+	if err := s.sb.DB.From("users").Select("id,name").In("id", ids).Execute(&users); err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		names[user.ID] = user.Name
+	}
+	return names, nil
+}
+
+// createForumThread starts a thread with its opening post. Any course member
+// can start one.
+func (s *Server) createForumThread(c *fiber.Ctx) error {
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	user, _, err := s.requireCourseMember(c, discussion.CourseID)
+	if err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var req models.ForumThreadRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("Error parsing forum thread: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" || strings.TrimSpace(req.Body) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "title and body are required"})
+	}
+	if req.ReadingID != nil {
+		var readings []models.Reading
+		err := s.sb.DB.From("readings").Select("id").
+			Eq("id", strconv.Itoa(*req.ReadingID)).
+			Eq("discussion_id", strconv.Itoa(discussion.ID)).
+			Execute(&readings)
+		if err != nil {
+			log.Printf("Error querying reading: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+		if len(readings) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "reading_id is not a reading of this discussion"})
+		}
+	}
+
+	now := time.Now().UTC()
+	thread := models.ForumThread{
+		DiscussionID: discussion.ID,
+		ReadingID:    req.ReadingID,
+		AuthorID:     user.ID,
+		Title:        req.Title,
+		CreatedAt:    now,
+		LastPostAt:   now,
+	}
+	var created []models.ForumThread
+	if err := s.sb.DB.From("forum_threads").Insert(thread).Execute(&created); err != nil {
+		log.Printf("Error inserting forum thread: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(created) == 0 {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: "thread insert returned no rows"})
+	}
+	thread = created[0]
+
+	post := models.ForumPost{ThreadID: thread.ID, AuthorID: user.ID, Body: req.Body, CreatedAt: now, UpdatedAt: now}
+	var posts []models.ForumPost
+	if err := s.sb.DB.From("forum_posts").Insert(post).Execute(&posts); err != nil {
+		log.Printf("Error inserting opening post: %v", err)
+		// Don't leave a thread without its opening post behind
+		var deleted []models.ForumThread
+		if err := s.sb.DB.From("forum_threads").Delete().Eq("id", strconv.Itoa(thread.ID)).Execute(&deleted); err != nil {
+			log.Printf("Error removing empty thread %d: %v", thread.ID, err)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	log.Printf("Created forum thread: %+v", thread)
+	return c.Status(fiber.StatusCreated).JSON(models.ForumThreadDto{ForumThread: thread, AuthorName: user.Name})
+}
+
+var errThreadNotFound = errors.New("thread not found")
+
+// forumThreadAccess loads a live thread and checks the user belongs to its
+// course, returning whether they facilitate it.
+func (s *Server) forumThreadAccess(c *fiber.Ctx, threadID string) (*models.ForumThread, *models.User, bool, error) {
+	var threads []models.ForumThread
+	if err := s.sb.DB.From("forum_threads").Select("*").Eq("id", threadID).Execute(&threads); err != nil {
+		return nil, nil, false, err
+	}
+	if len(threads) == 0 || threads[0].DeletedAt != nil {
+		return nil, nil, false, errThreadNotFound
+	}
+	discussion, err := s.getDiscussionByID(strconv.Itoa(threads[0].DiscussionID))
+	if err != nil {
+		return nil, nil, false, err
+	}
+	user, facilitator, err := s.requireCourseMember(c, discussion.CourseID)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return &threads[0], user, facilitator, nil
+}
+
+func forumErrorResponse(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errThreadNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Thread not found"})
+	}
+	return authErrorResponse(c, err)
+}
+
+// getForumThread returns a thread with one page of its posts in posting order.
+// Replies carry parent_id, from which clients build the reply tree.
+func (s *Server) getForumThread(c *fiber.Ctx) error {
+	thread, _, _, err := s.forumThreadAccess(c, c.Params("id"))
+	if err != nil {
+		return forumErrorResponse(c, err)
+	}
+	page, pageSize, err := pageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	var posts []models.ForumPost
+	err = s.sb.DB.From("forum_posts").Select("*").Eq("thread_id", strconv.Itoa(thread.ID)).Execute(&posts)
+	if err != nil {
+		log.Printf("Error querying forum posts: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	sort.Slice(posts, func(i, j int) bool { return posts[i].ID < posts[j].ID })
+
+	threadDtos, err := s.forumThreadDtos([]models.ForumThread{*thread})
+	if err != nil {
+		log.Printf("Error building forum thread: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	start, end := pageBounds(len(posts), page, pageSize)
+	pagePosts := posts[start:end]
+	userIDs := map[int]bool{}
+	for _, post := range pagePosts {
+		userIDs[post.AuthorID] = true
+	}
+	names, err := s.userNames(userIDs)
+	if err != nil {
+		log.Printf("Error querying post authors: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	dtos := make([]models.ForumPostDto, 0, len(pagePosts))
+	for _, post := range pagePosts {
+		dtos = append(dtos, forumPostDto(post, names[post.AuthorID]))
+	}
+	return c.JSON(models.ForumThreadDetail{
+		Thread:   threadDtos[0],
+		Posts:    dtos,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    len(posts),
+	})
+}
+
+func forumPostDto(post models.ForumPost, authorName string) models.ForumPostDto {
+	dto := models.ForumPostDto{ForumPost: post, AuthorName: authorName}
+	if post.DeletedAt != nil {
+		dto.Deleted = true
+		dto.Body = ""
+		return dto
+	}
+	dto.BodyHTML = renderMarkdown(post.Body)
+	return dto
+}
+
+// createForumPost replies to a thread, or to a post in it when parent_id is
+// given. Only the facilitator can post in a locked thread.
+func (s *Server) createForumPost(c *fiber.Ctx) error {
+	thread, user, facilitator, err := s.forumThreadAccess(c, c.Params("id"))
+	if err != nil {
+		return forumErrorResponse(c, err)
+	}
+	if thread.Locked && !facilitator {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{Message: "Thread is locked"})
+	}
+
+	var req models.ForumPostRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("Error parsing forum post: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "body is required"})
+	}
+	if req.ParentID != nil {
+		parent, err := s.getForumPost(strconv.Itoa(*req.ParentID))
+		if err != nil || parent.ThreadID != thread.ID {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "parent_id is not a post in this thread"})
+		}
+	}
+
+	now := time.Now().UTC()
+	post := models.ForumPost{
+		ThreadID:  thread.ID,
+		ParentID:  req.ParentID,
+		AuthorID:  user.ID,
+		Body:      req.Body,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	var created []models.ForumPost
+	if err := s.sb.DB.From("forum_posts").Insert(post).Execute(&created); err != nil {
+		log.Printf("Error inserting forum post: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(created) > 0 {
+		post = created[0]
+	}
+
+	var updated []models.ForumThread
+	err = s.sb.DB.From("forum_threads").
+		Update(map[string]interface{}{"last_post_at": now}).
+		Eq("id", strconv.Itoa(thread.ID)).
+		Execute(&updated)
+	if err != nil {
+		log.Printf("Error updating thread activity: %v", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(forumPostDto(post, user.Name))
+}
+
+func (s *Server) getForumPost(postID string) (*models.ForumPost, error) {
+	var posts []models.ForumPost
+	if err := s.sb.DB.From("forum_posts").Select("*").Eq("id", postID).Execute(&posts); err != nil {
+		return nil, err
+	}
+	if len(posts) == 0 {
+		return nil, errors.New("post not found")
+	}
+	return &posts[0], nil
+}
+
+// forumPostAccess loads a post and its thread and checks course membership.
+func (s *Server) forumPostAccess(c *fiber.Ctx) (*models.ForumPost, *models.ForumThread, *models.User, bool, error) {
+	post, err := s.getForumPost(c.Params("id"))
+	if err != nil {
+		return nil, nil, nil, false, errThreadNotFound
+	}
+	thread, user, facilitator, err := s.forumThreadAccess(c, strconv.Itoa(post.ThreadID))
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+	return post, thread, user, facilitator, nil
+}
+
+// updateForumPost lets an author edit their post, keeping the previous body
+// as a revision.
+func (s *Server) updateForumPost(c *fiber.Ctx) error {
+	post, thread, user, facilitator, err := s.forumPostAccess(c)
+	if err != nil {
+		return forumErrorResponse(c, err)
+	}
+	if post.AuthorID != user.ID {
+		return authErrorResponse(c, errForbidden)
+	}
+	if post.DeletedAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{Message: "Post has been deleted"})
+	}
+	if thread.Locked && !facilitator {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{Message: "Thread is locked"})
+	}
+
+	var req models.ForumPostRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("Error parsing forum post: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "body is required"})
+	}
+	if req.Body == post.Body {
+		return c.JSON(forumPostDto(*post, user.Name))
+	}
+
+	now := time.Now().UTC()
+	revision := models.ForumPostRevision{PostID: post.ID, Body: post.Body, EditorID: user.ID, EditedAt: now}
+	var revisions []models.ForumPostRevision
+	if err := s.sb.DB.From("forum_post_revisions").Insert(revision).Execute(&revisions); err != nil {
+		log.Printf("Error saving post revision: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	var updated []models.ForumPost
+	err = s.sb.DB.From("forum_posts").
+		Update(map[string]interface{}{"body": req.Body, "updated_at": now, "edit_count": post.EditCount + 1}).
+		Eq("id", strconv.Itoa(post.ID)).
+		Execute(&updated)
+	if err != nil {
+		log.Printf("Error updating forum post: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(updated) > 0 {
+		post = &updated[0]
+	}
+	return c.JSON(forumPostDto(*post, user.Name))
+}
+
+// listForumPostRevisions returns a post's earlier bodies, newest first, to its
+// author and the facilitator.
+func (s *Server) listForumPostRevisions(c *fiber.Ctx) error {
+	post, _, user, facilitator, err := s.forumPostAccess(c)
+	if err != nil {
+		return forumErrorResponse(c, err)
+	}
+	if post.AuthorID != user.ID && !facilitator {
+		return authErrorResponse(c, errForbidden)
+	}
+
+	var revisions []models.ForumPostRevision
+	err = s.sb.DB.From("forum_post_revisions").Select("*").Eq("post_id", strconv.Itoa(post.ID)).Execute(&revisions)
+	if err != nil {
+		log.Printf("Error querying post revisions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].ID > revisions[j].ID })
+	return c.JSON(revisions)
+}
+
+// deleteForumPost soft-deletes a post: it stays in the thread, so replies keep
+// their place, but its body is no longer shown. Authors can delete their own
+// posts and the facilitator any post.
+func (s *Server) deleteForumPost(c *fiber.Ctx) error {
+	post, _, user, facilitator, err := s.forumPostAccess(c)
+	if err != nil {
+		return forumErrorResponse(c, err)
+	}
+	if post.AuthorID != user.ID && !facilitator {
+		return authErrorResponse(c, errForbidden)
+	}
+	if post.DeletedAt != nil {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	var updated []models.ForumPost
+	err = s.sb.DB.From("forum_posts").
+		Update(map[string]interface{}{"deleted_at": time.Now().UTC(), "deleted_by": user.ID}).
+		Eq("id", strconv.Itoa(post.ID)).
+		Execute(&updated)
+	if err != nil {
+		log.Printf("Error deleting forum post: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	log.Printf("User %d deleted forum post %d", user.ID, post.ID)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// deleteForumThread soft-deletes a thread, hiding it from the discussion.
+func (s *Server) deleteForumThread(c *fiber.Ctx) error {
+	thread, user, facilitator, err := s.forumThreadAccess(c, c.Params("id"))
+	if err != nil {
+		return forumErrorResponse(c, err)
+	}
+	if thread.AuthorID != user.ID && !facilitator {
+		return authErrorResponse(c, errForbidden)
+	}
+
+	var updated []models.ForumThread
+	err = s.sb.DB.From("forum_threads").
+		Update(map[string]interface{}{"deleted_at": time.Now().UTC()}).
+		Eq("id", strconv.Itoa(thread.ID)).
+		Execute(&updated)
+	if err != nil {
+		log.Printf("Error deleting forum thread: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	log.Printf("User %d deleted forum thread %d", user.ID, thread.ID)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// moderateForumThread lets the facilitator pin or lock a thread.
+func (s *Server) moderateForumThread(c *fiber.Ctx) error {
+	thread, _, facilitator, err := s.forumThreadAccess(c, c.Params("id"))
+	if err != nil {
+		return forumErrorResponse(c, err)
+	}
+	if !facilitator {
+		return authErrorResponse(c, errForbidden)
+	}
+
+	var req models.ForumModerationRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("Error parsing moderation request: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	changes := map[string]interface{}{}
+	if req.Pinned != nil {
+		changes["pinned"] = *req.Pinned
+	}
+	if req.Locked != nil {
+		changes["locked"] = *req.Locked
+	}
+	if len(changes) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "pinned or locked is required"})
+	}
+
+	var updated []models.ForumThread
+	err = s.sb.DB.From("forum_threads").Update(changes).Eq("id", strconv.Itoa(thread.ID)).Execute(&updated)
+	if err != nil {
+		log.Printf("Error moderating forum thread: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(updated) > 0 {
+		thread = &updated[0]
+	}
+
+	log.Printf("Moderated forum thread %d: %+v", thread.ID, changes)
+	return c.JSON(thread)
+}
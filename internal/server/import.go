@@ -0,0 +1,60 @@
+package server
+
+import (
+	"bytes"
+	"hippias-fiber/internal/importer"
+	"io"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// importCatalog loads books and authors from an uploaded Open Library dump or
+// ISBN metadata file. It only returns the planned changes unless ?commit=true.
+func (s *Server) importCatalog(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var input io.ReadSeeker = bytes.NewReader(c.Body())
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+		}
+		defer file.Close()
+		input = file
+	}
+
+	records, err := importer.Parse(c.Query("source", importer.SourceOpenLibrary), input)
+	if err != nil {
+		log.Printf("Error parsing import: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	im := importer.New(s.sb)
+	plan, err := im.Plan(records)
+	if err != nil {
+		log.Printf("Error planning import: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	response := ImportResponse{Plan: plan}
+	if c.QueryBool("commit") {
+		result, err := im.Apply(plan)
+		if err != nil {
+			log.Printf("Error applying import: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+		log.Printf("Imported catalog: %+v", result)
+		response.Result = result
+	}
+
+	return c.JSON(response)
+}
+
+type ImportResponse struct {
+	Plan *importer.Plan `json:"plan"`
+	// Set only when the import was committed
+	Result *importer.Result `json:"result,omitempty"`
+}
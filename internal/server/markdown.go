@@ -0,0 +1,21 @@
+package server
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdownRenderer renders user-written markdown. Raw HTML and javascript:
+// links are dropped, since goldmark is not put in unsafe mode.
+var markdownRenderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// renderMarkdown converts markdown to HTML that is safe to embed in a page.
+func renderMarkdown(source string) string {
+	var out bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(source), &out); err != nil {
+		return ""
+	}
+	return out.String()
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"fmt"
+	"hippias-fiber/internal/dedupe"
+	"hippias-fiber/internal/models"
+	"hippias-fiber/internal/rpc"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// listAuthorDuplicates proposes authors to merge, best matches first.
+// ?min_score= (0-1) trades recall for precision.
+func (s *Server) listAuthorDuplicates(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var authors []models.Author
+	if err := s.sb.DB.From("authors").Select("*").Execute(&authors); err != nil {
+		log.Printf("Error querying authors: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	var books []models.Book
+	if err := s.sb.DB.From("books").Select("id,authorId").Execute(&books); err != nil {
+		log.Printf("Error querying books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	bookCounts := map[int]int{}
+	for _, book := range books {
+		bookCounts[book.AuthorID]++
+	}
+
+	minScore := c.QueryFloat("min_score", dedupe.DefaultMinScore)
+	return c.JSON(dedupe.Authors(authors, bookCounts, minScore))
+}
+
+// listBookDuplicates proposes books to merge, best matches first.
+func (s *Server) listBookDuplicates(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var books []models.Book
+	if err := s.sb.DB.From("books").Select("*").Execute(&books); err != nil {
+		log.Printf("Error querying books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	var courseBooks []models.CourseBook
+	if err := s.sb.DB.From("course_books").Select("book_id").Execute(&courseBooks); err != nil {
+		log.Printf("Error querying course books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	var readings []models.Reading
+	if err := s.sb.DB.From("readings").Select("book_id").Execute(&readings); err != nil {
+		log.Printf("Error querying readings: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	refCounts := map[int]int{}
+	for _, courseBook := range courseBooks {
+		refCounts[courseBook.BookID]++
+	}
+	for _, reading := range readings {
+		refCounts[reading.BookID]++
+	}
+
+	minScore := c.QueryFloat("min_score", dedupe.DefaultMinScore)
+	return c.JSON(dedupe.Books(books, refCounts, minScore))
+}
+
+// mergeAuthor merges the author in the path into the one named by "into",
+// moving their books over and leaving a redirect from the old ID.
+func (s *Server) mergeAuthor(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	fromID, into, err := parseMerge(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if _, err := s.getAuthorByID(strconv.Itoa(fromID)); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Author not found"})
+	}
+	if _, err := s.getAuthorByID(strconv.Itoa(into)); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "into: author not found"})
+	}
+
+	if err := s.mergeRecords("merge_authors", fromID, into); err != nil {
+		log.Printf("Error merging author %d into %d: %v", fromID, into, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	log.Printf("Merged author %d into %d", fromID, into)
+
+	survivor, err := s.getAuthorByID(strconv.Itoa(into))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	return c.JSON(survivor)
+}
+
+// mergeBook merges the book in the path into the one named by "into",
+// re-pointing course books, readings and editions and leaving a redirect.
+func (s *Server) mergeBook(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	fromID, into, err := parseMerge(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if _, err := s.getBookByID(strconv.Itoa(fromID)); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Book not found"})
+	}
+	if _, err := s.getBookByID(strconv.Itoa(into)); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "into: book not found"})
+	}
+
+	// Courses listing both books lose a row in the merge, leaving a gap in
+	// their reading order
+	var listed []models.CourseBook
+	err = s.sb.DB.From("course_books").Select("course_id").Eq("book_id", strconv.Itoa(fromID)).Execute(&listed)
+	if err != nil {
+		log.Printf("Error querying course books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	if err := s.mergeRecords("merge_books", fromID, into); err != nil {
+		log.Printf("Error merging book %d into %d: %v", fromID, into, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	log.Printf("Merged book %d into %d", fromID, into)
+
+	for _, courseBook := range listed {
+		courseBooks, err := s.courseBookRows(strconv.Itoa(courseBook.CourseID))
+		if err == nil {
+			err = s.saveCourseBookPositions(courseBooks)
+		}
+		if err != nil {
+			log.Printf("Error reordering course books: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+	}
+
+	survivor, err := s.getBookByID(strconv.Itoa(into))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	return c.JSON(survivor)
+}
+
+func parseMerge(c *fiber.Ctx) (fromID, into int, err error) {
+	fromID, err = strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid id")
+	}
+	var req models.MergeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return 0, 0, err
+	}
+	if req.Into == 0 {
+		return 0, 0, fmt.Errorf("into is required")
+	}
+	if req.Into == fromID {
+		return 0, 0, fmt.Errorf("cannot merge a record into itself")
+	}
+	return fromID, req.Into, nil
+}
+
+// mergeRecords calls one of the merge functions in sql/merge_records.sql,
+// which do all of a merge in a single transaction.
+func (s *Server) mergeRecords(function string, fromID, into int) error {
+	return rpc.Call(s.sb, function, map[string]int{"from_id": fromID, "into_id": into}, nil)
+}
+
+// redirectedID returns the ID that a merged author or book now lives under.
+// table is author_redirects or book_redirects.
+func (s *Server) redirectedID(table, id string) (int, bool) {
+	var redirects []models.Redirect
+	err := s.sb.DB.From(table).Select("*").Eq("old_id", id).Execute(&redirects)
+	if err != nil || len(redirects) == 0 {
+		return 0, false
+	}
+	return redirects[0].NewID, true
+}
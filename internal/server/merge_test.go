@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	supa "github.com/nedpals/supabase-go"
+)
+
+func TestMergeRecordsCallsFunction(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		reply   string
+		wantErr string
+	}{
+		{name: "success", status: http.StatusNoContent},
+		{name: "function error", status: http.StatusBadRequest,
+			reply: `{"code":"P0001","message":"book 9 not found"}`, wantErr: "merge_books failed: book 9 not found"},
+		{name: "error without body", status: http.StatusBadGateway, wantErr: "502"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath, gotKey string
+			var gotBody map[string]int
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.Method + " " + r.URL.Path
+				gotKey = r.Header.Get("apikey")
+				json.NewDecoder(r.Body).Decode(&gotBody)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.reply))
+			}))
+			defer ts.Close()
+
+			s := &Server{sb: supa.CreateClient(ts.URL, "service-key")}
+			err := s.mergeRecords("merge_books", 4, 9)
+
+			if gotPath != "POST /rest/v1/rpc/merge_books" {
+				t.Errorf("request = %q, want POST /rest/v1/rpc/merge_books", gotPath)
+			}
+			if gotKey != "service-key" {
+				t.Errorf("apikey header = %q", gotKey)
+			}
+			if gotBody["from_id"] != 4 || gotBody["into_id"] != 9 {
+				t.Errorf("body = %v", gotBody)
+			}
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"fmt"
+	"hippias-fiber/internal/models"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// getMinutes returns the latest version of a discussion's minutes.
+func (s *Server) getMinutes(c *fiber.Ctx) error {
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	if _, _, err := s.requireCourseMember(c, discussion.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	minutes, err := s.latestMinutes(discussion.ID)
+	if err != nil {
+		log.Printf("Error querying minutes: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if minutes == nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "No minutes have been written for this discussion"})
+	}
+	return c.JSON(minutes)
+}
+
+// listMinutesVersions returns every version of a discussion's minutes, newest first.
+func (s *Server) listMinutesVersions(c *fiber.Ctx) error {
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	if _, _, err := s.requireCourseMember(c, discussion.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	versions, err := s.minutesVersions(discussion.ID)
+	if err != nil {
+		log.Printf("Error querying minutes: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	return c.JSON(versions)
+}
+
+func (s *Server) getMinutesVersion(c *fiber.Ctx) error {
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	if _, _, err := s.requireCourseMember(c, discussion.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+	version, err := strconv.Atoi(c.Params("version"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid version"})
+	}
+
+	versions, err := s.minutesVersions(discussion.ID)
+	if err != nil {
+		log.Printf("Error querying minutes: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	for _, minutes := range versions {
+		if minutes.Version == version {
+			return c.JSON(minutes)
+		}
+	}
+	return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Minutes version not found"})
+}
+
+// saveMinutes stores a new version of the minutes, attributed to the current
+// user. Any course member can write them, since the note-taker is usually a
+// participant.
+func (s *Server) saveMinutes(c *fiber.Ctx) error {
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	user, _, err := s.requireCourseMember(c, discussion.CourseID)
+	if err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var body models.MinutesRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Printf("Error parsing minutes: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	minutes, err := minutesFromRequest(body)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	latest, err := s.latestMinutes(discussion.ID)
+	if err != nil {
+		log.Printf("Error querying minutes: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	current := 0
+	if latest != nil {
+		current = latest.Version
+	}
+	if body.BaseVersion != current {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Message: fmt.Sprintf("the minutes have been updated to version %d since version %d", current, body.BaseVersion),
+		})
+	}
+
+	minutes.DiscussionID = discussion.ID
+	minutes.Version = current + 1
+	minutes.AuthorID = user.ID
+	minutes.CreatedAt = time.Now().UTC()
+
+	var created []models.DiscussionMinutes
+	if err := s.sb.DB.From("discussion_minutes").Insert(minutes).Execute(&created); err != nil {
+		log.Printf("Error inserting minutes: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(created) > 0 {
+		minutes = created[0]
+	}
+
+	log.Printf("Saved version %d of the minutes for discussion %d", minutes.Version, discussion.ID)
+	return c.Status(fiber.StatusCreated).JSON(models.DiscussionMinutesDto{
+		DiscussionMinutes: minutes,
+		AuthorName:        user.Name,
+		BodyHTML:          renderMarkdown(minutes.Body),
+	})
+}
+
+func minutesFromRequest(body models.MinutesRequest) (models.DiscussionMinutes, error) {
+	minutes := models.DiscussionMinutes{
+		Body:          strings.TrimSpace(body.Body),
+		KeyQuotes:     []models.Quote{},
+		OpenQuestions: []string{},
+	}
+	if minutes.Body == "" {
+		return minutes, fmt.Errorf("body is required")
+	}
+	for _, quote := range body.KeyQuotes {
+		quote.Text = strings.TrimSpace(quote.Text)
+		quote.Attribution = strings.TrimSpace(quote.Attribution)
+		if quote.Text == "" {
+			return minutes, fmt.Errorf("key quotes must have text")
+		}
+		if quote.Page != nil && *quote.Page < 1 {
+			return minutes, fmt.Errorf("page must be positive")
+		}
+		minutes.KeyQuotes = append(minutes.KeyQuotes, quote)
+	}
+	for _, question := range body.OpenQuestions {
+		if question = strings.TrimSpace(question); question != "" {
+			minutes.OpenQuestions = append(minutes.OpenQuestions, question)
+		}
+	}
+	return minutes, nil
+}
+
+// minutesVersions returns a discussion's minutes, newest version first.
+func (s *Server) minutesVersions(discussionID int) ([]models.DiscussionMinutesDto, error) {
+	var versions []models.DiscussionMinutes
+	err := s.sb.DB.From("discussion_minutes").Select("*").Eq("discussion_id", strconv.Itoa(discussionID)).Execute(&versions)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version > versions[j].Version })
+
+	authors := map[int]bool{}
+	for _, minutes := range versions {
+		authors[minutes.AuthorID] = true
+	}
+	names, err := s.userNames(authors)
+	if err != nil {
+		return nil, err
+	}
+	dtos := make([]models.DiscussionMinutesDto, len(versions))
+	for i, minutes := range versions {
+		dtos[i] = models.DiscussionMinutesDto{
+			DiscussionMinutes: minutes,
+			AuthorName:        names[minutes.AuthorID],
+			BodyHTML:          renderMarkdown(minutes.Body),
+		}
+	}
+	return dtos, nil
+}
+
+// latestMinutesFor returns the current minutes of each of the discussions
+// that has any, keyed by discussion ID.
+func (s *Server) latestMinutesFor(discussionIDs []string) (map[int]models.DiscussionMinutesDto, error) {
+	latest := map[int]models.DiscussionMinutesDto{}
+	if len(discussionIDs) == 0 {
+		return latest, nil
+	}
+	var versions []models.DiscussionMinutes
+	err := s.sb.DB.From("discussion_minutes").Select("*").In("discussion_id", discussionIDs).Execute(&versions)
+	if err != nil {
+		return nil, err
+	}
+
+	current := map[int]models.DiscussionMinutes{}
+	for _, minutes := range versions {
+		if existing, ok := current[minutes.DiscussionID]; !ok || minutes.Version > existing.Version {
+			current[minutes.DiscussionID] = minutes
+		}
+	}
+	authors := map[int]bool{}
+	for _, minutes := range current {
+		authors[minutes.AuthorID] = true
+	}
+	names, err := s.userNames(authors)
+	if err != nil {
+		return nil, err
+	}
+	for id, minutes := range current {
+		latest[id] = models.DiscussionMinutesDto{
+			DiscussionMinutes: minutes,
+			AuthorName:        names[minutes.AuthorID],
+			BodyHTML:          renderMarkdown(minutes.Body),
+		}
+	}
+	return latest, nil
+}
+
+// latestMinutes returns the current minutes of a discussion, or nil if none
+// have been written.
+func (s *Server) latestMinutes(discussionID int) (*models.DiscussionMinutesDto, error) {
+	versions, err := s.minutesVersions(discussionID)
+	if err != nil || len(versions) == 0 {
+		return nil, err
+	}
+	return &versions[0], nil
+}
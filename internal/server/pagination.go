@@ -0,0 +1,32 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageParams reads ?page= (from 1) and ?page_size=.
+func pageParams(c *fiber.Ctx) (page, pageSize int, err error) {
+	page = c.QueryInt("page", 1)
+	pageSize = c.QueryInt("page_size", defaultPageSize)
+	if page < 1 {
+		return 0, 0, fmt.Errorf("page must be at least 1")
+	}
+	if pageSize < 1 || pageSize > maxPageSize {
+		return 0, 0, fmt.Errorf("page_size must be between 1 and %d", maxPageSize)
+	}
+	return page, pageSize, nil
+}
+
+// pageBounds returns the slice bounds of a page within total items.
+func pageBounds(total, page, pageSize int) (start, end int) {
+	start = min((page-1)*pageSize, total)
+	end = min(start+pageSize, total)
+	return start, end
+}
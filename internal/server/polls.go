@@ -0,0 +1,367 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hippias-fiber/internal/models"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxPollOptions = 10
+
+// pollHub tracks the live poll connections of each discussion so that votes
+// can be broadcast to everyone in the room.
+type pollHub struct {
+	mu    sync.Mutex
+	rooms map[int]*pollRoom
+}
+
+type pollRoom struct {
+	clients map[*pollClient]bool
+	// Held from reading a poll to broadcasting it, so concurrent votes
+	// cannot broadcast a stale tally after a newer one
+	changes sync.Mutex
+}
+
+type pollClient struct {
+	conn *websocket.Conn
+	// Guards writes; the connection allows only one writer at a time
+	mu sync.Mutex
+}
+
+func newPollHub() *pollHub {
+	return &pollHub{rooms: map[int]*pollRoom{}}
+}
+
+// join adds a client to a discussion's room and returns the room.
+func (h *pollHub) join(discussionID int, client *pollClient) *pollRoom {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	room := h.rooms[discussionID]
+	if room == nil {
+		room = &pollRoom{clients: map[*pollClient]bool{}}
+		h.rooms[discussionID] = room
+	}
+	room.clients[client] = true
+	return room
+}
+
+func (h *pollHub) leave(discussionID int, client *pollClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	room := h.rooms[discussionID]
+	if room == nil {
+		return
+	}
+	delete(room.clients, client)
+	if len(room.clients) == 0 {
+		delete(h.rooms, discussionID)
+	}
+}
+
+func (h *pollHub) broadcast(discussionID int, message models.PollMessage) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling poll message: %v", err)
+		return
+	}
+	h.mu.Lock()
+	var clients []*pollClient
+	if room := h.rooms[discussionID]; room != nil {
+		for client := range room.clients {
+			clients = append(clients, client)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, client := range clients {
+		// A failed write closes the connection, which ends its read loop
+		client.write(data)
+	}
+}
+
+func (client *pollClient) write(data []byte) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if err := client.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		log.Printf("Error writing poll message: %v", err)
+		client.conn.Close()
+	}
+}
+
+func (client *pollClient) send(message models.PollMessage) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling poll message: %v", err)
+		return
+	}
+	client.write(data)
+}
+
+// upgradeLivePolls checks that the caller belongs to the discussion's course
+// before switching to the WebSocket.
+func (s *Server) upgradeLivePolls(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return c.Status(fiber.StatusUpgradeRequired).JSON(ErrorResponse{Message: "Expected a WebSocket upgrade"})
+	}
+	acceptQueryToken(c)
+
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	user, facilitator, err := s.requireCourseMember(c, discussion.CourseID)
+	if err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	c.Locals("discussion", discussion)
+	c.Locals("user", user)
+	c.Locals("facilitator", facilitator)
+	return c.Next()
+}
+
+// livePolls serves a discussion's poll WebSocket. Facilitators create and
+// close polls; everyone in the course can vote, and each change is broadcast
+// to all connected clients.
+func (s *Server) livePolls(conn *websocket.Conn) {
+	discussion := conn.Locals("discussion").(*models.Discussion)
+	user := conn.Locals("user").(*models.User)
+	facilitator := conn.Locals("facilitator").(bool)
+
+	client := &pollClient{conn: conn}
+	room := s.polls.join(discussion.ID, client)
+	defer s.polls.leave(discussion.ID, client)
+
+	polls, err := s.discussionPolls(discussion.ID)
+	if err != nil {
+		log.Printf("Error querying polls: %v", err)
+		client.send(models.PollMessage{Type: models.PollMessageError, Message: err.Error()})
+		return
+	}
+	client.send(models.PollMessage{Type: models.PollMessageState, Polls: polls})
+
+	for {
+		var message models.PollMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("Error reading poll message: %v", err)
+			}
+			return
+		}
+
+		// Changes to the discussion's polls are applied and broadcast one at
+		// a time, so the last tally broadcast is the latest. The room outlives
+		// this handler, as it is only dropped once every client has left.
+		room.changes.Lock()
+		var reply models.PollMessage
+		var err error
+		switch message.Type {
+		case models.PollMessageCreate:
+			reply, err = s.createPoll(discussion, user, facilitator, message)
+		case models.PollMessageVote:
+			reply, err = s.votePoll(discussion, user, message)
+		case models.PollMessageClose:
+			reply, err = s.closePoll(discussion, facilitator, message)
+		default:
+			err = fmt.Errorf("unknown message type %q", message.Type)
+		}
+		if err == nil {
+			s.polls.broadcast(discussion.ID, reply)
+		}
+		room.changes.Unlock()
+		if err != nil {
+			client.send(models.PollMessage{Type: models.PollMessageError, PollID: message.PollID, Message: err.Error()})
+		}
+	}
+}
+
+func (s *Server) createPoll(discussion *models.Discussion, user *models.User, facilitator bool, message models.PollMessage) (models.PollMessage, error) {
+	if !facilitator {
+		return models.PollMessage{}, errForbidden
+	}
+	poll := models.Poll{
+		DiscussionID: discussion.ID,
+		Question:     strings.TrimSpace(message.Question),
+		Status:       models.PollOpen,
+		CreatedBy:    user.ID,
+		CreatedAt:    time.Now().UTC(),
+	}
+	for _, option := range message.Options {
+		if option = strings.TrimSpace(option); option != "" {
+			poll.Options = append(poll.Options, option)
+		}
+	}
+	if poll.Question == "" {
+		return models.PollMessage{}, fmt.Errorf("question is required")
+	}
+	if len(poll.Options) < 2 || len(poll.Options) > maxPollOptions {
+		return models.PollMessage{}, fmt.Errorf("a poll needs between 2 and %d options", maxPollOptions)
+	}
+	poll.Tallies = make([]int, len(poll.Options))
+
+	var created []models.Poll
+	if err := s.sb.DB.From("discussion_polls").Insert(poll).Execute(&created); err != nil {
+		log.Printf("Error inserting poll: %v", err)
+		return models.PollMessage{}, err
+	}
+	if len(created) > 0 {
+		poll = created[0]
+	}
+	return models.PollMessage{Type: models.PollMessageCreated, PollID: poll.ID, Poll: &poll}, nil
+}
+
+// votePoll records the user's answer, replacing any earlier one.
+func (s *Server) votePoll(discussion *models.Discussion, user *models.User, message models.PollMessage) (models.PollMessage, error) {
+	poll, err := s.getDiscussionPoll(discussion.ID, message.PollID)
+	if err != nil {
+		return models.PollMessage{}, err
+	}
+	if poll.Status != models.PollOpen {
+		return models.PollMessage{}, fmt.Errorf("the poll is closed")
+	}
+	if message.Option == nil || *message.Option < 0 || *message.Option >= len(poll.Options) {
+		return models.PollMessage{}, fmt.Errorf("option must be between 0 and %d", len(poll.Options)-1)
+	}
+
+	var existing []models.PollVote
+	err = s.sb.DB.From("poll_votes").Select("*").
+		Eq("poll_id", strconv.Itoa(poll.ID)).
+		Eq("user_id", strconv.Itoa(user.ID)).
+		Execute(&existing)
+	if err != nil {
+		log.Printf("Error querying poll votes: %v", err)
+		return models.PollMessage{}, err
+	}
+	vote := models.PollVote{PollID: poll.ID, UserID: user.ID, Option: *message.Option, UpdatedAt: time.Now().UTC()}
+	if len(existing) > 0 {
+		vote.ID = existing[0].ID
+		err = s.sb.DB.From("poll_votes").Update(vote).Eq("id", strconv.Itoa(vote.ID)).Execute(nil)
+	} else {
+		err = s.sb.DB.From("poll_votes").Insert(vote).Execute(nil)
+	}
+	if err != nil {
+		log.Printf("Error saving poll vote: %v", err)
+		return models.PollMessage{}, err
+	}
+
+	if err := s.tallyPoll(poll); err != nil {
+		log.Printf("Error tallying poll: %v", err)
+		return models.PollMessage{}, err
+	}
+	return models.PollMessage{Type: models.PollMessageUpdated, PollID: poll.ID, Poll: poll}, nil
+}
+
+// closePoll stops voting and stores the final tallies on the poll.
+func (s *Server) closePoll(discussion *models.Discussion, facilitator bool, message models.PollMessage) (models.PollMessage, error) {
+	if !facilitator {
+		return models.PollMessage{}, errForbidden
+	}
+	poll, err := s.getDiscussionPoll(discussion.ID, message.PollID)
+	if err != nil {
+		return models.PollMessage{}, err
+	}
+	if poll.Status == models.PollClosed {
+		return models.PollMessage{}, fmt.Errorf("the poll is already closed")
+	}
+	if err := s.tallyPoll(poll); err != nil {
+		log.Printf("Error tallying poll: %v", err)
+		return models.PollMessage{}, err
+	}
+	now := time.Now().UTC()
+	poll.Status = models.PollClosed
+	poll.ClosedAt = &now
+
+	update := map[string]interface{}{
+		"status":    poll.Status,
+		"tallies":   poll.Tallies,
+		"votes":     poll.Votes,
+		"closed_at": poll.ClosedAt,
+	}
+	if err := s.sb.DB.From("discussion_polls").Update(update).Eq("id", strconv.Itoa(poll.ID)).Execute(nil); err != nil {
+		log.Printf("Error closing poll: %v", err)
+		return models.PollMessage{}, err
+	}
+	return models.PollMessage{Type: models.PollMessageClosed, PollID: poll.ID, Poll: poll}, nil
+}
+
+var errPollNotFound = errors.New("poll not found")
+
+func (s *Server) getDiscussionPoll(discussionID, pollID int) (*models.Poll, error) {
+	var polls []models.Poll
+	err := s.sb.DB.From("discussion_polls").Select("*").Eq("id", strconv.Itoa(pollID)).Execute(&polls)
+	if err != nil {
+		log.Printf("Error querying poll: %v", err)
+		return nil, err
+	}
+	if len(polls) == 0 || polls[0].DiscussionID != discussionID {
+		return nil, errPollNotFound
+	}
+	return &polls[0], nil
+}
+
+// tallyPoll counts the votes cast on a poll.
+func (s *Server) tallyPoll(poll *models.Poll) error {
+	var votes []models.PollVote
+	err := s.sb.DB.From("poll_votes").Select("*").Eq("poll_id", strconv.Itoa(poll.ID)).Execute(&votes)
+	if err != nil {
+		return err
+	}
+	poll.Tallies = make([]int, len(poll.Options))
+	poll.Votes = 0
+	for _, vote := range votes {
+		if vote.Option >= 0 && vote.Option < len(poll.Tallies) {
+			poll.Tallies[vote.Option]++
+			poll.Votes++
+		}
+	}
+	return nil
+}
+
+// discussionPolls returns a discussion's polls in the order they were asked,
+// with live tallies for those still open.
+func (s *Server) discussionPolls(discussionID int) ([]models.Poll, error) {
+	polls := []models.Poll{}
+	err := s.sb.DB.From("discussion_polls").Select("*").Eq("discussion_id", strconv.Itoa(discussionID)).Execute(&polls)
+	if err != nil {
+		return nil, err
+	}
+	for i := range polls {
+		if polls[i].Status == models.PollOpen {
+			if err := s.tallyPoll(&polls[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	sort.Slice(polls, func(i, j int) bool { return polls[i].ID < polls[j].ID })
+	return polls, nil
+}
+
+// listDiscussionPolls returns the results of a discussion's polls.
+func (s *Server) listDiscussionPolls(c *fiber.Ctx) error {
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	if _, _, err := s.requireCourseMember(c, discussion.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	polls, err := s.discussionPolls(discussion.ID)
+	if err != nil {
+		log.Printf("Error querying polls: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	return c.JSON(polls)
+}
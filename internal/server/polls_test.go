@@ -0,0 +1,42 @@
+package server
+
+import "testing"
+
+func TestPollHubRooms(t *testing.T) {
+	tests := []struct {
+		name string
+		// Clients joining discussion 1, then the ones leaving it
+		join, leave int
+		wantRoom    bool
+	}{
+		{name: "room kept while anyone is connected", join: 3, leave: 2, wantRoom: true},
+		{name: "room dropped when the last client leaves", join: 2, leave: 2, wantRoom: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := newPollHub()
+			clients := make([]*pollClient, tt.join)
+			var room *pollRoom
+			for i := range clients {
+				clients[i] = &pollClient{}
+				joined := hub.join(1, clients[i])
+				if room != nil && joined != room {
+					t.Fatalf("client %d joined a different room", i)
+				}
+				room = joined
+			}
+			if other := hub.join(2, &pollClient{}); other == room {
+				t.Fatalf("discussions share a room")
+			}
+			for _, client := range clients[:tt.leave] {
+				hub.leave(1, client)
+			}
+			if got := hub.rooms[1] != nil; got != tt.wantRoom {
+				t.Errorf("room present = %v, want %v", got, tt.wantRoom)
+			}
+			if tt.wantRoom && len(hub.rooms[1].clients) != tt.join-tt.leave {
+				t.Errorf("room has %d clients, want %d", len(hub.rooms[1].clients), tt.join-tt.leave)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"fmt"
+	"hippias-fiber/internal/models"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// getReadingProgress returns the user's own progress on a reading.
+func (s *Server) getReadingProgress(c *fiber.Ctx) error {
+	reading, user, _, err := s.readingAccess(c, c.Params("id"))
+	if err != nil {
+		return readingErrorResponse(c, err)
+	}
+
+	progress, err := s.findReadingProgress(reading.ID, user.ID)
+	if err != nil {
+		log.Printf("Error querying reading progress: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if progress == nil {
+		progress = &models.ReadingProgress{ReadingID: reading.ID, UserID: user.ID, Status: models.ProgressNotStarted}
+	}
+	return c.JSON(progress)
+}
+
+func (s *Server) findReadingProgress(readingID, userID int) (*models.ReadingProgress, error) {
+	var rows []models.ReadingProgress
+	err := s.sb.DB.From("reading_progress").Select("*").
+		Eq("reading_id", strconv.Itoa(readingID)).
+		Eq("user_id", strconv.Itoa(userID)).
+		Execute(&rows)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return &rows[0], nil
+}
+
+// setReadingProgress records the user's progress on a reading. The status can
+// be left out when percent is given: 0 is not started, 100 finished.
+func (s *Server) setReadingProgress(c *fiber.Ctx) error {
+	reading, user, _, err := s.readingAccess(c, c.Params("id"))
+	if err != nil {
+		return readingErrorResponse(c, err)
+	}
+
+	var progress models.ReadingProgress
+	if err := c.BodyParser(&progress); err != nil {
+		log.Printf("Error parsing reading progress: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if err := validateReadingProgress(&progress, reading); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	progress.ReadingID = reading.ID
+	progress.UserID = user.ID
+	progress.UpdatedAt = time.Now().UTC()
+
+	existing, err := s.findReadingProgress(reading.ID, user.ID)
+	if err != nil {
+		log.Printf("Error querying reading progress: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	var saved []models.ReadingProgress
+	if existing != nil {
+		progress.ID = existing.ID
+		err = s.sb.DB.From("reading_progress").Update(progress).Eq("id", strconv.Itoa(existing.ID)).Execute(&saved)
+	} else {
+		progress.ID = 0
+		err = s.sb.DB.From("reading_progress").Insert(progress).Execute(&saved)
+	}
+	if err != nil {
+		log.Printf("Error saving reading progress: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(saved) > 0 {
+		progress = saved[0]
+	}
+	return c.JSON(progress)
+}
+
+func validateReadingProgress(progress *models.ReadingProgress, reading *models.Reading) error {
+	if progress.Percent != nil && (*progress.Percent < 0 || *progress.Percent > 100) {
+		return fmt.Errorf("percent must be between 0 and 100")
+	}
+	if progress.Page != nil {
+		if *progress.Page < 1 {
+			return fmt.Errorf("page must be positive")
+		}
+		if reading.PageStart > 0 && reading.PageEnd >= reading.PageStart &&
+			(*progress.Page < reading.PageStart || *progress.Page > reading.PageEnd) {
+			return fmt.Errorf("page must be within the reading's pages %d-%d", reading.PageStart, reading.PageEnd)
+		}
+	}
+
+	if progress.Status == "" {
+		switch {
+		case progress.Percent != nil && *progress.Percent == 100:
+			progress.Status = models.ProgressFinished
+		case progress.Percent != nil && *progress.Percent == 0 && progress.Page == nil:
+			progress.Status = models.ProgressNotStarted
+		case progress.Percent != nil || progress.Page != nil:
+			progress.Status = models.ProgressInProgress
+		default:
+			return fmt.Errorf("status, percent or page is required")
+		}
+	}
+	switch progress.Status {
+	case models.ProgressNotStarted:
+		progress.Percent, progress.Page = nil, nil
+	case models.ProgressInProgress:
+	case models.ProgressFinished:
+		full := 100
+		progress.Percent = &full
+	default:
+		return fmt.Errorf("status must be %s, %s or %s",
+			models.ProgressNotStarted, models.ProgressInProgress, models.ProgressFinished)
+	}
+	return nil
+}
+
+// getDiscussionProgress shows the facilitator how far participants have got
+// with the discussion's readings.
+func (s *Server) getDiscussionProgress(c *fiber.Ctx) error {
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	if _, err := s.requireFacilitator(c, discussion.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var readings []models.Reading
+	err = s.sb.DB.From("readings").Select("*").Eq("discussion_id", strconv.Itoa(discussion.ID)).Execute(&readings)
+	if err != nil {
+		log.Printf("Error querying readings: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	participants, err := s.courseParticipantIDs(discussion.CourseID)
+	if err != nil {
+		log.Printf("Error querying course participants: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	progress, err := s.readingProgressFor(readings)
+	if err != nil {
+		log.Printf("Error querying reading progress: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	return c.JSON(discussionCompletion(discussion.ID, readings, participants, progress))
+}
+
+func (s *Server) courseParticipantIDs(courseID int) ([]int, error) {
+	var participants []models.CourseParticipant
+	err := s.sb.DB.From("course_participants").Select("*").Eq("course_id", strconv.Itoa(courseID)).Execute(&participants)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(participants))
+	for i, participant := range participants {
+		ids[i] = participant.UserID
+	}
+	return ids, nil
+}
+
+// readingProgressFor returns every participant's progress on the readings.
+func (s *Server) readingProgressFor(readings []models.Reading) ([]models.ReadingProgress, error) {
+	progress := []models.ReadingProgress{}
+	if len(readings) == 0 {
+		return progress, nil
+	}
+	ids := make([]string, len(readings))
+	for i, reading := range readings {
+		ids[i] = strconv.Itoa(reading.ID)
+	}
+	err := s.sb.DB.From("reading_progress").Select("*").In("reading_id", ids).Execute(&progress)
+	return progress, err
+}
+
+// discussionCompletion summarizes progress for the given participants; progress
+// recorded by anyone else, such as the facilitator, is ignored.
+func discussionCompletion(discussionID int, readings []models.Reading, participantIDs []int, progress []models.ReadingProgress) models.DiscussionCompletion {
+	enrolled := map[int]bool{}
+	for _, id := range participantIDs {
+		enrolled[id] = true
+	}
+	byReading := map[int]map[int]models.ReadingProgress{}
+	for _, row := range progress {
+		if !enrolled[row.UserID] {
+			continue
+		}
+		if byReading[row.ReadingID] == nil {
+			byReading[row.ReadingID] = map[int]models.ReadingProgress{}
+		}
+		byReading[row.ReadingID][row.UserID] = row
+	}
+
+	completion := models.DiscussionCompletion{
+		DiscussionID: discussionID,
+		Participants: len(enrolled),
+		Readings:     []models.ReadingCompletion{},
+	}
+	finishedAll := map[int]bool{}
+	for id := range enrolled {
+		finishedAll[id] = len(readings) > 0
+	}
+	for _, reading := range readings {
+		summary := models.ReadingCompletion{ReadingID: reading.ID, Title: reading.Title, Participants: len(enrolled)}
+		totalPercent := 0
+		for id := range enrolled {
+			row, ok := byReading[reading.ID][id]
+			switch {
+			case ok && row.Status == models.ProgressFinished:
+				summary.Finished++
+				totalPercent += 100
+				continue
+			case ok && row.Status == models.ProgressInProgress:
+				summary.InProgress++
+				if row.Percent != nil {
+					totalPercent += *row.Percent
+				}
+			default:
+				summary.NotStarted++
+			}
+			finishedAll[id] = false
+		}
+		if summary.Participants > 0 {
+			summary.FinishedPercent = percent(summary.Finished, summary.Participants)
+			summary.AveragePercent = percent(totalPercent, summary.Participants*100)
+		}
+		completion.Readings = append(completion.Readings, summary)
+	}
+	for _, done := range finishedAll {
+		if done {
+			completion.FinishedAll++
+		}
+	}
+	if completion.Participants > 0 {
+		completion.FinishedAllPercent = percent(completion.FinishedAll, completion.Participants)
+	}
+	return completion
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"hippias-fiber/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestDiscussionCompletion(t *testing.T) {
+	half := 50
+	readings := []models.Reading{{ID: 10, Title: "Apology"}, {ID: 11, Title: "Crito"}}
+
+	tests := []struct {
+		name           string
+		readings       []models.Reading
+		participantIDs []int
+		progress       []models.ReadingProgress
+		want           models.DiscussionCompletion
+	}{
+		{
+			name:           "no readings",
+			participantIDs: []int{1, 2},
+			want:           models.DiscussionCompletion{DiscussionID: 7, Participants: 2, Readings: []models.ReadingCompletion{}},
+		},
+		{
+			name:           "progress of those not enrolled is ignored",
+			readings:       readings[:1],
+			participantIDs: nil,
+			progress:       []models.ReadingProgress{{ReadingID: 10, UserID: 99, Status: models.ProgressFinished}},
+			want: models.DiscussionCompletion{
+				DiscussionID: 7,
+				Readings:     []models.ReadingCompletion{{ReadingID: 10, Title: "Apology"}},
+			},
+		},
+		{
+			name:           "mixed progress",
+			readings:       readings,
+			participantIDs: []int{1, 2, 3},
+			progress: []models.ReadingProgress{
+				{ReadingID: 10, UserID: 1, Status: models.ProgressFinished},
+				{ReadingID: 11, UserID: 1, Status: models.ProgressFinished},
+				{ReadingID: 10, UserID: 2, Status: models.ProgressInProgress, Percent: &half},
+				{ReadingID: 11, UserID: 2, Status: models.ProgressFinished},
+				{ReadingID: 10, UserID: 3, Status: models.ProgressNotStarted},
+				{ReadingID: 10, UserID: 99, Status: models.ProgressFinished},
+			},
+			want: models.DiscussionCompletion{
+				DiscussionID:       7,
+				Participants:       3,
+				FinishedAll:        1,
+				FinishedAllPercent: 33.3,
+				Readings: []models.ReadingCompletion{
+					{ReadingID: 10, Title: "Apology", Participants: 3, NotStarted: 1, InProgress: 1, Finished: 1, FinishedPercent: 33.3, AveragePercent: 50},
+					{ReadingID: 11, Title: "Crito", Participants: 3, NotStarted: 1, Finished: 2, FinishedPercent: 66.7, AveragePercent: 66.7},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := discussionCompletion(7, tt.readings, tt.participantIDs, tt.progress)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("discussionCompletion() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
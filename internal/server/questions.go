@@ -0,0 +1,337 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"hippias-fiber/internal/models"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// questionStatusOrder is the agenda order of question statuses.
+var questionStatusOrder = map[string]int{
+	models.QuestionSelected: 0,
+	models.QuestionOpen:     1,
+	models.QuestionAnswered: 2,
+}
+
+// listQuestions returns a discussion's questions in agenda order to course members.
+func (s *Server) listQuestions(c *fiber.Ctx) error {
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	user, _, err := s.requireCourseMember(c, discussion.CourseID)
+	if err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	questions, err := s.rankedQuestions(discussion.ID, user.ID)
+	if err != nil {
+		log.Printf("Error querying questions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	return c.JSON(questions)
+}
+
+// rankedQuestions loads a discussion's questions with vote counts, in agenda
+// order. viewerID marks the questions that user voted for; pass 0 for none.
+func (s *Server) rankedQuestions(discussionID, viewerID int) ([]models.QuestionDto, error) {
+	var questions []models.Question
+	err := s.sb.DB.From("discussion_questions").Select("*").Eq("discussion_id", strconv.Itoa(discussionID)).Execute(&questions)
+	if err != nil {
+		return nil, err
+	}
+	dtos := make([]models.QuestionDto, 0, len(questions))
+	if len(questions) == 0 {
+		return dtos, nil
+	}
+
+	var questionIDs []string
+	authorIDs := map[int]bool{}
+	for _, question := range questions {
+		questionIDs = append(questionIDs, strconv.Itoa(question.ID))
+		authorIDs[question.AuthorID] = true
+	}
+	var votes []models.QuestionVote
+	if err := s.sb.DB.From("question_votes").Select("*").In("question_id", questionIDs).Execute(&votes); err != nil {
+		return nil, err
+	}
+	upvotes := map[int]int{}
+	voted := map[int]bool{}
+	for _, vote := range votes {
+		upvotes[vote.QuestionID]++
+		if vote.UserID == viewerID {
+			voted[vote.QuestionID] = true
+		}
+	}
+	names, err := s.userNames(authorIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, question := range questions {
+		dtos = append(dtos, models.QuestionDto{
+			Question:   question,
+			AuthorName: names[question.AuthorID],
+			Upvotes:    upvotes[question.ID],
+			Voted:      voted[question.ID],
+		})
+	}
+	sort.SliceStable(dtos, func(i, j int) bool {
+		a, b := dtos[i], dtos[j]
+		if questionStatusOrder[a.Status] != questionStatusOrder[b.Status] {
+			return questionStatusOrder[a.Status] < questionStatusOrder[b.Status]
+		}
+		if a.Upvotes != b.Upvotes {
+			return a.Upvotes > b.Upvotes
+		}
+		return a.ID < b.ID
+	})
+	for i := range dtos {
+		dtos[i].Rank = i + 1
+	}
+	return dtos, nil
+}
+
+// createQuestion submits a question for a discussion. Any course member can
+// ask; reading_id must be one of the discussion's readings.
+func (s *Server) createQuestion(c *fiber.Ctx) error {
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	user, _, err := s.requireCourseMember(c, discussion.CourseID)
+	if err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var req models.QuestionRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("Error parsing question: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Body == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "body is required"})
+	}
+	if req.ReadingID != nil {
+		var readings []models.Reading
+		err := s.sb.DB.From("readings").Select("id").
+			Eq("id", strconv.Itoa(*req.ReadingID)).
+			Eq("discussion_id", strconv.Itoa(discussion.ID)).
+			Execute(&readings)
+		if err != nil {
+			log.Printf("Error querying reading: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+		if len(readings) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "reading_id is not a reading of this discussion"})
+		}
+	}
+
+	now := time.Now().UTC()
+	question := models.Question{
+		DiscussionID: discussion.ID,
+		ReadingID:    req.ReadingID,
+		AuthorID:     user.ID,
+		Body:         req.Body,
+		Status:       models.QuestionOpen,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	var created []models.Question
+	if err := s.sb.DB.From("discussion_questions").Insert(question).Execute(&created); err != nil {
+		log.Printf("Error inserting question: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(created) > 0 {
+		question = created[0]
+	}
+
+	log.Printf("Created question: %+v", question)
+	return c.Status(fiber.StatusCreated).JSON(models.QuestionDto{Question: question, AuthorName: user.Name})
+}
+
+var errQuestionNotFound = errors.New("question not found")
+
+// questionAccess loads a question and checks the user belongs to its course,
+// returning whether they facilitate it.
+func (s *Server) questionAccess(c *fiber.Ctx) (*models.Question, *models.User, bool, error) {
+	var questions []models.Question
+	if err := s.sb.DB.From("discussion_questions").Select("*").Eq("id", c.Params("id")).Execute(&questions); err != nil {
+		return nil, nil, false, err
+	}
+	if len(questions) == 0 {
+		return nil, nil, false, errQuestionNotFound
+	}
+	discussion, err := s.getDiscussionByID(strconv.Itoa(questions[0].DiscussionID))
+	if err != nil {
+		return nil, nil, false, err
+	}
+	user, facilitator, err := s.requireCourseMember(c, discussion.CourseID)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return &questions[0], user, facilitator, nil
+}
+
+func questionErrorResponse(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errQuestionNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Question not found"})
+	}
+	return authErrorResponse(c, err)
+}
+
+// updateQuestion lets the author reword a question that is still open.
+func (s *Server) updateQuestion(c *fiber.Ctx) error {
+	question, user, _, err := s.questionAccess(c)
+	if err != nil {
+		return questionErrorResponse(c, err)
+	}
+	if question.AuthorID != user.ID {
+		return authErrorResponse(c, errForbidden)
+	}
+	if question.Status != models.QuestionOpen {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{Message: "Only open questions can be edited"})
+	}
+
+	var req models.QuestionRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("Error parsing question: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Body == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "body is required"})
+	}
+
+	return s.saveQuestion(c, question, map[string]interface{}{"body": req.Body})
+}
+
+// setQuestionStatus lets the facilitator select a question for the agenda,
+// mark it answered, or reopen it.
+func (s *Server) setQuestionStatus(c *fiber.Ctx) error {
+	question, _, facilitator, err := s.questionAccess(c)
+	if err != nil {
+		return questionErrorResponse(c, err)
+	}
+	if !facilitator {
+		return authErrorResponse(c, errForbidden)
+	}
+
+	var req models.QuestionStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("Error parsing question status: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if _, ok := questionStatusOrder[req.Status]; !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Message: fmt.Sprintf("status must be %s, %s or %s", models.QuestionOpen, models.QuestionSelected, models.QuestionAnswered),
+		})
+	}
+
+	return s.saveQuestion(c, question, map[string]interface{}{"status": req.Status})
+}
+
+func (s *Server) saveQuestion(c *fiber.Ctx, question *models.Question, changes map[string]interface{}) error {
+	changes["updated_at"] = time.Now().UTC()
+	var updated []models.Question
+	err := s.sb.DB.From("discussion_questions").Update(changes).Eq("id", strconv.Itoa(question.ID)).Execute(&updated)
+	if err != nil {
+		log.Printf("Error updating question: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(updated) > 0 {
+		question = &updated[0]
+	}
+	return c.JSON(question)
+}
+
+// deleteQuestion removes a question and its votes. Authors can delete their
+// own questions and the facilitator any question.
+func (s *Server) deleteQuestion(c *fiber.Ctx) error {
+	question, user, facilitator, err := s.questionAccess(c)
+	if err != nil {
+		return questionErrorResponse(c, err)
+	}
+	if question.AuthorID != user.ID && !facilitator {
+		return authErrorResponse(c, errForbidden)
+	}
+
+	questionID := strconv.Itoa(question.ID)
+	var votes []models.QuestionVote
+	err = s.sb.DB.From("question_votes").Delete().Eq("question_id", questionID).Execute(&votes)
+	if err == nil {
+		var deleted []models.Question
+		err = s.sb.DB.From("discussion_questions").Delete().Eq("id", questionID).Execute(&deleted)
+	}
+	if err != nil {
+		log.Printf("Error deleting question: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	log.Printf("Deleted question with ID: %s", questionID)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// upvoteQuestion records the user's vote; voting twice has no further effect
+// and authors cannot vote for their own questions.
+func (s *Server) upvoteQuestion(c *fiber.Ctx) error {
+	question, user, _, err := s.questionAccess(c)
+	if err != nil {
+		return questionErrorResponse(c, err)
+	}
+	if question.AuthorID == user.ID {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "You cannot upvote your own question"})
+	}
+
+	votes, err := s.userQuestionVotes(question.ID, user.ID)
+	if err != nil {
+		log.Printf("Error querying question votes: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(votes) == 0 {
+		vote := models.QuestionVote{QuestionID: question.ID, UserID: user.ID, CreatedAt: time.Now().UTC()}
+		var created []models.QuestionVote
+		if err := s.sb.DB.From("question_votes").Insert(vote).Execute(&created); err != nil {
+			log.Printf("Error inserting question vote: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (s *Server) removeQuestionVote(c *fiber.Ctx) error {
+	question, user, _, err := s.questionAccess(c)
+	if err != nil {
+		return questionErrorResponse(c, err)
+	}
+
+	var deleted []models.QuestionVote
+	err = s.sb.DB.From("question_votes").Delete().
+		Eq("question_id", strconv.Itoa(question.ID)).
+		Eq("user_id", strconv.Itoa(user.ID)).
+		Execute(&deleted)
+	if err != nil {
+		log.Printf("Error deleting question vote: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (s *Server) userQuestionVotes(questionID, userID int) ([]models.QuestionVote, error) {
+	var votes []models.QuestionVote
+	err := s.sb.DB.From("question_votes").Select("id").
+		Eq("question_id", strconv.Itoa(questionID)).
+		Eq("user_id", strconv.Itoa(userID)).
+		Execute(&votes)
+	return votes, err
+}
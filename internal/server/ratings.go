@@ -0,0 +1,197 @@
+package server
+
+import (
+	"fmt"
+	"hippias-fiber/internal/models"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (s *Server) getReadingRatingByID(ratingID string) (*models.ReadingRating, error) {
+	var rating models.ReadingRating
+	err := s.sb.DB.From("reading_ratings").Select("*").Single().Eq("id", ratingID).Execute(&rating)
+	if err != nil {
+		return nil, err
+	}
+	return &rating, nil
+}
+
+func validateReadingRating(rating *models.ReadingRating) error {
+	if rating.ReadingID == 0 {
+		return fmt.Errorf("reading_id is required")
+	}
+	if rating.Rating < 1 || rating.Rating > models.RatingScaleMax {
+		return fmt.Errorf("rating must be between 1 and %d", models.RatingScaleMax)
+	}
+	dimensions := []struct {
+		name  string
+		score *int
+	}{
+		{"difficulty", rating.Difficulty},
+		{"relevance", rating.Relevance},
+		{"enjoyment", rating.Enjoyment},
+	}
+	for _, dimension := range dimensions {
+		if dimension.score != nil && (*dimension.score < 1 || *dimension.score > models.RatingScaleMax) {
+			return fmt.Errorf("%s must be between 1 and %d", dimension.name, models.RatingScaleMax)
+		}
+	}
+	rating.Review = strings.TrimSpace(rating.Review)
+	return nil
+}
+
+// redactRatings hides who gave anonymous ratings from everyone but the rater
+// and the course facilitator.
+func redactRatings(ratings []models.ReadingRating, viewer *models.User, facilitator bool) {
+	if facilitator {
+		return
+	}
+	for i := range ratings {
+		if ratings[i].Anonymous && ratings[i].UserID != viewer.ID {
+			ratings[i].UserID = 0
+		}
+	}
+}
+
+func (s *Server) ratingsForReadings(readings []models.Reading) ([]models.ReadingRating, error) {
+	ratings := []models.ReadingRating{}
+	if len(readings) == 0 {
+		return ratings, nil
+	}
+	ids := make([]string, len(readings))
+	for i, reading := range readings {
+		ids[i] = strconv.Itoa(reading.ID)
+	}
+	err := s.sb.DB.From("reading_ratings").Select("*").In("reading_id", ids).Execute(&ratings)
+	return ratings, err
+}
+
+// getReadingRatingStats summarizes a reading's ratings for course members.
+func (s *Server) getReadingRatingStats(c *fiber.Ctx) error {
+	reading, _, _, err := s.readingAccess(c, c.Params("id"))
+	if err != nil {
+		return readingErrorResponse(c, err)
+	}
+
+	ratings, err := s.ratingsForReadings([]models.Reading{*reading})
+	if err != nil {
+		log.Printf("Error querying reading ratings: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	return c.JSON(models.ReadingRatingStats{
+		ReadingID:   reading.ID,
+		Title:       reading.Title,
+		RatingStats: ratingStats(ratings),
+	})
+}
+
+// getDiscussionRatingStats summarizes the ratings of all of a discussion's
+// readings, together and per reading.
+func (s *Server) getDiscussionRatingStats(c *fiber.Ctx) error {
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	if _, _, err := s.requireCourseMember(c, discussion.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var readings []models.Reading
+	err = s.sb.DB.From("readings").Select("*").Eq("discussion_id", strconv.Itoa(discussion.ID)).Execute(&readings)
+	if err != nil {
+		log.Printf("Error querying readings: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	ratings, err := s.ratingsForReadings(readings)
+	if err != nil {
+		log.Printf("Error querying reading ratings: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	byReading := map[int][]models.ReadingRating{}
+	for _, rating := range ratings {
+		byReading[rating.ReadingID] = append(byReading[rating.ReadingID], rating)
+	}
+	stats := models.DiscussionRatingStats{
+		DiscussionID: discussion.ID,
+		Overall:      ratingStats(ratings),
+		Readings:     []models.ReadingRatingStats{},
+	}
+	for _, reading := range readings {
+		stats.Readings = append(stats.Readings, models.ReadingRatingStats{
+			ReadingID:   reading.ID,
+			Title:       reading.Title,
+			RatingStats: ratingStats(byReading[reading.ID]),
+		})
+	}
+	return c.JSON(stats)
+}
+
+func ratingStats(ratings []models.ReadingRating) models.RatingStats {
+	var overall, difficulty, relevance, enjoyment []int
+	reviews := 0
+	for _, rating := range ratings {
+		overall = append(overall, rating.Rating)
+		if rating.Difficulty != nil {
+			difficulty = append(difficulty, *rating.Difficulty)
+		}
+		if rating.Relevance != nil {
+			relevance = append(relevance, *rating.Relevance)
+		}
+		if rating.Enjoyment != nil {
+			enjoyment = append(enjoyment, *rating.Enjoyment)
+		}
+		if rating.Review != "" {
+			reviews++
+		}
+	}
+	return models.RatingStats{
+		Count:      len(ratings),
+		Overall:    dimensionStats(overall),
+		Difficulty: dimensionStats(difficulty),
+		Relevance:  dimensionStats(relevance),
+		Enjoyment:  dimensionStats(enjoyment),
+		Reviews:    reviews,
+	}
+}
+
+func dimensionStats(scores []int) models.DimensionStats {
+	stats := models.DimensionStats{Count: len(scores), Histogram: make([]models.RatingBucket, models.RatingScaleMax)}
+	for i := range stats.Histogram {
+		stats.Histogram[i].Score = i + 1
+	}
+	if len(scores) == 0 {
+		return stats
+	}
+
+	sorted := append([]int(nil), scores...)
+	sort.Ints(sorted)
+	total := 0
+	for _, score := range sorted {
+		total += score
+		if score >= 1 && score <= models.RatingScaleMax {
+			stats.Histogram[score-1].Count++
+		}
+	}
+	mean := roundTo(float64(total)/float64(len(sorted)), 2)
+	middle := len(sorted) / 2
+	median := float64(sorted[middle])
+	if len(sorted)%2 == 0 {
+		median = float64(sorted[middle-1]+sorted[middle]) / 2
+	}
+	stats.Mean = &mean
+	stats.Median = &median
+	return stats
+}
+
+func roundTo(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
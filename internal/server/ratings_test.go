@@ -0,0 +1,92 @@
+package server
+
+import (
+	"hippias-fiber/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestDimensionStats(t *testing.T) {
+	histogram := func(counts ...int) []models.RatingBucket {
+		buckets := make([]models.RatingBucket, models.RatingScaleMax)
+		for i := range buckets {
+			buckets[i].Score = i + 1
+			if i < len(counts) {
+				buckets[i].Count = counts[i]
+			}
+		}
+		return buckets
+	}
+	value := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name   string
+		scores []int
+		want   models.DimensionStats
+	}{
+		{
+			name: "no scores",
+			want: models.DimensionStats{Histogram: histogram()},
+		},
+		{
+			name:   "odd count",
+			scores: []int{5, 1, 4},
+			want:   models.DimensionStats{Count: 3, Mean: value(3.33), Median: value(4), Histogram: histogram(1, 0, 0, 1, 1)},
+		},
+		{
+			name:   "even count averages the middle scores",
+			scores: []int{2, 5, 3, 2},
+			want:   models.DimensionStats{Count: 4, Mean: value(3), Median: value(2.5), Histogram: histogram(0, 2, 1, 0, 1)},
+		},
+		{
+			name:   "out of range scores count but have no bucket",
+			scores: []int{0, 5},
+			want:   models.DimensionStats{Count: 2, Mean: value(2.5), Median: value(2.5), Histogram: histogram(0, 0, 0, 0, 1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scores := append([]int(nil), tt.scores...)
+			got := dimensionStats(tt.scores)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dimensionStats(%v) = %+v, want %+v", tt.scores, got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.scores, scores) {
+				t.Errorf("dimensionStats reordered its input to %v", tt.scores)
+			}
+		})
+	}
+}
+
+func TestRedactRatings(t *testing.T) {
+	ratings := func() []models.ReadingRating {
+		return []models.ReadingRating{
+			{ReadingID: 1, UserID: 10, Rating: 4},
+			{ReadingID: 1, UserID: 20, Rating: 2, Anonymous: true},
+			{ReadingID: 1, UserID: 30, Rating: 5, Anonymous: true},
+		}
+	}
+	tests := []struct {
+		name        string
+		viewer      int
+		facilitator bool
+		want        []int
+	}{
+		{"participant sees only their own anonymous rating", 20, false, []int{10, 20, 0}},
+		{"outsider to every rating", 40, false, []int{10, 0, 0}},
+		{"facilitator sees every rater", 40, true, []int{10, 20, 30}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := ratings()
+			redactRatings(rows, &models.User{ID: tt.viewer}, tt.facilitator)
+			var got []int
+			for _, row := range rows {
+				got = append(got, row.UserID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("user IDs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,400 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hippias-fiber/internal/bibliography"
+	"hippias-fiber/internal/importer"
+	"hippias-fiber/internal/models"
+	"hippias-fiber/internal/rpc"
+	"io"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// importReadings creates readings for a discussion from an uploaded BibTeX or
+// RIS file (multipart "file" or the raw body), adding books and authors to the
+// catalog where needed. ?format=bibtex|ris overrides detection.
+func (s *Server) importReadings(c *fiber.Ctx) error {
+	discussion, err := s.getDiscussionByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	if _, err := s.requireFacilitator(c, discussion.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	format := c.Query("format")
+	var input io.Reader = bytes.NewReader(c.Body())
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+		}
+		defer file.Close()
+		input = file
+		if format == "" {
+			switch strings.ToLower(filepath.Ext(fileHeader.Filename)) {
+			case ".bib":
+				format = bibliography.FormatBibTeX
+			case ".ris":
+				format = bibliography.FormatRIS
+			}
+		}
+	}
+
+	entries, err := bibliography.Parse(format, input)
+	if err != nil {
+		log.Printf("Error parsing bibliography: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	imp, err := s.newReadingImport(discussion.ID)
+	if err != nil {
+		log.Printf("Error loading catalog for import: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	for _, entry := range entries {
+		if err := imp.add(entry); err != nil {
+			log.Printf("Error importing %q: %v", entry.Title, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+	}
+	if err := imp.commit(); err != nil {
+		log.Printf("Error saving imported readings: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	for _, reading := range imp.result.Readings {
+		s.publishCourseEvent(discussion.CourseID, models.EventReadingAdded, reading)
+	}
+
+	log.Printf("Imported %d readings into discussion %d (%d duplicates, %d unmapped)",
+		len(imp.result.Readings), discussion.ID, len(imp.result.Duplicates), len(imp.result.Unmapped))
+	return c.Status(fiber.StatusCreated).JSON(imp.result)
+}
+
+// readingImport holds the catalog and the discussion's readings in memory so
+// that entries are matched against existing rows and earlier entries in the
+// same file. Nothing is written until commit.
+type readingImport struct {
+	s            *Server
+	discussionID int
+	authors      []models.Author
+	books        []models.Book
+	// Reading identity (see readingIdentity) to existing reading ID, 0 for
+	// entries seen earlier in the file
+	seen map[string]int
+	// Rows to create on commit; books and authors by key
+	newAuthors []importAuthor
+	newBooks   []importBook
+	readings   []importReading
+	queued     map[string]bool
+	result     models.ReadingImportResult
+}
+
+// importAuthor, importBook and importReading are the rows passed to the
+// import_readings function in sql/import_readings.sql. Rows created in the
+// same import are referred to by key.
+type importAuthor struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+type importBook struct {
+	Key       string `json:"key"`
+	Title     string `json:"title"`
+	Author    string `json:"author"`
+	AuthorID  int    `json:"author_id,omitempty"`
+	AuthorKey string `json:"author_key,omitempty"`
+}
+
+type importReading struct {
+	models.Reading
+	BookKey string `json:"book_key,omitempty"`
+}
+
+func (s *Server) newReadingImport(discussionID int) (*readingImport, error) {
+	imp := &readingImport{
+		s:            s,
+		discussionID: discussionID,
+		seen:         map[string]int{},
+		queued:       map[string]bool{},
+		result: models.ReadingImportResult{
+			Readings:       []models.Reading{},
+			CreatedBooks:   []models.Book{},
+			CreatedAuthors: []models.Author{},
+			Duplicates:     []models.ReadingImportIssue{},
+			Unmapped:       []models.ReadingImportIssue{},
+		},
+	}
+	if err := s.sb.DB.From("authors").Select("*").Execute(&imp.authors); err != nil {
+		return nil, err
+	}
+	if err := s.sb.DB.From("books").Select("*").Execute(&imp.books); err != nil {
+		return nil, err
+	}
+
+	var existing []models.Reading
+	err := s.sb.DB.From("readings").Select("*").Eq("discussion_id", strconv.Itoa(discussionID)).Execute(&existing)
+	if err != nil {
+		return nil, err
+	}
+	for _, reading := range existing {
+		imp.seen[readingIdentity(reading)] = reading.ID
+	}
+	return imp, nil
+}
+
+// errUnmappable marks entries that are reported rather than failing the import.
+type errUnmappable struct{ reason string }
+
+func (e errUnmappable) Error() string { return e.reason }
+
+func unmappable(format string, args ...interface{}) error {
+	return errUnmappable{reason: fmt.Sprintf(format, args...)}
+}
+
+// newBook is a book an entry refers to that is not in the catalog yet. It is
+// only created once the entry is known to become a reading.
+type newBook struct {
+	title  string
+	author string
+}
+
+// Stands in for the ID of a book that will be created, while validating
+const pendingBookID = -1
+
+// add queues one entry for import. Unmappable entries and duplicates are
+// recorded in the result; only catalog or database failures are returned.
+// Books and authors are queued only for entries that become readings.
+func (imp *readingImport) add(entry bibliography.Entry) error {
+	issue := models.ReadingImportIssue{Key: entry.Key, Title: entry.Title}
+
+	reading, pending, err := imp.reading(entry)
+	var skip errUnmappable
+	if errors.As(err, &skip) {
+		issue.Reason = skip.reason
+		imp.result.Unmapped = append(imp.result.Unmapped, issue)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	identity := readingIdentity(*reading)
+	if pending != nil {
+		// No reading can refer to a book that does not exist yet, so only
+		// repeats within the file can match
+		identity += "|" + importer.NormalizeTitle(pending.title) + "|" + importer.NormalizeName(pending.author)
+	}
+	if readingID, ok := imp.seen[identity]; ok {
+		issue.ReadingID = readingID
+		issue.Reason = "already a reading in this discussion"
+		if readingID == 0 {
+			issue.Reason = "repeats an earlier entry in the file"
+		}
+		imp.result.Duplicates = append(imp.result.Duplicates, issue)
+		return nil
+	}
+	imp.seen[identity] = 0
+
+	queued := importReading{Reading: *reading}
+	if pending != nil {
+		queued.BookID = pendingBookID
+	}
+	if err := imp.s.validateReading(&queued.Reading); err != nil {
+		issue.Reason = err.Error()
+		imp.result.Unmapped = append(imp.result.Unmapped, issue)
+		return nil
+	}
+	queued.BookID = reading.BookID
+	if pending != nil {
+		queued.BookKey = imp.queueBook(pending.title, pending.author)
+	}
+	imp.readings = append(imp.readings, queued)
+	return nil
+}
+
+// commit writes the queued authors, books and readings in one call to the
+// import_readings function, so a failure leaves none of them behind.
+func (imp *readingImport) commit() error {
+	if len(imp.readings) == 0 {
+		return nil
+	}
+	var created struct {
+		Authors  []models.Author  `json:"authors"`
+		Books    []models.Book    `json:"books"`
+		Readings []models.Reading `json:"readings"`
+	}
+	params := map[string]interface{}{
+		"discussion_id": imp.discussionID,
+		"authors":       imp.newAuthors,
+		"books":         imp.newBooks,
+		"readings":      imp.readings,
+	}
+	if err := rpc.Call(imp.s.sb, "import_readings", params, &created); err != nil {
+		return err
+	}
+	imp.result.CreatedAuthors = append(imp.result.CreatedAuthors, created.Authors...)
+	imp.result.CreatedBooks = append(imp.result.CreatedBooks, created.Books...)
+	imp.result.Readings = append(imp.result.Readings, created.Readings...)
+	return nil
+}
+
+// reading maps an entry onto a reading of the matching kind, finding the book
+// for book sections. A book not in the catalog is returned as newBook and left
+// for the caller to create.
+func (imp *readingImport) reading(entry bibliography.Entry) (*models.Reading, *newBook, error) {
+	title := strings.TrimSpace(entry.Title)
+	if title == "" {
+		return nil, nil, unmappable("entry has no title")
+	}
+	reading := &models.Reading{
+		DiscussionID: imp.discussionID,
+		Title:        title,
+		URL:          entry.URL,
+		Chapter:      entry.Chapter,
+		PageStart:    entry.PageStart,
+		PageEnd:      entry.PageEnd,
+	}
+
+	switch entry.Kind {
+	case bibliography.KindBook, bibliography.KindChapter:
+		bookTitle := title
+		if entry.Kind == bibliography.KindChapter {
+			bookTitle = strings.TrimSpace(entry.Container)
+			if bookTitle == "" {
+				return nil, nil, unmappable("chapter has no book title")
+			}
+		} else if entry.Chapter == "" && entry.PageStart == 0 {
+			return nil, nil, unmappable("a whole book needs a chapter or page range to be assigned as a reading")
+		}
+		author := ""
+		if len(entry.Authors) > 0 {
+			author = entry.Authors[0]
+		}
+		reading.Type = models.ReadingBookSection
+		book := imp.findBook(bookTitle, author)
+		if book == nil {
+			return reading, &newBook{title: bookTitle, author: author}, nil
+		}
+		reading.BookID = book.ID
+		var err error
+		reading.EditionID, err = imp.editionID(book.ID, entry.ISBN)
+		if err != nil {
+			return nil, nil, err
+		}
+	case bibliography.KindArticle:
+		reading.Type = models.ReadingArticle
+		reading.Source = entry.Container
+	case bibliography.KindVideo:
+		reading.Type = models.ReadingVideo
+		reading.VideoURL = entry.URL
+	case bibliography.KindAudio:
+		reading.Type = models.ReadingAudio
+	case bibliography.KindWebPage:
+		reading.Type = models.ReadingWebPage
+	default:
+		return nil, nil, unmappable("entry type is not supported")
+	}
+	return reading, nil, nil
+}
+
+// findBook finds a book by folded title and author.
+func (imp *readingImport) findBook(title, authorName string) *models.Book {
+	authorKey := importer.NormalizeName(authorName)
+	for i, book := range imp.books {
+		if importer.NormalizeTitle(book.Title) != importer.NormalizeTitle(title) {
+			continue
+		}
+		if authorKey == "" || importer.NormalizeName(book.Author) == authorKey {
+			return &imp.books[i]
+		}
+	}
+	return nil
+}
+
+// queueBook queues a book for creation, along with its author when they are
+// new, and returns the key readings refer to it by. Entries naming the same
+// new book share one.
+func (imp *readingImport) queueBook(title, authorName string) string {
+	authorKey := importer.NormalizeName(authorName)
+	key := importer.NormalizeTitle(title) + "|" + authorKey
+	if imp.queued["book|"+key] {
+		return key
+	}
+	imp.queued["book|"+key] = true
+
+	book := importBook{Key: key, Title: title}
+	if authorKey != "" {
+		book.Author = strings.TrimSpace(authorName)
+		if author := imp.findAuthor(authorKey); author != nil {
+			book.AuthorID = author.ID
+			book.Author = author.Name
+		} else {
+			book.AuthorKey = authorKey
+			if !imp.queued["author|"+authorKey] {
+				imp.queued["author|"+authorKey] = true
+				imp.newAuthors = append(imp.newAuthors, importAuthor{Key: authorKey, Name: book.Author})
+			}
+		}
+	}
+	imp.newBooks = append(imp.newBooks, book)
+	return key
+}
+
+func (imp *readingImport) findAuthor(key string) *models.Author {
+	for i, author := range imp.authors {
+		if importer.NormalizeName(author.Name) == key {
+			return &imp.authors[i]
+		}
+	}
+	return nil
+}
+
+// editionID returns the book's edition with the given ISBN, or 0 if there is
+// no such edition. Editions are not created by imports.
+func (imp *readingImport) editionID(bookID int, isbn string) (int, error) {
+	isbn = strings.ReplaceAll(strings.ReplaceAll(isbn, "-", ""), " ", "")
+	if isbn == "" || bookID == 0 {
+		return 0, nil
+	}
+	var editions []models.Edition
+	err := imp.s.sb.DB.From("editions").Select("*").Eq("book_id", strconv.Itoa(bookID)).Execute(&editions)
+	if err != nil {
+		return 0, err
+	}
+	for _, edition := range editions {
+		if strings.ReplaceAll(edition.ISBN, "-", "") == isbn {
+			return edition.ID, nil
+		}
+	}
+	return 0, nil
+}
+
+// readingIdentity is the key two readings of a discussion are considered
+// duplicates under: same kind, title and book, and the same pages or link.
+func readingIdentity(reading models.Reading) string {
+	kind := readingKind(&reading)
+	locator := reading.URL
+	switch kind {
+	case models.ReadingVideo:
+		if reading.VideoURL != "" {
+			locator = reading.VideoURL
+		}
+	case models.ReadingBookSection:
+		locator = reading.Chapter + "|" + strconv.Itoa(reading.PageStart) + "-" + strconv.Itoa(reading.PageEnd)
+	}
+	return strings.Join([]string{
+		kind,
+		importer.NormalizeTitle(reading.Title),
+		strconv.Itoa(reading.BookID),
+		locator,
+	}, "|")
+}
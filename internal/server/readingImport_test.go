@@ -0,0 +1,71 @@
+package server
+
+import (
+	"hippias-fiber/internal/bibliography"
+	"hippias-fiber/internal/models"
+	"testing"
+)
+
+func TestReadingImportQueue(t *testing.T) {
+	imp := &readingImport{
+		s:            &Server{},
+		discussionID: 4,
+		authors:      []models.Author{{ID: 5, Name: "Plato"}},
+		books:        []models.Book{{ID: 9, Title: "Republic", Author: "Plato", AuthorID: 5}},
+		seen:         map[string]int{},
+		queued:       map[string]bool{},
+	}
+	// Saved before types were normalized
+	existing := models.Reading{ID: 77, DiscussionID: 4, Type: "Book", Title: "Republic, book 1", BookID: 9, Chapter: "1"}
+	imp.seen[readingIdentity(existing)] = existing.ID
+
+	chapter := func(title, book, author string, start, end int) bibliography.Entry {
+		return bibliography.Entry{Kind: bibliography.KindChapter, Title: title, Container: book, Authors: []string{author}, PageStart: start, PageEnd: end}
+	}
+	entries := []bibliography.Entry{
+		chapter("Apology", "Five Dialogues", "Plato", 21, 44),
+		chapter("Apology", "Five Dialogues", "Plato", 21, 44),
+		chapter("Crito", "Five Dialogues", "Plato", 45, 56),
+		{Kind: bibliography.KindChapter, Title: "Republic, book 1", Container: "Republic", Authors: []string{"Plato"}, Chapter: "1"},
+		chapter("First Meditation", "Meditations", "René Descartes", 12, 15),
+		{Kind: bibliography.KindArticle},
+	}
+	for _, entry := range entries {
+		if err := imp.add(entry); err != nil {
+			t.Fatalf("add(%q): %v", entry.Title, err)
+		}
+	}
+
+	var titles []string
+	for _, reading := range imp.readings {
+		titles = append(titles, reading.Title)
+	}
+	if len(titles) != 3 || titles[0] != "Apology" || titles[1] != "Crito" || titles[2] != "First Meditation" {
+		t.Fatalf("queued readings %v, want Apology, Crito and First Meditation", titles)
+	}
+	if len(imp.newBooks) != 2 {
+		t.Fatalf("queued %d books, want 2: %+v", len(imp.newBooks), imp.newBooks)
+	}
+	if imp.readings[0].BookKey != imp.newBooks[0].Key || imp.readings[1].BookKey != imp.newBooks[0].Key {
+		t.Errorf("chapters of one new book refer to %q and %q, want %q", imp.readings[0].BookKey, imp.readings[1].BookKey, imp.newBooks[0].Key)
+	}
+	if imp.newBooks[0].AuthorID != 5 || imp.newBooks[0].AuthorKey != "" {
+		t.Errorf("book by an existing author = %+v, want author_id 5", imp.newBooks[0])
+	}
+	if len(imp.newAuthors) != 1 || imp.newBooks[1].AuthorKey != imp.newAuthors[0].Key || imp.newAuthors[0].Name != "René Descartes" {
+		t.Errorf("new author %+v for book %+v, want René Descartes referred to by key", imp.newAuthors, imp.newBooks[1])
+	}
+
+	if len(imp.result.Duplicates) != 2 {
+		t.Fatalf("duplicates = %+v, want 2", imp.result.Duplicates)
+	}
+	if imp.result.Duplicates[0].ReadingID != 0 {
+		t.Errorf("repeat within the file matched reading %d", imp.result.Duplicates[0].ReadingID)
+	}
+	if imp.result.Duplicates[1].ReadingID != existing.ID {
+		t.Errorf("legacy-typed reading matched %d, want %d", imp.result.Duplicates[1].ReadingID, existing.ID)
+	}
+	if len(imp.result.Unmapped) != 1 {
+		t.Errorf("unmapped = %+v, want the untitled entry", imp.result.Unmapped)
+	}
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"hippias-fiber/internal/models"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Rates used to estimate how long a reading takes. Philosophy is slow going, so
+// these are well below general reading speeds.
+const (
+	minutesPerPage = 2.5
+	wordsPerMinute = 180.0
+)
+
+// legacyReadingTypes maps the free-text types readings had before they were
+// given kinds onto the matching kind. Keys are normalized as in readingKind.
+var legacyReadingTypes = map[string]string{
+	"book":            models.ReadingBookSection,
+	"chapter":         models.ReadingBookSection,
+	"book_chapter":    models.ReadingBookSection,
+	"section":         models.ReadingBookSection,
+	"excerpt":         models.ReadingBookSection,
+	"paper":           models.ReadingArticle,
+	"essay":           models.ReadingArticle,
+	"journal":         models.ReadingArticle,
+	"journal_article": models.ReadingArticle,
+	"pdf":             models.ReadingArticle,
+	"lecture":         models.ReadingVideo,
+	"film":            models.ReadingVideo,
+	"youtube":         models.ReadingVideo,
+	"podcast":         models.ReadingAudio,
+	"recording":       models.ReadingAudio,
+	"web":             models.ReadingWebPage,
+	"webpage":         models.ReadingWebPage,
+	"website":         models.ReadingWebPage,
+	"link":            models.ReadingWebPage,
+	"blog":            models.ReadingWebPage,
+	"blog_post":       models.ReadingWebPage,
+	"online":          models.ReadingWebPage,
+}
+
+// readingKind normalizes a reading's type to one of the Reading* kinds,
+// mapping legacy free-text types. An empty type is inferred from the fields
+// that are set. Unknown types are returned as given.
+func readingKind(reading *models.Reading) string {
+	kind := strings.ToLower(strings.TrimSpace(reading.Type))
+	kind = strings.NewReplacer(" ", "_", "-", "_").Replace(kind)
+	if mapped, ok := legacyReadingTypes[kind]; ok {
+		return mapped
+	}
+	switch kind {
+	case models.ReadingBookSection, models.ReadingArticle, models.ReadingVideo, models.ReadingAudio, models.ReadingWebPage:
+		return kind
+	case "":
+		switch {
+		case reading.BookID != 0:
+			return models.ReadingBookSection
+		case reading.VideoURL != "":
+			return models.ReadingVideo
+		case reading.URL != "":
+			return models.ReadingWebPage
+		}
+	}
+	return strings.TrimSpace(reading.Type)
+}
+
+// validateReading checks the structured fields required by the reading's kind
+// and fills in EstimatedMinutes. Legacy free-text types are normalized first.
+func (s *Server) validateReading(reading *models.Reading) error {
+	reading.Type = readingKind(reading)
+	if reading.PageStart < 0 || reading.PageEnd < 0 || reading.WordCount < 0 ||
+		reading.StartSeconds < 0 || reading.EndSeconds < 0 || reading.DurationSeconds < 0 {
+		return fmt.Errorf("page numbers, word counts and timestamps must not be negative")
+	}
+	if reading.PageEnd != 0 && reading.PageEnd < reading.PageStart {
+		return fmt.Errorf("page_end must not be before page_start")
+	}
+	if reading.EndSeconds != 0 && reading.EndSeconds <= reading.StartSeconds {
+		return fmt.Errorf("end_seconds must be after start_seconds")
+	}
+	hasPages := reading.PageStart != 0 || reading.PageEnd != 0
+	hasTimestamps := reading.StartSeconds != 0 || reading.EndSeconds != 0 || reading.DurationSeconds != 0
+
+	switch reading.Type {
+	case models.ReadingBookSection:
+		if reading.BookID == 0 {
+			return fmt.Errorf("a book_section needs a book_id")
+		}
+		if reading.Chapter == "" && !hasPages {
+			return fmt.Errorf("a book_section needs a chapter or a page range")
+		}
+		if hasTimestamps {
+			return fmt.Errorf("a book_section cannot have timestamps")
+		}
+	case models.ReadingArticle:
+		if strings.TrimSpace(reading.Title) == "" {
+			return fmt.Errorf("an article needs a title")
+		}
+		if reading.Source == "" && reading.URL == "" {
+			return fmt.Errorf("an article needs a source or a url")
+		}
+		if hasTimestamps {
+			return fmt.Errorf("an article cannot have timestamps")
+		}
+	case models.ReadingVideo:
+		if reading.VideoURL == "" {
+			reading.VideoURL = reading.URL
+		}
+		if err := validateReadingURL("video_url", reading.VideoURL); err != nil {
+			return err
+		}
+		if hasPages {
+			return fmt.Errorf("a video cannot have a page range")
+		}
+	case models.ReadingAudio:
+		if err := validateReadingURL("url", reading.URL); err != nil {
+			return err
+		}
+		if hasPages {
+			return fmt.Errorf("audio cannot have a page range")
+		}
+	case models.ReadingWebPage:
+		if err := validateReadingURL("url", reading.URL); err != nil {
+			return err
+		}
+		if hasPages || hasTimestamps {
+			return fmt.Errorf("a web_page cannot have a page range or timestamps")
+		}
+	default:
+		return fmt.Errorf("type must be one of %s, %s, %s, %s or %s", models.ReadingBookSection,
+			models.ReadingArticle, models.ReadingVideo, models.ReadingAudio, models.ReadingWebPage)
+	}
+
+	if reading.URL != "" {
+		if err := validateReadingURL("url", reading.URL); err != nil {
+			return err
+		}
+	}
+	if err := s.validateReadingPages(reading); err != nil {
+		return err
+	}
+	reading.EstimatedMinutes = estimateReadingMinutes(reading)
+	return nil
+}
+
+func validateReadingURL(field, raw string) error {
+	if raw == "" {
+		return fmt.Errorf("%s is required for this type of reading", field)
+	}
+	u, err := url.ParseRequestURI(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s must be an http or https URL", field)
+	}
+	return nil
+}
+
+// estimateReadingMinutes estimates time to read or watch from whichever of the
+// page range, word count or recording length is known. It returns 0 when none is.
+func estimateReadingMinutes(reading *models.Reading) int {
+	var minutes float64
+	switch {
+	case reading.EndSeconds > 0:
+		minutes = float64(reading.EndSeconds-reading.StartSeconds) / 60
+	case reading.DurationSeconds > 0:
+		minutes = float64(reading.DurationSeconds-reading.StartSeconds) / 60
+	case reading.WordCount > 0:
+		minutes = float64(reading.WordCount) / wordsPerMinute
+	case reading.PageStart > 0 && reading.PageEnd >= reading.PageStart:
+		minutes = float64(reading.PageEnd-reading.PageStart+1) * minutesPerPage
+	case reading.PageStart > 0:
+		minutes = minutesPerPage
+	case reading.PageEnd > 0:
+		// Read from the start up to page_end
+		minutes = float64(reading.PageEnd) * minutesPerPage
+	}
+	if minutes <= 0 {
+		return 0
+	}
+	return int(math.Ceil(minutes))
+}
+
+func (s *Server) getReadingByID(readingID string) (*models.Reading, error) {
+	var readings []models.Reading
+	if err := s.sb.DB.From("readings").Select("*").Eq("id", readingID).Execute(&readings); err != nil {
+		return nil, err
+	}
+	if len(readings) == 0 {
+		return nil, errReadingNotFound
+	}
+	return &readings[0], nil
+}
+
+var errReadingNotFound = errors.New("reading not found")
+
+// readingAccess loads a reading and checks that the user belongs to the
+// course it is assigned in, returning whether they facilitate the course.
+func (s *Server) readingAccess(c *fiber.Ctx, readingID string) (*models.Reading, *models.User, bool, error) {
+	reading, err := s.getReadingByID(readingID)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	discussion, err := s.getDiscussionByID(strconv.Itoa(reading.DiscussionID))
+	if err != nil {
+		return nil, nil, false, err
+	}
+	user, facilitator, err := s.requireCourseMember(c, discussion.CourseID)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return reading, user, facilitator, nil
+}
+
+func readingErrorResponse(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errReadingNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Reading not found"})
+	}
+	return authErrorResponse(c, err)
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"hippias-fiber/internal/models"
+	"testing"
+)
+
+func TestReadingKind(t *testing.T) {
+	tests := []struct {
+		name    string
+		reading models.Reading
+		want    string
+	}{
+		{"kind", models.Reading{Type: "article"}, models.ReadingArticle},
+		{"legacy value", models.Reading{Type: "Book"}, models.ReadingBookSection},
+		{"legacy value with spaces", models.Reading{Type: " Blog post "}, models.ReadingWebPage},
+		{"legacy podcast", models.Reading{Type: "podcast"}, models.ReadingAudio},
+		{"kind in capitals", models.Reading{Type: "Web-Page"}, models.ReadingWebPage},
+		{"empty with book", models.Reading{BookID: 3}, models.ReadingBookSection},
+		{"empty with video", models.Reading{VideoURL: "https://example.com/v"}, models.ReadingVideo},
+		{"empty with url", models.Reading{URL: "https://example.com"}, models.ReadingWebPage},
+		{"empty with nothing", models.Reading{}, ""},
+		{"unknown", models.Reading{Type: "Poem"}, "Poem"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readingKind(&tt.reading); got != tt.want {
+				t.Errorf("readingKind() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateReadingMinutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		reading models.Reading
+		want    int
+	}{
+		{"nothing known", models.Reading{}, 0},
+		{"page range", models.Reading{PageStart: 10, PageEnd: 19}, 25},
+		{"single page", models.Reading{PageStart: 10}, 3},
+		{"only page_end", models.Reading{PageEnd: 4}, 10},
+		{"word count", models.Reading{WordCount: 900}, 5},
+		{"recording segment", models.Reading{StartSeconds: 60, EndSeconds: 181}, 3},
+		{"rest of recording", models.Reading{StartSeconds: 600, DurationSeconds: 1200}, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimateReadingMinutes(&tt.reading); got != tt.want {
+				t.Errorf("estimateReadingMinutes() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,419 @@
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"hippias-fiber/internal/models"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+const defaultLowTurnoutThreshold = 50.0
+
+// getCourseReport serves the participation report as JSON, CSV or XLSX depending
+// on ?format=.
+func (s *Server) getCourseReport(c *fiber.Ctx) error {
+	courseID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid course ID"})
+	}
+	if _, err := s.requireFacilitator(c, courseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	threshold := defaultLowTurnoutThreshold
+	if raw := c.Query("low_turnout"); raw != "" {
+		threshold, err = strconv.ParseFloat(raw, 64)
+		if err != nil || threshold < 0 || threshold > 100 {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "low_turnout must be a percentage between 0 and 100"})
+		}
+	}
+
+	report, err := s.buildCourseReport(courseID, threshold, time.Now())
+	if err != nil {
+		log.Printf("Error building course report: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	filename := fmt.Sprintf("course-%d-participation", courseID)
+	switch c.Query("format", "json") {
+	case "json":
+		return c.JSON(report)
+	case "csv":
+		data, err := courseReportCSV(report)
+		if err != nil {
+			log.Printf("Error writing course report CSV: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Attachment(filename + ".csv")
+		return c.Send(data)
+	case "xlsx":
+		data, err := courseReportXLSX(report)
+		if err != nil {
+			log.Printf("Error writing course report XLSX: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+		c.Set(fiber.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		c.Attachment(filename + ".xlsx")
+		return c.Send(data)
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "format must be json, csv or xlsx"})
+	}
+}
+
+// buildCourseReport assembles attendance and rating totals for every participant
+// of a course from discussion_attendance, reading_ratings and course_participants.
+func (s *Server) buildCourseReport(courseID int, threshold float64, now time.Time) (*models.CourseReport, error) {
+	course, err := s.getCourseByID(strconv.Itoa(courseID))
+	if err != nil {
+		return nil, err
+	}
+
+	var discussions []models.Discussion
+	err = s.sb.DB.From("discussions").
+		Select("*").
+		Eq("course_id", strconv.Itoa(courseID)).
+		Execute(&discussions)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(discussions, func(i, j int) bool {
+		return discussions[i].DateTime.Before(discussions[j].DateTime)
+	})
+
+	participants, err := s.listCourseParticipantDtos(courseID, "id,name,email")
+	if err != nil {
+		return nil, err
+	}
+
+	var discussionIDs []string
+	for _, discussion := range discussions {
+		discussionIDs = append(discussionIDs, strconv.Itoa(discussion.ID))
+	}
+
+	var attendance []models.DiscussionAttendance
+	var ratings []models.ReadingRating
+	if len(discussionIDs) > 0 {
+		err = s.sb.DB.From("discussion_attendance").
+			Select("*").
+			In("discussion_id", discussionIDs).
+			Execute(&attendance)
+		if err != nil {
+			return nil, err
+		}
+
+		var readings []models.Reading
+		err = s.sb.DB.From("readings").
+			Select("*").
+			In("discussion_id", discussionIDs).
+			Execute(&readings)
+		if err != nil {
+			return nil, err
+		}
+
+		var readingIDs []string
+		for _, reading := range readings {
+			readingIDs = append(readingIDs, strconv.Itoa(reading.ID))
+		}
+		if len(readingIDs) > 0 {
+			err = s.sb.DB.From("reading_ratings").
+				Select("*").
+				In("reading_id", readingIDs).
+				Execute(&ratings)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	loc, err := loadLocation(course.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	minutes, err := s.latestMinutesFor(discussionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	report := compileCourseReport(*course, discussions, participants, attendance, ratings, threshold, now)
+	report.Minutes = []models.DiscussionMinutesDto{}
+	for _, discussion := range discussions {
+		if m, ok := minutes[discussion.ID]; ok {
+			report.Minutes = append(report.Minutes, m)
+		}
+	}
+	for i := range report.Discussions {
+		report.Discussions[i].DateTime = report.Discussions[i].DateTime.In(loc)
+	}
+	for i := range report.LowTurnout {
+		report.LowTurnout[i].DateTime = report.LowTurnout[i].DateTime.In(loc)
+	}
+	return report, nil
+}
+
+// compileCourseReport does the counting for buildCourseReport. Excused absences
+// are left out of a participant's attendance percentage.
+func compileCourseReport(course models.Course, discussions []models.Discussion, participants []models.CourseParticipantDto, attendance []models.DiscussionAttendance, ratings []models.ReadingRating, threshold float64, now time.Time) *models.CourseReport {
+	type key struct{ discussionID, userID int }
+	statusByKey := map[key]string{}
+	for _, row := range attendance {
+		statusByKey[key{row.DiscussionID, row.UserID}] = attendanceStatus(row)
+	}
+	// Count readings rated rather than rating rows, so re-rating a reading
+	// doesn't inflate the total
+	type ratingKey struct{ readingID, userID int }
+	ratingsByUser := map[int]int{}
+	rated := map[ratingKey]bool{}
+	for _, rating := range ratings {
+		k := ratingKey{rating.ReadingID, rating.UserID}
+		if !rated[k] {
+			rated[k] = true
+			ratingsByUser[rating.UserID]++
+		}
+	}
+
+	report := &models.CourseReport{
+		Course:              course,
+		GeneratedAt:         now.UTC(),
+		LowTurnoutThreshold: threshold,
+		Discussions:         []models.DiscussionTurnout{},
+		Participants:        []models.ParticipantReport{},
+		LowTurnout:          []models.DiscussionTurnout{},
+	}
+
+	for _, discussion := range discussions {
+		turnout := models.DiscussionTurnout{
+			DiscussionID: discussion.ID,
+			Name:         discussion.Name,
+			DateTime:     discussion.DateTime,
+			Held:         discussion.DateTime.Before(now),
+		}
+		for _, participant := range participants {
+			switch statusByKey[key{discussion.ID, participant.UserID}] {
+			case models.AttendancePresent:
+				turnout.Present++
+			case models.AttendanceExcused:
+				turnout.Excused++
+			}
+		}
+		if len(participants) > 0 {
+			turnout.TurnoutPercent = percent(turnout.Present, len(participants))
+		}
+		report.Discussions = append(report.Discussions, turnout)
+		if turnout.Held && turnout.TurnoutPercent < threshold {
+			report.LowTurnout = append(report.LowTurnout, turnout)
+		}
+	}
+
+	for _, participant := range participants {
+		row := models.ParticipantReport{
+			UserID:           participant.UserID,
+			Name:             participant.User.Name,
+			Email:            participant.User.Email,
+			Attendance:       make([]string, len(discussions)),
+			RatingsSubmitted: ratingsByUser[participant.UserID],
+		}
+		expected := 0
+		for i, discussion := range discussions {
+			status := statusByKey[key{discussion.ID, participant.UserID}]
+			row.Attendance[i] = status
+			if !report.Discussions[i].Held || status == models.AttendanceExcused {
+				continue
+			}
+			expected++
+			if status == models.AttendancePresent {
+				row.Attended++
+			}
+		}
+		if expected > 0 {
+			row.AttendancePercent = percent(row.Attended, expected)
+		}
+		report.Participants = append(report.Participants, row)
+	}
+
+	return report
+}
+
+// percent returns part/whole as a percentage rounded to one decimal place.
+func percent(part, whole int) float64 {
+	return float64(int(float64(part)/float64(whole)*1000+0.5)) / 10
+}
+
+// courseReportRows lays the report out as the attendance matrix used by both the
+// CSV and XLSX exports, with a turnout row underneath.
+func courseReportRows(report *models.CourseReport) [][]string {
+	header := []string{"Participant", "Email"}
+	for _, discussion := range report.Discussions {
+		header = append(header, fmt.Sprintf("%s (%s)", discussion.Name, discussion.DateTime.Format("2006-01-02")))
+	}
+	header = append(header, "Attended", "Attendance %", "Ratings submitted")
+	rows := [][]string{header}
+
+	for _, participant := range report.Participants {
+		row := []string{participant.Name, participant.Email}
+		row = append(row, participant.Attendance...)
+		row = append(row,
+			strconv.Itoa(participant.Attended),
+			strconv.FormatFloat(participant.AttendancePercent, 'f', 1, 64),
+			strconv.Itoa(participant.RatingsSubmitted),
+		)
+		rows = append(rows, row)
+	}
+
+	turnout := []string{"Turnout %", ""}
+	for _, discussion := range report.Discussions {
+		turnout = append(turnout, strconv.FormatFloat(discussion.TurnoutPercent, 'f', 1, 64))
+	}
+	return append(rows, turnout)
+}
+
+// courseReportMinutesRows lists each discussion's latest minutes with their
+// key quotes and open questions, one per line within the cell.
+func courseReportMinutesRows(report *models.CourseReport) [][]string {
+	names := map[int]string{}
+	dates := map[int]string{}
+	for _, discussion := range report.Discussions {
+		names[discussion.DiscussionID] = discussion.Name
+		dates[discussion.DiscussionID] = discussion.DateTime.Format("2006-01-02")
+	}
+
+	rows := [][]string{{"Discussion", "Date", "Version", "Written by", "Minutes", "Key quotes", "Open questions"}}
+	for _, minutes := range report.Minutes {
+		var quotes []string
+		for _, quote := range minutes.KeyQuotes {
+			line := fmt.Sprintf("%q", quote.Text)
+			if quote.Attribution != "" {
+				line += " - " + quote.Attribution
+			}
+			quotes = append(quotes, line)
+		}
+		rows = append(rows, []string{
+			names[minutes.DiscussionID],
+			dates[minutes.DiscussionID],
+			strconv.Itoa(minutes.Version),
+			minutes.AuthorName,
+			minutes.Body,
+			strings.Join(quotes, "\n"),
+			strings.Join(minutes.OpenQuestions, "\n"),
+		})
+	}
+	return rows
+}
+
+// escapeFormula stops spreadsheet apps from evaluating user-supplied text such
+// as a participant's name as a formula, by prefixing it with an apostrophe.
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// courseReportCSV writes the attendance matrix followed, after a blank line,
+// by the discussions' minutes.
+func courseReportCSV(report *models.CourseReport) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	rows := courseReportRows(report)
+	if len(report.Minutes) > 0 {
+		rows = append(rows, []string{})
+		rows = append(rows, courseReportMinutesRows(report)...)
+	}
+	for _, row := range rows {
+		for i, value := range row {
+			row[i] = escapeFormula(value)
+		}
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// courseReportXLSX writes the attendance matrix to one sheet, per-discussion
+// turnout, flagging low turnout, to a second and the minutes to a third.
+func courseReportXLSX(report *models.CourseReport) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	const attendanceSheet = "Attendance"
+	const turnoutSheet = "Turnout"
+	const minutesSheet = "Minutes"
+	if err := f.SetSheetName("Sheet1", attendanceSheet); err != nil {
+		return nil, err
+	}
+	if _, err := f.NewSheet(turnoutSheet); err != nil {
+		return nil, err
+	}
+	if _, err := f.NewSheet(minutesSheet); err != nil {
+		return nil, err
+	}
+
+	for i, row := range courseReportRows(report) {
+		values := make([]interface{}, len(row))
+		for j, value := range row {
+			values[j] = escapeFormula(value)
+		}
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return nil, err
+		}
+		if err := f.SetSheetRow(attendanceSheet, cell, &values); err != nil {
+			return nil, err
+		}
+	}
+
+	turnoutRows := [][]interface{}{{"Discussion", "Date", "Held", "Present", "Excused", "Turnout %", "Low turnout"}}
+	lowTurnout := map[int]bool{}
+	for _, discussion := range report.LowTurnout {
+		lowTurnout[discussion.DiscussionID] = true
+	}
+	for _, discussion := range report.Discussions {
+		turnoutRows = append(turnoutRows, []interface{}{
+			escapeFormula(discussion.Name),
+			discussion.DateTime.Format("2006-01-02 15:04"),
+			discussion.Held,
+			discussion.Present,
+			discussion.Excused,
+			discussion.TurnoutPercent,
+			lowTurnout[discussion.DiscussionID],
+		})
+	}
+	for i, row := range turnoutRows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return nil, err
+		}
+		if err := f.SetSheetRow(turnoutSheet, cell, &row); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, row := range courseReportMinutesRows(report) {
+		values := make([]interface{}, len(row))
+		for j, value := range row {
+			values[j] = escapeFormula(value)
+		}
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return nil, err
+		}
+		if err := f.SetSheetRow(minutesSheet, cell, &values); err != nil {
+			return nil, err
+		}
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
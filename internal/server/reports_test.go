@@ -0,0 +1,126 @@
+package server
+
+import (
+	"hippias-fiber/internal/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompileCourseReport(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	discussions := []models.Discussion{
+		{ID: 1, Name: "Apology", DateTime: now.Add(-14 * 24 * time.Hour)},
+		{ID: 2, Name: "Crito", DateTime: now.Add(-7 * 24 * time.Hour)},
+		{ID: 3, Name: "Phaedo", DateTime: now.Add(7 * 24 * time.Hour)},
+	}
+	participants := []models.CourseParticipantDto{
+		{CourseParticipant: models.CourseParticipant{UserID: 10}, User: models.User{Name: "Ada"}},
+		{CourseParticipant: models.CourseParticipant{UserID: 20}, User: models.User{Name: "Ben"}},
+	}
+
+	tests := []struct {
+		name           string
+		attendance     []models.DiscussionAttendance
+		ratings        []models.ReadingRating
+		threshold      float64
+		wantTurnout    []float64
+		wantLowTurnout []int
+		wantPercent    map[int]float64
+		wantRatings    map[int]int
+	}{
+		{
+			name:           "nothing recorded",
+			threshold:      50,
+			wantTurnout:    []float64{0, 0, 0},
+			wantLowTurnout: []int{1, 2},
+			wantPercent:    map[int]float64{10: 0, 20: 0},
+			wantRatings:    map[int]int{10: 0, 20: 0},
+		},
+		{
+			name: "excused absences are left out of the percentage",
+			attendance: []models.DiscussionAttendance{
+				{DiscussionID: 1, UserID: 10, Status: models.AttendancePresent},
+				{DiscussionID: 2, UserID: 10, Status: models.AttendanceExcused},
+				{DiscussionID: 1, UserID: 20, Attended: true},
+				{DiscussionID: 2, UserID: 20, Status: models.AttendanceAbsent},
+			},
+			threshold:      50,
+			wantTurnout:    []float64{100, 0, 0},
+			wantLowTurnout: []int{2},
+			wantPercent:    map[int]float64{10: 100, 20: 50},
+			wantRatings:    map[int]int{10: 0, 20: 0},
+		},
+		{
+			name: "re-rating a reading counts once",
+			ratings: []models.ReadingRating{
+				{ReadingID: 5, UserID: 10, Rating: 4},
+				{ReadingID: 5, UserID: 10, Rating: 2},
+				{ReadingID: 6, UserID: 10, Rating: 3},
+				{ReadingID: 5, UserID: 20, Rating: 5},
+			},
+			threshold:      0,
+			wantTurnout:    []float64{0, 0, 0},
+			wantLowTurnout: nil,
+			wantPercent:    map[int]float64{10: 0, 20: 0},
+			wantRatings:    map[int]int{10: 2, 20: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := compileCourseReport(models.Course{ID: 1}, discussions, participants, tt.attendance, tt.ratings, tt.threshold, now)
+
+			var turnout []float64
+			for _, discussion := range report.Discussions {
+				turnout = append(turnout, discussion.TurnoutPercent)
+			}
+			if !reflect.DeepEqual(turnout, tt.wantTurnout) {
+				t.Errorf("turnout = %v, want %v", turnout, tt.wantTurnout)
+			}
+			if report.Discussions[2].Held {
+				t.Errorf("future discussion marked as held")
+			}
+
+			var low []int
+			for _, discussion := range report.LowTurnout {
+				low = append(low, discussion.DiscussionID)
+			}
+			if !reflect.DeepEqual(low, tt.wantLowTurnout) {
+				t.Errorf("low turnout = %v, want %v", low, tt.wantLowTurnout)
+			}
+
+			for _, participant := range report.Participants {
+				if participant.AttendancePercent != tt.wantPercent[participant.UserID] {
+					t.Errorf("user %d attendance = %v, want %v", participant.UserID, participant.AttendancePercent, tt.wantPercent[participant.UserID])
+				}
+				if participant.RatingsSubmitted != tt.wantRatings[participant.UserID] {
+					t.Errorf("user %d ratings = %d, want %d", participant.UserID, participant.RatingsSubmitted, tt.wantRatings[participant.UserID])
+				}
+				if len(participant.Attendance) != len(discussions) {
+					t.Errorf("user %d has %d attendance cells, want %d", participant.UserID, len(participant.Attendance), len(discussions))
+				}
+			}
+		})
+	}
+}
+
+func TestEscapeFormula(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"Ada Lovelace", "Ada Lovelace"},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1", "'+1"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tindented", "'\tindented"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		if got := escapeFormula(tt.value); got != tt.want {
+			t.Errorf("escapeFormula(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"hippias-fiber/internal/models"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func validRsvpResponse(response string) bool {
+	switch response {
+	case models.RsvpGoing, models.RsvpMaybe, models.RsvpNotGoing:
+		return true
+	}
+	return false
+}
+
+// setRsvp records or replaces the authenticated participant's RSVP for a discussion.
+func (s *Server) setRsvp(c *fiber.Ctx) error {
+	discussionID := c.Params("id")
+
+	var body struct {
+		Response string `json:"response"`
+		Note     string `json:"note"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		log.Printf("Error parsing RSVP: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if !validRsvpResponse(body.Response) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "response must be going, maybe or not_going"})
+	}
+
+	user, err := s.currentUser(c)
+	if err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	discussion, err := s.getDiscussionByID(discussionID)
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	enrolled, err := s.isCourseParticipant(discussion.CourseID, user.ID)
+	if err != nil {
+		log.Printf("Error querying course participants: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if !enrolled {
+		return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{Message: "You are not enrolled in this course"})
+	}
+
+	var existing []models.DiscussionRsvp
+	err = s.sb.DB.From("discussion_rsvps").
+		Select("*").
+		Eq("discussion_id", discussionID).
+		Eq("user_id", strconv.Itoa(user.ID)).
+		Execute(&existing)
+	if err != nil {
+		log.Printf("Error querying RSVPs: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	now := time.Now().UTC()
+	rsvp := models.DiscussionRsvp{
+		DiscussionID: discussion.ID,
+		UserID:       user.ID,
+		Response:     body.Response,
+		Note:         body.Note,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	var saved []models.DiscussionRsvp
+	if len(existing) > 0 {
+		rsvp.ID = existing[0].ID
+		rsvp.CreatedAt = existing[0].CreatedAt
+		err = s.sb.DB.From("discussion_rsvps").
+			Update(rsvp).
+			Eq("id", strconv.Itoa(rsvp.ID)).
+			Execute(&saved)
+	} else {
+		err = s.sb.DB.From("discussion_rsvps").Insert(rsvp).Execute(&saved)
+	}
+	if err != nil {
+		log.Printf("Error saving RSVP: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	log.Printf("User %d RSVP'd %s to discussion %s", user.ID, body.Response, discussionID)
+	if len(saved) > 0 {
+		rsvp = saved[0]
+	}
+	return c.JSON(rsvp)
+}
+
+// listRsvps lists a discussion's RSVPs to the members of its course.
+func (s *Server) listRsvps(c *fiber.Ctx) error {
+	discussionID := c.Params("id")
+
+	discussion, err := s.getDiscussionByID(discussionID)
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	if _, _, err := s.requireCourseMember(c, discussion.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var rsvps []models.DiscussionRsvp
+	err = s.sb.DB.From("discussion_rsvps").
+		Select("*").
+		Eq("discussion_id", discussionID).
+		Execute(&rsvps)
+	if err != nil {
+		log.Printf("Error querying RSVPs: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	return c.JSON(rsvps)
+}
+
+// getRsvpReconciliation is the facilitator's view of who said they would come
+// against who actually did.
+func (s *Server) getRsvpReconciliation(c *fiber.Ctx) error {
+	discussionID := c.Params("id")
+
+	discussion, err := s.getDiscussionByID(discussionID)
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	if _, err := s.requireFacilitator(c, discussion.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	participants, err := s.listCourseParticipantDtos(discussion.CourseID, "id,name")
+	if err != nil {
+		log.Printf("Error querying course participants: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	var rsvps []models.DiscussionRsvp
+	err = s.sb.DB.From("discussion_rsvps").
+		Select("*").
+		Eq("discussion_id", discussionID).
+		Execute(&rsvps)
+	if err != nil {
+		log.Printf("Error querying RSVPs: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	var attendance []models.DiscussionAttendance
+	err = s.sb.DB.From("discussion_attendance").
+		Select("*").
+		Eq("discussion_id", discussionID).
+		Execute(&attendance)
+	if err != nil {
+		log.Printf("Error querying discussion attendance: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	rsvpByUser := map[int]models.DiscussionRsvp{}
+	for _, rsvp := range rsvps {
+		rsvpByUser[rsvp.UserID] = rsvp
+	}
+	attendanceByUser := map[int]models.DiscussionAttendance{}
+	for _, row := range attendance {
+		attendanceByUser[row.UserID] = row
+	}
+
+	past := discussion.DateTime.Before(time.Now())
+	var reconciliation []models.RsvpReconciliation
+	for _, participant := range participants {
+		rsvp := rsvpByUser[participant.UserID]
+		row, recorded := attendanceByUser[participant.UserID]
+		status := ""
+		if recorded {
+			status = attendanceStatus(row)
+		}
+		reconciliation = append(reconciliation, models.RsvpReconciliation{
+			UserID:     participant.UserID,
+			User:       participant.User,
+			Rsvp:       rsvp.Response,
+			Note:       rsvp.Note,
+			Attendance: status,
+			Outcome:    rsvpOutcome(rsvp.Response, status, past),
+		})
+	}
+
+	return c.JSON(reconciliation)
+}
+
+// attendanceStatus reads the status of a row, falling back to the Attended flag
+// for rows recorded before statuses existed.
+func attendanceStatus(row models.DiscussionAttendance) string {
+	if row.Status != "" {
+		return row.Status
+	}
+	if row.Attended {
+		return models.AttendancePresent
+	}
+	return models.AttendanceAbsent
+}
+
+// rsvpOutcome classifies an RSVP against the recorded attendance status. A
+// missing attendance record only counts against a "going" RSVP once the
+// discussion has taken place.
+func rsvpOutcome(rsvp, status string, past bool) string {
+	switch {
+	case status == models.AttendancePresent && (rsvp == models.RsvpGoing || rsvp == models.RsvpMaybe):
+		return "kept"
+	case status == models.AttendancePresent:
+		return "unexpected"
+	case status == models.AttendanceExcused:
+		return "excused"
+	case rsvp == models.RsvpGoing && (status == models.AttendanceAbsent || past):
+		return "no_show"
+	case status == models.AttendanceAbsent || (past && rsvp != ""):
+		return "absent"
+	default:
+		return "pending"
+	}
+}
+
+// participantAttendanceStats tallies RSVP outcomes per user across the
+// discussions of a course that have already happened.
+func participantAttendanceStats(discussions []models.Discussion, rsvps []models.DiscussionRsvp, attendance []models.DiscussionAttendance, now time.Time) map[int]*models.ParticipantAttendanceStats {
+	past := map[int]bool{}
+	for _, discussion := range discussions {
+		past[discussion.ID] = discussion.DateTime.Before(now)
+	}
+
+	type key struct{ discussionID, userID int }
+	statusByKey := map[key]string{}
+	for _, row := range attendance {
+		statusByKey[key{row.DiscussionID, row.UserID}] = attendanceStatus(row)
+	}
+
+	stats := map[int]*models.ParticipantAttendanceStats{}
+	get := func(userID int) *models.ParticipantAttendanceStats {
+		if stats[userID] == nil {
+			stats[userID] = &models.ParticipantAttendanceStats{}
+		}
+		return stats[userID]
+	}
+
+	rsvpByKey := map[key]string{}
+	for _, rsvp := range rsvps {
+		if !past[rsvp.DiscussionID] {
+			continue
+		}
+		k := key{rsvp.DiscussionID, rsvp.UserID}
+		rsvpByKey[k] = rsvp.Response
+		if rsvp.Response == models.RsvpGoing {
+			get(rsvp.UserID).RsvpGoing++
+		}
+		if rsvpOutcome(rsvp.Response, statusByKey[k], true) == "no_show" {
+			get(rsvp.UserID).NoShows++
+		}
+	}
+	for k, status := range statusByKey {
+		if !past[k.discussionID] || status != models.AttendancePresent {
+			continue
+		}
+		get(k.userID).Attended++
+		if _, ok := rsvpByKey[k]; !ok {
+			get(k.userID).Unannounced++
+		}
+	}
+
+	for _, stat := range stats {
+		if stat.RsvpGoing > 0 {
+			stat.NoShowRate = float64(stat.NoShows) / float64(stat.RsvpGoing)
+		}
+	}
+	return stats
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"hippias-fiber/internal/models"
+	"testing"
+)
+
+func TestRsvpOutcome(t *testing.T) {
+	tests := []struct {
+		rsvp   string
+		status string
+		past   bool
+		want   string
+	}{
+		{models.RsvpGoing, models.AttendancePresent, true, "kept"},
+		{models.RsvpMaybe, models.AttendancePresent, true, "kept"},
+		{models.RsvpNotGoing, models.AttendancePresent, true, "unexpected"},
+		{"", models.AttendancePresent, true, "unexpected"},
+		{models.RsvpGoing, models.AttendanceExcused, true, "excused"},
+		{models.RsvpGoing, models.AttendanceAbsent, false, "no_show"},
+		{models.RsvpGoing, "", true, "no_show"},
+		{models.RsvpGoing, "", false, "pending"},
+		{models.RsvpMaybe, "", true, "absent"},
+		{models.RsvpNotGoing, models.AttendanceAbsent, true, "absent"},
+		{"", models.AttendanceAbsent, true, "absent"},
+		{"", "", true, "pending"},
+		{models.RsvpMaybe, "", false, "pending"},
+	}
+	for _, tt := range tests {
+		if got := rsvpOutcome(tt.rsvp, tt.status, tt.past); got != tt.want {
+			t.Errorf("rsvpOutcome(%q, %q, %v) = %q, want %q", tt.rsvp, tt.status, tt.past, got, tt.want)
+		}
+	}
+}
@@ -2,12 +2,16 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"hippias-fiber/internal/models"
 	_ "hippias-fiber/swagger"
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/session"
@@ -19,6 +23,14 @@ import (
 type Server struct {
 	*fiber.App
 	sb *supa.Client
+	// Key used to sign attendance check-in tokens
+	checkInSecret []byte
+	// Users allowed to use the /admin endpoints
+	adminEmails []string
+	// Live poll connections per discussion
+	polls *pollHub
+	// Course event stream subscribers
+	events *eventHub
 }
 
 func getDecoder() *mapstructure.Decoder {
@@ -51,8 +63,12 @@ func New() *Server {
 	}))
 	app.Get("/swagger/*", swagger.HandlerDefault)
 	server := &Server{
-		App: app,
-		sb:  client,
+		App:           app,
+		sb:            client,
+		checkInSecret: []byte(os.Getenv("CHECKIN_SECRET")),
+		adminEmails:   strings.Split(os.Getenv("ADMIN_EMAILS"), ","),
+		polls:         newPollHub(),
+		events:        newEventHub(),
 	}
 
 	server.setupRoutes()
@@ -65,15 +81,68 @@ func (s *Server) Sb() *supa.Client {
 }
 
 func (s *Server) setupRoutes() {
-	s.App.Get("/book/id", s.getBook)
-	s.App.Get("/list", s.listBooks)
+	s.App.Get("/tags", s.listTags)
+	s.App.Get("/tags/counts", s.getTagCounts)
+	s.App.Get("/tags/:id", s.getTag)
+	s.App.Get("/tags/:id/items", s.getTaggedItems)
+	s.App.Post("/tags", s.createTag)
+	s.App.Put("/tags/:id", s.updateTag)
+	s.App.Delete("/tags/:id", s.deleteTag)
+	s.App.Get("/books", s.listBooks)
+	s.App.Get("/books/duplicates", s.listBookDuplicates)
+	s.App.Get("/books/:id", s.getBook)
+	s.App.Post("/books/:id/merge", s.mergeBook)
+	s.App.Post("/books", s.createBook)
+	s.App.Put("/books/:id", s.updateBook)
+	s.App.Delete("/books/:id", s.deleteBook)
+	s.App.Get("/books/:id/editions", s.listBookEditions)
+	s.App.Get("/books/:id/tags", s.entityTagsHandler(models.TagBook))
+	s.App.Put("/books/:id/tags", s.setEntityTagsHandler(models.TagBook))
+	s.App.Post("/books/:id/editions", s.createEdition)
+	s.App.Get("/editions/:id", s.getEdition)
+	s.App.Put("/editions/:id", s.updateEdition)
+	s.App.Delete("/editions/:id", s.deleteEdition)
 	s.App.Get("/authors", s.listAuthors)
+	s.App.Get("/authors/duplicates", s.listAuthorDuplicates)
 	s.App.Get("/authors/:id", s.getAuthor)
+	s.App.Post("/authors/:id/merge", s.mergeAuthor)
+	s.App.Get("/authors/:id/relations", s.listAuthorRelations)
+	s.App.Post("/authors/:id/relations", s.createAuthorRelation)
+	s.App.Delete("/authors/:id/relations/:relationId", s.deleteAuthorRelation)
+	s.App.Get("/authors/:id/neighborhood", s.getAuthorNeighborhood)
+	s.App.Get("/authors/:id/tags", s.entityTagsHandler(models.TagAuthor))
+	s.App.Put("/authors/:id/tags", s.setEntityTagsHandler(models.TagAuthor))
+	s.App.Get("/authors/:id/books", s.getBooksByAuthorID)
+	s.App.Post("/authors", s.createAuthor)
+	s.App.Put("/authors/:id", s.updateAuthor)
+	s.App.Delete("/authors/:id", s.deleteAuthor)
+	// Older paths, kept for existing clients
+	s.App.Get("/list", s.listBooks)
 	s.App.Get("/books/author/:id", s.getBooksByAuthorID)
 	s.App.Get("/courses", s.listCourses)
 	s.App.Get("/courses/:id", s.getCourse)
 	s.App.Get("/courses/details/:id", s.GetCourseWithDetails)
+	s.App.Get("/courses/:id/books", s.getCourseBooks)
+	s.App.Post("/courses/:id/books", s.addCourseBook)
+	s.App.Put("/courses/:id/books/order", s.reorderCourseBooks)
+	s.App.Put("/courses/:id/books/:bookId", s.updateCourseBook)
+	s.App.Delete("/courses/:id/books/:bookId", s.removeCourseBook)
+	s.App.Get("/courses/:id/bibliography", s.getCourseBibliography)
+	s.App.Get("/courses/:id/timeline", s.getCourseTimeline)
+	s.App.Get("/courses/:id/events", s.streamCourseEvents)
+	s.App.Get("/courses/:id/announcements", s.listAnnouncements)
+	s.App.Post("/courses/:id/announcements", s.createAnnouncement)
+	s.App.Get("/courses/:id/announcements/feed", s.getCourseFeed)
+	s.App.Get("/courses/:id/announcements/feed-token", s.getCourseFeedToken)
+	s.App.Post("/courses/:id/announcements/feed-token", s.rotateCourseFeedToken)
+	s.App.Get("/announcements/feed", s.getPublicFeed)
+	s.App.Get("/announcements/:id", s.getAnnouncement)
+	s.App.Put("/announcements/:id", s.updateAnnouncement)
+	s.App.Delete("/announcements/:id", s.deleteAnnouncement)
+	s.App.Get("/courses/:id/tags", s.entityTagsHandler(models.TagCourse))
+	s.App.Put("/courses/:id/tags", s.setEntityTagsHandler(models.TagCourse))
 	s.App.Post("/courses", s.createCourse)
+	s.App.Put("/courses/:id/visibility", s.setCourseVisibility)
 	s.App.Get("/facilitators", s.listFacilitators)
 	s.App.Get("/facilitators/:id", s.getFacilitator)
 	s.App.Post("/facilitators", s.createFacilitator)
@@ -84,11 +153,14 @@ func (s *Server) setupRoutes() {
 	s.App.Get("/discussions", s.listDiscussions)
 	s.App.Get("/discussions/:id", s.getDiscussion)
 	s.App.Post("/discussions", s.createDiscussion)
+	s.App.Post("/courses/:id/discussions/recurring", s.createRecurringDiscussions)
 	s.App.Put("/discussions/:id", s.updateDiscussion)
 	s.App.Delete("/discussions/:id", s.deleteDiscussion)
 	s.App.Post("/reading-ratings", s.createReadingRating)
 	s.App.Get("/reading-ratings/:id", s.getReadingRating)
 	s.App.Get("/readings/:id/ratings", s.listReadingRatings)
+	s.App.Get("/readings/:id/ratings/stats", s.getReadingRatingStats)
+	s.App.Get("/discussions/:id/ratings/stats", s.getDiscussionRatingStats)
 	s.App.Put("/reading-ratings/:id", s.updateReadingRating)
 	s.App.Delete("/reading-ratings/:id", s.deleteReadingRating)
 	s.App.Get("/readings", s.listReadings)
@@ -96,10 +168,60 @@ func (s *Server) setupRoutes() {
 	s.App.Post("/readings", s.createReading)
 	s.App.Put("/readings/:id", s.updateReading)
 	s.App.Delete("/readings/:id", s.deleteReading)
+	s.App.Get("/readings/:id/tags", s.entityTagsHandler(models.TagReading))
+	s.App.Put("/readings/:id/tags", s.setEntityTagsHandler(models.TagReading))
+	s.App.Get("/readings/:id/progress", s.getReadingProgress)
+	s.App.Put("/readings/:id/progress", s.setReadingProgress)
+	s.App.Get("/readings/:id/annotations", s.listAnnotations)
+	s.App.Post("/readings/:id/annotations", s.createAnnotation)
+	s.App.Get("/readings/:id/annotations/passages", s.getAnnotatedPassages)
+	s.App.Put("/annotations/:id", s.updateAnnotation)
+	s.App.Delete("/annotations/:id", s.deleteAnnotation)
+	s.App.Post("/discussions/:id/readings/import", s.importReadings)
 	s.App.Post("/discussion-attendance", s.createDiscussionAttendance)
 	s.App.Get("/discussions/:id/attendance", s.listDiscussionAttendance)
+	s.App.Put("/discussions/:id/attendance", s.recordBulkAttendance)
+	s.App.Post("/discussions/:id/check-in-token", s.createCheckInToken)
+	s.App.Post("/check-in", s.redeemCheckInToken)
+	s.App.Put("/discussions/:id/rsvp", s.setRsvp)
+	s.App.Get("/discussions/:id/rsvps", s.listRsvps)
+	s.App.Get("/discussions/:id/rsvps/reconciliation", s.getRsvpReconciliation)
 	s.App.Get("/courses/:id/management", s.getCourseManagementDetails)
+	s.App.Get("/courses/:id/reports/participation", s.getCourseReport)
+	s.App.Get("/courses/:id/completion-criteria", s.getCompletionCriteria)
+	s.App.Put("/courses/:id/completion-criteria", s.setCompletionCriteria)
+	s.App.Post("/courses/:id/certificates", s.issueCertificates)
+	s.App.Get("/certificates/:code", s.verifyCertificate)
+	s.App.Get("/certificates/:code/pdf", s.getCertificatePDF)
 	s.App.Get("/discussions/:id/management", s.GetDiscussionMgmtDetails)
+	s.App.Get("/discussions/:id/threads", s.listForumThreads)
+	s.App.Post("/discussions/:id/threads", s.createForumThread)
+	s.App.Get("/threads/:id", s.getForumThread)
+	s.App.Post("/threads/:id/posts", s.createForumPost)
+	s.App.Put("/threads/:id/moderation", s.moderateForumThread)
+	s.App.Delete("/threads/:id", s.deleteForumThread)
+	s.App.Put("/forum-posts/:id", s.updateForumPost)
+	s.App.Delete("/forum-posts/:id", s.deleteForumPost)
+	s.App.Get("/forum-posts/:id/revisions", s.listForumPostRevisions)
+	s.App.Get("/discussions/:id/progress", s.getDiscussionProgress)
+	s.App.Get("/discussions/:id/minutes", s.getMinutes)
+	s.App.Put("/discussions/:id/minutes", s.saveMinutes)
+	s.App.Get("/discussions/:id/minutes/versions", s.listMinutesVersions)
+	s.App.Get("/discussions/:id/minutes/versions/:version", s.getMinutesVersion)
+	s.App.Get("/discussions/:id/assignments", s.getAssignments)
+	s.App.Put("/discussions/:id/assignments", s.updateAssignments)
+	s.App.Post("/discussions/:id/assignments/generate", s.generateAssignments)
+	s.App.Post("/discussions/:id/assignments/publish", s.publishAssignments)
+	s.App.Get("/discussions/:id/polls", s.listDiscussionPolls)
+	s.App.Get("/discussions/:id/polls/live", s.upgradeLivePolls, websocket.New(s.livePolls))
+	s.App.Get("/discussions/:id/questions", s.listQuestions)
+	s.App.Post("/discussions/:id/questions", s.createQuestion)
+	s.App.Put("/questions/:id", s.updateQuestion)
+	s.App.Delete("/questions/:id", s.deleteQuestion)
+	s.App.Put("/questions/:id/status", s.setQuestionStatus)
+	s.App.Put("/questions/:id/vote", s.upvoteQuestion)
+	s.App.Delete("/questions/:id/vote", s.removeQuestionVote)
+	s.App.Post("/admin/import", s.importCatalog)
 }
 
 func (s *Server) login(c *fiber.Ctx) error {
@@ -149,115 +271,24 @@ func (s *Server) register(c *fiber.Ctx) error {
 	log.Printf("User: %+v", user)
 	return c.JSON(map[string]string{"message": "Registration successful"})
 }
-func (s *Server) getBook(c *fiber.Ctx) error {
-	bookID := c.Params("id")
-
-	var result map[string]interface{}
-	err := s.sb.DB.From("books").
-		Select("*").
-		Single().
-		Eq("id", bookID).
-		Execute(&result)
-
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
-	}
-
-	var book models.Book
-	if err := mapstructure.Decode(result, &book); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
-	}
-
-	return c.JSON(book)
-}
-
-func (s *Server) getBooksByAuthorID(c *fiber.Ctx) error {
-	authorID := c.Params("id")
-	log.Printf("Author ID: %v", authorID)
-
-	if authorID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Missing author ID"})
-	}
-
-	var result map[string]interface{}
-	err := s.sb.DB.From("books").
-		Select("*").
-		Eq("authorId", authorID).
-		Execute(&result)
-
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
-	}
-
-	log.Printf("Result: %v", result)
-	var books []models.Book
-	if err := mapstructure.Decode(result, &books); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
-	}
-	log.Printf("Books: %v", books)
-
-	return c.JSON(books)
-}
-
-func (s *Server) listAuthors(c *fiber.Ctx) error {
-	var results []map[string]interface{}
-	err := s.sb.DB.From("authors").Select("*").Execute(&results)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
-	}
 
-	var authors []models.Author
-	if err := mapstructure.Decode(results, &authors); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
-	}
-
-	return c.JSON(authors)
-}
-
-func (s *Server) getAuthor(c *fiber.Ctx) error {
-	authorID := c.Params("id")
-	log.Printf("Author ID: %v", authorID)
-	var result map[string]interface{}
-	err := s.sb.DB.From("authors").
-		Select("*").
-		Single().
-		Eq("id", authorID).
-		Execute(&result)
-
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
-	}
-
-	var author models.Author
-	if err := mapstructure.Decode(result, &author); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
-	}
-
-	return c.JSON(author)
-}
-
-func (s *Server) listBooks(c *fiber.Ctx) error {
-	var result []map[string]interface{}
-	err := s.sb.DB.From("books").Select("*").Execute(&result)
+// listCourses takes ?tag= (an ID or slug) to list only courses under that tag.
+func (s *Server) listCourses(c *fiber.Ctx) error {
+	tagged, err := s.tagFilter(c, models.TagCourse)
 	if err != nil {
-		log.Printf("Error querying books: %v", err)
+		log.Printf("Error querying tags: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 	}
-
-	var books []models.Book
-	if err := mapstructure.Decode(result, &books); err != nil {
-		log.Printf("Error unmarshaling books: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	query := s.sb.DB.From("courses").Select("*")
+	if tagged != nil {
+		if len(tagged) == 0 {
+			return c.JSON([]models.Course{})
+		}
+		query.In("id", tagged)
 	}
 
-	log.Printf("Books: %+v", books)
-
-	return c.JSON(books)
-}
-
-func (s *Server) listCourses(c *fiber.Ctx) error {
 	var jsonResult json.RawMessage
-	err := s.sb.DB.From("courses").Select("*").Execute(&jsonResult)
+	err = query.Execute(&jsonResult)
 	if err != nil {
 		log.Printf("Error querying courses: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
@@ -298,6 +329,24 @@ func (s *Server) getCourse(c *fiber.Ctx) error {
 	return c.JSON(course)
 }
 
+func (s *Server) getCourseByID(courseID string) (*models.Course, error) {
+	var courseResult json.RawMessage
+	err := s.sb.DB.From("courses").
+		Select("*").
+		Single().
+		Eq("id", courseID).
+		Execute(&courseResult)
+	if err != nil {
+		return nil, err
+	}
+
+	var course models.Course
+	if err := json.Unmarshal(courseResult, &course); err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
 func (s *Server) GetCourseWithDetails(c *fiber.Ctx) error {
 	courseID := c.Params("id")
 	log.Printf("GetCourseWithDetails: Processing request for course ID: %s", courseID)
@@ -347,51 +396,12 @@ func (s *Server) GetCourseWithDetails(c *fiber.Ctx) error {
 	}
 	log.Printf("GetCourseWithDetails: Fetched facilitator: %+v", facilitator)
 
-	var jsonBooksResult json.RawMessage
-	err3 := s.sb.DB.From("course_books").
-		Select("book_id").
-		Eq("course_id", courseID).
-		Execute(&jsonBooksResult)
-	if err3 != nil {
-		log.Printf("GetCourseWithDetails: Error querying course books: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
-	}
-
-	var courseBooks []models.CourseBook
-	err = json.Unmarshal(jsonBooksResult, &courseBooks)
+	books, err := s.listCourseBooks(courseID)
 	if err != nil {
-		log.Printf("GetCourseWithDetails: Error unmarshaling course books data: %v", err)
+		log.Printf("GetCourseWithDetails: Error querying course books: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 	}
-	log.Printf("GetCourseWithDetails: Fetched course books: %+v", courseBooks)
-
-	var books []models.Book
-	for _, courseBook := range courseBooks {
-		bookID := strconv.Itoa(courseBook.BookID)
-		log.Printf("GetCourseWithDetails: Processing book ID: %s", bookID)
-
-		var jsonBookResult json.RawMessage
-		err := s.Sb().DB.From("books").
-			Select("*").
-			Single().
-			Eq("id", bookID).
-			Execute(&jsonBookResult)
-		if err != nil {
-			log.Printf("GetCourseWithDetails: Error querying book: %v", err)
-			log.Printf("GetCourseWithDetails: Book ID: %s", bookID)
-			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
-		}
-
-		var book models.Book
-		err = json.Unmarshal(jsonBookResult, &book)
-		if err != nil {
-			log.Printf("GetCourseWithDetails: Error unmarshaling book data: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
-		}
-		log.Printf("GetCourseWithDetails: Fetched book: %+v", book)
-
-		books = append(books, book)
-	}
+	log.Printf("GetCourseWithDetails: Fetched course books: %+v", books)
 
 	response := GetCourseWithDetailsResponse{
 		Course:      course,
@@ -406,7 +416,8 @@ func (s *Server) GetCourseWithDetails(c *fiber.Ctx) error {
 type GetCourseWithDetailsResponse struct {
 	Course      models.Course      `json:"course"`
 	Facilitator models.Facilitator `json:"facilitator"`
-	Books       []models.Book      `json:"books"`
+	// Books in the course's reading order
+	Books []models.CourseBookDto `json:"books"`
 }
 
 type ErrorResponse struct {
@@ -419,6 +430,15 @@ func (s *Server) createCourse(c *fiber.Ctx) error {
 		log.Printf("Error parsing course: %v", err)
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
 	}
+	if course.Timezone == "" {
+		course.Timezone = "UTC"
+	}
+	if _, err := loadLocation(course.Timezone); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	course.CreatedAt = now
+	course.UpdatedAt = now
 
 	data, err := json.Marshal(course)
 	if err != nil {
@@ -437,6 +457,41 @@ func (s *Server) createCourse(c *fiber.Ctx) error {
 	return c.JSON(data)
 }
 
+// setCourseVisibility lets the facilitator make a course public, listing its
+// announcements in the public feed, or private again.
+func (s *Server) setCourseVisibility(c *fiber.Ctx) error {
+	courseID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid course ID"})
+	}
+	if _, err := s.requireFacilitator(c, courseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var body models.CourseVisibilityRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Printf("Error parsing course visibility: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if body.Public == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "public is required"})
+	}
+
+	changes := map[string]interface{}{"public": *body.Public, "updated_at": time.Now().UTC().Format(time.RFC3339)}
+	var updated []models.Course
+	err = s.sb.DB.From("courses").Update(changes).Eq("id", strconv.Itoa(courseID)).Execute(&updated)
+	if err != nil {
+		log.Printf("Error updating course visibility: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(updated) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Course not found"})
+	}
+
+	log.Printf("Course %d is now public=%t", courseID, *body.Public)
+	return c.JSON(updated[0])
+}
+
 func (s *Server) listFacilitators(c *fiber.Ctx) error {
 	var jsonResult json.RawMessage
 	err := s.sb.DB.From("facilitators").Select("*").Execute(&jsonResult)
@@ -520,29 +575,118 @@ func (s *Server) deleteFacilitator(c *fiber.Ctx) error {
 
 // DISCUSSIONS
 func (s *Server) createDiscussion(c *fiber.Ctx) error {
-	var discussion models.Discussion
-	if err := c.BodyParser(&discussion); err != nil {
+	var body models.DiscussionRequest
+	if err := c.BodyParser(&body); err != nil {
 		log.Printf("Error parsing discussion: %v", err)
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
 	}
 
+	loc, err := s.inputLocation(c, body.CourseID)
+	if err != nil {
+		log.Printf("Error loading course timezone: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	dateTime, err := parseDiscussionTime(body.DateTime, loc)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	discussion := models.Discussion{
+		CourseID:    body.CourseID,
+		Name:        body.Name,
+		Description: body.Description,
+		DateTime:    dateTime,
+	}
+
 	data, err := json.Marshal(discussion)
 	if err != nil {
 		log.Printf("Error marshaling discussion: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 	}
 
-	var jsonResult json.RawMessage
-	err = s.sb.DB.From("discussions").Insert(string(data)).Execute(&jsonResult)
+	var created []models.Discussion
+	err = s.sb.DB.From("discussions").Insert(string(data)).Execute(&created)
 	if err != nil {
 		log.Printf("Error inserting discussion: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 	}
+	if len(created) > 0 {
+		discussion = created[0]
+	}
+	s.publishCourseEvent(discussion.CourseID, models.EventDiscussionCreated, discussion)
 
 	log.Printf("Created discussion: %+v", discussion)
+	discussion.DateTime = discussion.DateTime.In(loc)
 	return c.JSON(discussion)
 }
 
+func (s *Server) createRecurringDiscussions(c *fiber.Ctx) error {
+	courseID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "Invalid course ID"})
+	}
+	if _, err := s.requireFacilitator(c, courseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var body models.RecurringDiscussionRequest
+	if err := c.BodyParser(&body); err != nil {
+		log.Printf("Error parsing recurring discussion: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if body.IntervalDays < 1 || body.Count < 1 || body.Count > 104 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "interval_days must be positive and count between 1 and 104"})
+	}
+
+	courseLoc, err := s.courseLocation(courseID)
+	if err != nil {
+		log.Printf("Error loading course timezone: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	loc, err := s.displayLocation(c, courseLoc)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	startsAt, err := parseDiscussionTime(body.StartsAt, loc)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	// Repeat on the course's calendar so the meeting time holds across its DST
+	var discussions []models.Discussion
+	for _, t := range recurringTimes(startsAt, courseLoc, body.IntervalDays, body.Count) {
+		discussions = append(discussions, models.Discussion{
+			CourseID:    courseID,
+			Name:        body.Name,
+			Description: body.Description,
+			DateTime:    t,
+		})
+	}
+
+	data, err := json.Marshal(discussions)
+	if err != nil {
+		log.Printf("Error marshaling discussions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	var created []models.Discussion
+	err = s.sb.DB.From("discussions").Insert(string(data)).Execute(&created)
+	if err != nil {
+		log.Printf("Error inserting discussions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	for _, discussion := range created {
+		s.publishCourseEvent(courseID, models.EventDiscussionCreated, discussion)
+	}
+
+	log.Printf("Created %d recurring discussions for course %d", len(created), courseID)
+	localizeDiscussions(created, loc)
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
 func (s *Server) getDiscussion(c *fiber.Ctx) error {
 	discussionID := c.Params("id")
 
@@ -564,10 +708,38 @@ func (s *Server) getDiscussion(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 	}
 
+	courseLoc, err := s.courseLocation(discussion.CourseID)
+	if err != nil {
+		log.Printf("Error loading course timezone: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	loc, err := s.displayLocation(c, courseLoc)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	discussion.DateTime = discussion.DateTime.In(loc)
+
 	log.Printf("Discussion: %+v", discussion)
 	return c.JSON(discussion)
 }
 
+func (s *Server) getDiscussionByID(discussionID string) (*models.Discussion, error) {
+	var discussions []models.Discussion
+	err := s.sb.DB.From("discussions").
+		Select("*").
+		Eq("id", discussionID).
+		Execute(&discussions)
+	if err != nil {
+		return nil, err
+	}
+	if len(discussions) == 0 {
+		return nil, errDiscussionNotFound
+	}
+	return &discussions[0], nil
+}
+
+var errDiscussionNotFound = errors.New("discussion not found")
+
 func (s *Server) listDiscussions(c *fiber.Ctx) error {
 	var jsonResult json.RawMessage
 	err := s.sb.DB.From("discussions").Select("*").Execute(&jsonResult)
@@ -583,6 +755,29 @@ func (s *Server) listDiscussions(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 	}
 
+	// Discussions span courses, so without an explicit zone each one is rendered
+	// in its own course's timezone.
+	override, err := s.displayLocation(c, nil)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	courseLocs := map[int]*time.Location{}
+	for i := range discussions {
+		loc := override
+		if loc == nil {
+			var ok bool
+			if loc, ok = courseLocs[discussions[i].CourseID]; !ok {
+				loc, err = s.courseLocation(discussions[i].CourseID)
+				if err != nil {
+					log.Printf("Error loading course timezone: %v", err)
+					loc = time.UTC
+				}
+				courseLocs[discussions[i].CourseID] = loc
+			}
+		}
+		discussions[i].DateTime = discussions[i].DateTime.In(loc)
+	}
+
 	log.Printf("Discussions: %+v", discussions)
 	return c.JSON(discussions)
 }
@@ -590,12 +785,44 @@ func (s *Server) listDiscussions(c *fiber.Ctx) error {
 func (s *Server) updateDiscussion(c *fiber.Ctx) error {
 	discussionID := c.Params("id")
 
-	var discussion models.Discussion
-	if err := c.BodyParser(&discussion); err != nil {
+	var body models.DiscussionRequest
+	if err := c.BodyParser(&body); err != nil {
 		log.Printf("Error parsing discussion: %v", err)
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
 	}
 
+	// The body may leave out course_id; the discussion stays in its course
+	stored, err := s.getDiscussionByID(discussionID)
+	if errors.Is(err, errDiscussionNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	courseID := body.CourseID
+	if courseID == 0 {
+		courseID = stored.CourseID
+	}
+
+	loc, err := s.inputLocation(c, courseID)
+	if err != nil {
+		log.Printf("Error loading course timezone: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	dateTime, err := parseDiscussionTime(body.DateTime, loc)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	discussion := models.Discussion{
+		CourseID:    courseID,
+		Name:        body.Name,
+		Description: body.Description,
+		DateTime:    dateTime,
+	}
+
 	data, err := json.Marshal(discussion)
 	if err != nil {
 		log.Printf("Error marshaling discussion: %v", err)
@@ -610,18 +837,28 @@ func (s *Server) updateDiscussion(c *fiber.Ctx) error {
 	}
 
 	log.Printf("Updated discussion: %+v", discussion)
+	discussion.ID, _ = strconv.Atoi(discussionID)
+	s.publishCourseEvent(discussion.CourseID, models.EventDiscussionUpdated, discussion)
+	discussion.DateTime = discussion.DateTime.In(loc)
 	return c.JSON(discussion)
 }
 
 func (s *Server) deleteDiscussion(c *fiber.Ctx) error {
 	discussionID := c.Params("id")
 
+	discussion, err := s.getDiscussionByID(discussionID)
+	if err != nil {
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+
 	var jsonResult json.RawMessage
-	err := s.sb.DB.From("discussions").Delete().Eq("id", discussionID).Execute(&jsonResult)
+	err = s.sb.DB.From("discussions").Delete().Eq("id", discussionID).Execute(&jsonResult)
 	if err != nil {
 		log.Printf("Error deleting discussion: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 	}
+	s.publishCourseEvent(discussion.CourseID, models.EventDiscussionDeleted, discussion)
 
 	log.Printf("Deleted discussion with ID: %s", discussionID)
 	return c.SendStatus(fiber.StatusNoContent)
@@ -635,6 +872,9 @@ func (s *Server) createReading(c *fiber.Ctx) error {
 		log.Printf("Error parsing reading: %v", err)
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
 	}
+	if err := s.validateReading(&reading); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
 
 	data, err := json.Marshal(reading)
 	if err != nil {
@@ -642,12 +882,16 @@ func (s *Server) createReading(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 	}
 
-	var jsonResult json.RawMessage
-	err = s.sb.DB.From("readings").Insert(string(data)).Execute(&jsonResult)
+	var created []models.Reading
+	err = s.sb.DB.From("readings").Insert(string(data)).Execute(&created)
 	if err != nil {
 		log.Printf("Error inserting reading: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 	}
+	if len(created) > 0 {
+		reading = created[0]
+	}
+	s.publishDiscussionEvent(reading.DiscussionID, models.EventReadingAdded, reading)
 
 	log.Printf("Created reading: %+v", reading)
 	return c.JSON(reading)
@@ -678,9 +922,23 @@ func (s *Server) getReading(c *fiber.Ctx) error {
 	return c.JSON(reading)
 }
 
+// listReadings takes ?tag= (an ID or slug) to list only readings under that tag.
 func (s *Server) listReadings(c *fiber.Ctx) error {
+	tagged, err := s.tagFilter(c, models.TagReading)
+	if err != nil {
+		log.Printf("Error querying tags: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	query := s.sb.DB.From("readings").Select("*")
+	if tagged != nil {
+		if len(tagged) == 0 {
+			return c.JSON([]models.Reading{})
+		}
+		query.In("id", tagged)
+	}
+
 	var jsonResult json.RawMessage
-	err := s.sb.DB.From("readings").Select("*").Execute(&jsonResult)
+	err = query.Execute(&jsonResult)
 	if err != nil {
 		log.Printf("Error querying readings: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
@@ -705,6 +963,9 @@ func (s *Server) updateReading(c *fiber.Ctx) error {
 		log.Printf("Error parsing reading: %v", err)
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
 	}
+	if err := s.validateReading(&reading); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
 
 	data, err := json.Marshal(reading)
 	if err != nil {
@@ -728,6 +989,9 @@ func (s *Server) deleteReading(c *fiber.Ctx) error {
 
 	var jsonResult json.RawMessage
 	err := s.sb.DB.From("readings").Delete().Eq("id", readingID).Execute(&jsonResult)
+	if err == nil {
+		err = s.removeEntityTags(models.TagReading, readingID)
+	}
 	if err != nil {
 		log.Printf("Error deleting reading: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
@@ -739,25 +1003,52 @@ func (s *Server) deleteReading(c *fiber.Ctx) error {
 
 //Discussion Attendance
 
+// createDiscussionAttendance records one participant's attendance. Only the
+// facilitator may record it; participants check themselves in with a
+// check-in token instead.
 func (s *Server) createDiscussionAttendance(c *fiber.Ctx) error {
 	var attendance models.DiscussionAttendance
 	if err := c.BodyParser(&attendance); err != nil {
 		log.Printf("Error parsing discussion attendance: %v", err)
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
 	}
-
-	data, err := json.Marshal(attendance)
+	discussion, err := s.getDiscussionByID(strconv.Itoa(attendance.DiscussionID))
 	if err != nil {
-		log.Printf("Error marshaling discussion attendance: %v", err)
+		log.Printf("Error querying discussion: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Discussion not found"})
+	}
+	if _, err := s.requireFacilitator(c, discussion.CourseID); err != nil {
+		return authErrorResponse(c, err)
+	}
+	enrolled, err := s.isCourseParticipant(discussion.CourseID, attendance.UserID)
+	if err != nil {
+		log.Printf("Error querying course participants: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 	}
+	if !enrolled {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "User " + strconv.Itoa(attendance.UserID) + " is not enrolled in this course"})
+	}
+	if attendance.Status == "" {
+		attendance.Status = models.AttendanceAbsent
+		if attendance.Attended {
+			attendance.Status = models.AttendancePresent
+		}
+	}
+	if !validAttendanceStatus(attendance.Status) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "status must be present, absent or excused"})
+	}
+	attendance.Attended = attendance.Status == models.AttendancePresent
 
-	var jsonResult json.RawMessage
-	err = s.sb.DB.From("discussion_attendance").Insert(string(data)).Execute(&jsonResult)
+	// Recording someone twice updates their row rather than adding another
+	saved, err := s.recordAttendance(discussion.ID, []models.AttendanceRecord{{UserID: attendance.UserID, Status: attendance.Status}})
 	if err != nil {
-		log.Printf("Error inserting discussion attendance: %v", err)
+		log.Printf("Error recording discussion attendance: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 	}
+	if len(saved) > 0 {
+		attendance = saved[0]
+	}
+	s.publishCourseEvent(discussion.CourseID, models.EventAttendanceRecorded, attendance)
 
 	log.Printf("Created discussion attendance: %+v", attendance)
 	return c.JSON(attendance)
@@ -790,60 +1081,74 @@ func (s *Server) listDiscussionAttendance(c *fiber.Ctx) error {
 // ReadingRating
 
 func (s *Server) getReadingRating(c *fiber.Ctx) error {
-	ratingID := c.Params("id")
-
-	var jsonResult json.RawMessage
-	err := s.sb.DB.From("reading_ratings").
-		Select("*").
-		Single().
-		Eq("id", ratingID).
-		Execute(&jsonResult)
+	rating, err := s.getReadingRatingByID(c.Params("id"))
 	if err != nil {
 		log.Printf("Error querying reading rating: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Reading rating not found"})
 	}
-
-	var rating models.ReadingRating
-	err = json.Unmarshal(jsonResult, &rating)
+	_, user, facilitator, err := s.readingAccess(c, strconv.Itoa(rating.ReadingID))
 	if err != nil {
-		log.Printf("Error unmarshaling reading rating: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		return readingErrorResponse(c, err)
 	}
 
-	log.Printf("Reading rating: %+v", rating)
-	return c.JSON(rating)
+	ratings := []models.ReadingRating{*rating}
+	redactRatings(ratings, user, facilitator)
+	log.Printf("Reading rating: %+v", ratings[0])
+	return c.JSON(ratings[0])
 }
 
 func (s *Server) listReadingRatings(c *fiber.Ctx) error {
-	readingID := c.Params("id")
-
-	var jsonResult json.RawMessage
-	err := s.sb.DB.From("reading_ratings").
-		Select("*").
-		Eq("reading_id", readingID).
-		Execute(&jsonResult)
+	reading, user, facilitator, err := s.readingAccess(c, c.Params("id"))
 	if err != nil {
-		log.Printf("Error querying reading ratings: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		return readingErrorResponse(c, err)
 	}
 
-	var ratings []models.ReadingRating
-	err = json.Unmarshal(jsonResult, &ratings)
+	ratings, err := s.ratingsForReadings([]models.Reading{*reading})
 	if err != nil {
-		log.Printf("Error unmarshaling reading ratings: %v", err)
+		log.Printf("Error querying reading ratings: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 	}
 
+	redactRatings(ratings, user, facilitator)
 	log.Printf("Reading ratings: %+v", ratings)
 	return c.JSON(ratings)
 }
 
+// createReadingRating records the current user's rating of a reading; each
+// participant rates a reading once and edits that rating afterwards.
 func (s *Server) createReadingRating(c *fiber.Ctx) error {
 	var rating models.ReadingRating
 	if err := c.BodyParser(&rating); err != nil {
 		log.Printf("Error parsing reading rating: %v", err)
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
 	}
+	if err := validateReadingRating(&rating); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	reading, user, _, err := s.readingAccess(c, strconv.Itoa(rating.ReadingID))
+	if err != nil {
+		return readingErrorResponse(c, err)
+	}
+
+	var existing []models.ReadingRating
+	err = s.sb.DB.From("reading_ratings").Select("*").
+		Eq("reading_id", strconv.Itoa(reading.ID)).
+		Eq("user_id", strconv.Itoa(user.ID)).
+		Execute(&existing)
+	if err != nil {
+		log.Printf("Error querying reading ratings: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(existing) > 0 {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{Message: "You have already rated this reading"})
+	}
+
+	now := time.Now().UTC()
+	rating.ID = 0
+	rating.UserID = user.ID
+	rating.DiscussionID = reading.DiscussionID
+	rating.CreatedAt = now
+	rating.UpdatedAt = now
 
 	data, err := json.Marshal(rating)
 	if err != nil {
@@ -851,25 +1156,55 @@ func (s *Server) createReadingRating(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 	}
 
-	var jsonResult json.RawMessage
-	err = s.sb.DB.From("reading_ratings").Insert(string(data)).Execute(&jsonResult)
+	var created []models.ReadingRating
+	err = s.sb.DB.From("reading_ratings").Insert(string(data)).Execute(&created)
 	if err != nil {
 		log.Printf("Error inserting reading rating: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
 	}
+	if len(created) > 0 {
+		rating = created[0]
+	}
+	// The stream reaches every course member, so anonymous raters stay hidden
+	event := rating
+	if event.Anonymous {
+		event.UserID = 0
+	}
+	s.publishDiscussionEvent(reading.DiscussionID, models.EventRatingSubmitted, event)
 
 	log.Printf("Created reading rating: %+v", rating)
-	return c.JSON(rating)
+	return c.Status(fiber.StatusCreated).JSON(rating)
 }
 
+// updateReadingRating lets the rater change their scores, review or anonymity.
 func (s *Server) updateReadingRating(c *fiber.Ctx) error {
-	ratingID := c.Params("id")
+	existing, err := s.getReadingRatingByID(c.Params("id"))
+	if err != nil {
+		log.Printf("Error querying reading rating: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Reading rating not found"})
+	}
+	_, user, _, err := s.readingAccess(c, strconv.Itoa(existing.ReadingID))
+	if err != nil {
+		return readingErrorResponse(c, err)
+	}
+	if existing.UserID != user.ID {
+		return authErrorResponse(c, errForbidden)
+	}
 
 	var rating models.ReadingRating
 	if err := c.BodyParser(&rating); err != nil {
 		log.Printf("Error parsing reading rating: %v", err)
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
 	}
+	if err := validateReadingRating(&rating); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	rating.ID = existing.ID
+	rating.ReadingID = existing.ReadingID
+	rating.DiscussionID = existing.DiscussionID
+	rating.UserID = existing.UserID
+	rating.CreatedAt = existing.CreatedAt
+	rating.UpdatedAt = time.Now().UTC()
 
 	data, err := json.Marshal(rating)
 	if err != nil {
@@ -878,7 +1213,7 @@ func (s *Server) updateReadingRating(c *fiber.Ctx) error {
 	}
 
 	var jsonResult json.RawMessage
-	err = s.sb.DB.From("reading_ratings").Update(string(data)).Eq("id", ratingID).Execute(&jsonResult)
+	err = s.sb.DB.From("reading_ratings").Update(string(data)).Eq("id", strconv.Itoa(existing.ID)).Execute(&jsonResult)
 	if err != nil {
 		log.Printf("Error updating reading rating: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
@@ -888,11 +1223,25 @@ func (s *Server) updateReadingRating(c *fiber.Ctx) error {
 	return c.JSON(rating)
 }
 
+// deleteReadingRating removes a rating; the rater or the facilitator may do so.
 func (s *Server) deleteReadingRating(c *fiber.Ctx) error {
 	ratingID := c.Params("id")
 
+	rating, err := s.getReadingRatingByID(ratingID)
+	if err != nil {
+		log.Printf("Error querying reading rating: %v", err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Reading rating not found"})
+	}
+	_, user, facilitator, err := s.readingAccess(c, strconv.Itoa(rating.ReadingID))
+	if err != nil {
+		return readingErrorResponse(c, err)
+	}
+	if rating.UserID != user.ID && !facilitator {
+		return authErrorResponse(c, errForbidden)
+	}
+
 	var jsonResult json.RawMessage
-	err := s.sb.DB.From("reading_ratings").Delete().Eq("id", ratingID).Execute(&jsonResult)
+	err = s.sb.DB.From("reading_ratings").Delete().Eq("id", ratingID).Execute(&jsonResult)
 	if err != nil {
 		log.Printf("Error deleting reading rating: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
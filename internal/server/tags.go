@@ -0,0 +1,546 @@
+package server
+
+import (
+	"fmt"
+	"hippias-fiber/internal/importer"
+	"hippias-fiber/internal/models"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// tagTables maps a tag entity type to the table its records live in.
+var tagTables = map[string]string{
+	models.TagCourse:  "courses",
+	models.TagBook:    "books",
+	models.TagAuthor:  "authors",
+	models.TagReading: "readings",
+}
+
+// listTags returns all tags, or with ?tree=true the taxonomy as nested nodes.
+func (s *Server) listTags(c *fiber.Ctx) error {
+	tags, err := s.allTags()
+	if err != nil {
+		log.Printf("Error querying tags: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if c.QueryBool("tree") {
+		return c.JSON(tagTree(tags, nil))
+	}
+	return c.JSON(tags)
+}
+
+func (s *Server) allTags() ([]models.Tag, error) {
+	var tags []models.Tag
+	if err := s.sb.DB.From("tags").Select("*").Execute(&tags); err != nil {
+		return nil, err
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+// tagTree builds the nodes under parentID (nil for the roots).
+func tagTree(tags []models.Tag, parentID *int) []models.TagNode {
+	nodes := []models.TagNode{}
+	for _, tag := range tags {
+		if (tag.ParentID == nil) != (parentID == nil) || (parentID != nil && *tag.ParentID != *parentID) {
+			continue
+		}
+		id := tag.ID
+		nodes = append(nodes, models.TagNode{Tag: tag, Children: tagTree(tags, &id)})
+	}
+	return nodes
+}
+
+// findTag looks a tag up by ID or slug.
+func findTag(tags []models.Tag, ref string) (*models.Tag, bool) {
+	id, _ := strconv.Atoi(ref)
+	for i := range tags {
+		if tags[i].ID == id || tags[i].Slug == ref {
+			return &tags[i], true
+		}
+	}
+	return nil, false
+}
+
+// tagSubtree returns the ID of the tag and of every tag nested under it.
+func tagSubtree(tags []models.Tag, rootID int) []int {
+	ids := []int{rootID}
+	for i := 0; i < len(ids); i++ {
+		for _, tag := range tags {
+			if tag.ParentID != nil && *tag.ParentID == ids[i] {
+				ids = append(ids, tag.ID)
+			}
+		}
+	}
+	return ids
+}
+
+func tagSlug(name string) string {
+	return strings.ReplaceAll(importer.NormalizeTitle(name), " ", "-")
+}
+
+func (s *Server) getTag(c *fiber.Ctx) error {
+	tags, err := s.allTags()
+	if err != nil {
+		log.Printf("Error querying tags: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	tag, ok := findTag(tags, c.Params("id"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Tag not found"})
+	}
+	return c.JSON(models.TagNode{Tag: *tag, Children: tagTree(tags, &tag.ID)})
+}
+
+func (s *Server) createTag(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	var tag models.Tag
+	if err := c.BodyParser(&tag); err != nil {
+		log.Printf("Error parsing tag: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	tags, err := s.allTags()
+	if err != nil {
+		log.Printf("Error querying tags: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	tag.ID = 0
+	if err := validateTag(&tag, tags); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	tag.CreatedAt = time.Now().UTC()
+
+	var created []models.Tag
+	err = s.sb.DB.From("tags").Insert(tag).Execute(&created)
+	if err != nil {
+		log.Printf("Error inserting tag: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(created) > 0 {
+		tag = created[0]
+	}
+
+	log.Printf("Created tag: %+v", tag)
+	return c.Status(fiber.StatusCreated).JSON(tag)
+}
+
+func (s *Server) updateTag(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	tags, err := s.allTags()
+	if err != nil {
+		log.Printf("Error querying tags: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	existing, ok := findTag(tags, c.Params("id"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Tag not found"})
+	}
+
+	var tag models.Tag
+	if err := c.BodyParser(&tag); err != nil {
+		log.Printf("Error parsing tag: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+	tag.ID = existing.ID
+	tag.CreatedAt = existing.CreatedAt
+	if err := validateTag(&tag, tags); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	var updated []models.Tag
+	err = s.sb.DB.From("tags").Update(tag).Eq("id", strconv.Itoa(tag.ID)).Execute(&updated)
+	if err != nil {
+		log.Printf("Error updating tag: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	if len(updated) > 0 {
+		tag = updated[0]
+	}
+
+	log.Printf("Updated tag: %+v", tag)
+	return c.JSON(tag)
+}
+
+// validateTag fills in the slug and checks that it is unique and that the
+// parent exists and is not the tag itself or one of its subtags.
+func validateTag(tag *models.Tag, tags []models.Tag) error {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if tag.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if tag.Slug == "" {
+		tag.Slug = tagSlug(tag.Name)
+	} else {
+		tag.Slug = tagSlug(tag.Slug)
+	}
+	if tag.Slug == "" {
+		return fmt.Errorf("name must contain letters or digits")
+	}
+	if _, err := strconv.Atoi(tag.Slug); err == nil {
+		return fmt.Errorf("slug must not be a number")
+	}
+	for _, other := range tags {
+		if other.ID != tag.ID && other.Slug == tag.Slug {
+			return fmt.Errorf("a tag with slug %q already exists", tag.Slug)
+		}
+	}
+
+	if tag.ParentID == nil {
+		return nil
+	}
+	if _, ok := findTag(tags, strconv.Itoa(*tag.ParentID)); !ok {
+		return fmt.Errorf("parent_id: tag not found")
+	}
+	if tag.ID != 0 {
+		for _, id := range tagSubtree(tags, tag.ID) {
+			if id == *tag.ParentID {
+				return fmt.Errorf("a tag cannot be nested under itself or one of its subtags")
+			}
+		}
+	}
+	return nil
+}
+
+// deleteTag refuses to remove a tag that still has subtags, and removes the
+// tag from every record it was assigned to.
+func (s *Server) deleteTag(c *fiber.Ctx) error {
+	if _, err := s.requireAdmin(c); err != nil {
+		return authErrorResponse(c, err)
+	}
+
+	tags, err := s.allTags()
+	if err != nil {
+		log.Printf("Error querying tags: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	tag, ok := findTag(tags, c.Params("id"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Tag not found"})
+	}
+	if subtree := tagSubtree(tags, tag.ID); len(subtree) > 1 {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{Message: fmt.Sprintf("Tag still has %d subtags", len(subtree)-1)})
+	}
+
+	tagID := strconv.Itoa(tag.ID)
+	err = s.sb.DB.From("tag_assignments").Delete().Eq("tag_id", tagID).Execute(nil)
+	if err == nil {
+		var deleted []models.Tag
+		err = s.sb.DB.From("tags").Delete().Eq("id", tagID).Execute(&deleted)
+	}
+	if err != nil {
+		log.Printf("Error deleting tag: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	log.Printf("Deleted tag %q", tag.Slug)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// getTagCounts counts the records under each tag, directly and including subtags.
+func (s *Server) getTagCounts(c *fiber.Ctx) error {
+	tags, err := s.allTags()
+	if err != nil {
+		log.Printf("Error querying tags: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	var assignments []models.TagAssignment
+	if err := s.sb.DB.From("tag_assignments").Select("*").Execute(&assignments); err != nil {
+		log.Printf("Error querying tag assignments: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+
+	byTag := map[int][]models.TagAssignment{}
+	for _, assignment := range assignments {
+		byTag[assignment.TagID] = append(byTag[assignment.TagID], assignment)
+	}
+
+	counts := make([]models.TagCount, 0, len(tags))
+	for _, tag := range tags {
+		count := models.TagCount{TagID: tag.ID, Name: tag.Name, Slug: tag.Slug}
+		countEntities(&count.Direct, byTag[tag.ID])
+
+		var subtree []models.TagAssignment
+		seen := map[string]bool{}
+		for _, id := range tagSubtree(tags, tag.ID) {
+			for _, assignment := range byTag[id] {
+				key := assignment.EntityType + "/" + strconv.Itoa(assignment.EntityID)
+				if !seen[key] {
+					seen[key] = true
+					subtree = append(subtree, assignment)
+				}
+			}
+		}
+		countEntities(&count.Total, subtree)
+		counts = append(counts, count)
+	}
+	return c.JSON(counts)
+}
+
+func countEntities(counts *models.EntityCounts, assignments []models.TagAssignment) {
+	for _, assignment := range assignments {
+		switch assignment.EntityType {
+		case models.TagCourse:
+			counts.Courses++
+		case models.TagBook:
+			counts.Books++
+		case models.TagAuthor:
+			counts.Authors++
+		case models.TagReading:
+			counts.Readings++
+		}
+	}
+}
+
+// getTaggedItems returns the courses, books, authors and readings under a tag
+// and its subtags; ?include_subtags=false limits it to the tag itself.
+func (s *Server) getTaggedItems(c *fiber.Ctx) error {
+	tags, err := s.allTags()
+	if err != nil {
+		log.Printf("Error querying tags: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+	}
+	tag, ok := findTag(tags, c.Params("id"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: "Tag not found"})
+	}
+	tagIDs := []int{tag.ID}
+	if c.QueryBool("include_subtags", true) {
+		tagIDs = tagSubtree(tags, tag.ID)
+	}
+
+	items := models.TaggedItems{
+		Tag:      *tag,
+		Courses:  []models.Course{},
+		Books:    []models.Book{},
+		Authors:  []models.Author{},
+		Readings: []models.Reading{},
+	}
+	targets := map[string]interface{}{
+		models.TagCourse:  &items.Courses,
+		models.TagBook:    &items.Books,
+		models.TagAuthor:  &items.Authors,
+		models.TagReading: &items.Readings,
+	}
+	for entityType, target := range targets {
+		ids, err := s.taggedEntityIDs(entityType, tagIDs)
+		if err != nil {
+			log.Printf("Error querying tag assignments: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		err = s.sb.DB.From(tagTables[entityType]).Select("*").In("id", ids).Execute(target)
+		if err != nil {
+			log.Printf("Error querying tagged %ss: %v", entityType, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+	}
+	return c.JSON(items)
+}
+
+// taggedEntityIDs returns the IDs of records of one type carrying any of the tags.
+func (s *Server) taggedEntityIDs(entityType string, tagIDs []int) ([]string, error) {
+	ids := make([]string, len(tagIDs))
+	for i, id := range tagIDs {
+		ids[i] = strconv.Itoa(id)
+	}
+	var assignments []models.TagAssignment
+	err := s.sb.DB.From("tag_assignments").Select("entity_id").
+		Eq("entity_type", entityType).
+		In("tag_id", ids).
+		Execute(&assignments)
+	if err != nil {
+		return nil, err
+	}
+	entityIDs := []string{}
+	seen := map[int]bool{}
+	for _, assignment := range assignments {
+		if !seen[assignment.EntityID] {
+			seen[assignment.EntityID] = true
+			entityIDs = append(entityIDs, strconv.Itoa(assignment.EntityID))
+		}
+	}
+	return entityIDs, nil
+}
+
+// tagFilter applies the ?tag= filter (an ID or slug, matching subtags too)
+// of the list endpoints. It returns nil IDs when there is no filter.
+func (s *Server) tagFilter(c *fiber.Ctx, entityType string) ([]string, error) {
+	ref := c.Query("tag")
+	if ref == "" {
+		return nil, nil
+	}
+	tags, err := s.allTags()
+	if err != nil {
+		return nil, err
+	}
+	tag, ok := findTag(tags, ref)
+	if !ok {
+		return []string{}, nil
+	}
+	return s.taggedEntityIDs(entityType, tagSubtree(tags, tag.ID))
+}
+
+// entityTagsHandler lists the tags on a record of the given type.
+func (s *Server) entityTagsHandler(entityType string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tags, err := s.entityTags(entityType, c.Params("id"))
+		if err != nil {
+			log.Printf("Error querying %s tags: %v", entityType, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+		return c.JSON(tags)
+	}
+}
+
+func (s *Server) entityTags(entityType, entityID string) ([]models.Tag, error) {
+	var assignments []models.TagAssignment
+	err := s.sb.DB.From("tag_assignments").Select("tag_id").
+		Eq("entity_type", entityType).
+		Eq("entity_id", entityID).
+		Execute(&assignments)
+	if err != nil {
+		return nil, err
+	}
+	tags := []models.Tag{}
+	if len(assignments) == 0 {
+		return tags, nil
+	}
+	var tagIDs []string
+	for _, assignment := range assignments {
+		tagIDs = append(tagIDs, strconv.Itoa(assignment.TagID))
+	}
+	if err := s.sb.DB.From("tags").Select("*").In("id", tagIDs).Execute(&tags); err != nil {
+		return nil, err
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+// requireTagEditor checks that the user may change a record's tags: admins
+// for books and authors, the facilitator for a course or a reading in it.
+func (s *Server) requireTagEditor(c *fiber.Ctx, entityType string, entityID int) error {
+	switch entityType {
+	case models.TagCourse:
+		_, err := s.requireFacilitator(c, entityID)
+		return err
+	case models.TagReading:
+		_, _, facilitator, err := s.readingAccess(c, strconv.Itoa(entityID))
+		if err != nil {
+			return err
+		}
+		if !facilitator {
+			return errForbidden
+		}
+		return nil
+	}
+	_, err := s.requireAdmin(c)
+	return err
+}
+
+// setEntityTagsHandler replaces the tags on a record of the given type with
+// tag_ids.
+func (s *Server) setEntityTagsHandler(entityType string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		entityID, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: "invalid id"})
+		}
+		var existing []map[string]interface{}
+		err = s.sb.DB.From(tagTables[entityType]).Select("id").Eq("id", c.Params("id")).Execute(&existing)
+		if err != nil {
+			log.Printf("Error querying %s: %v", entityType, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+		if len(existing) == 0 {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Message: fmt.Sprintf("%s not found", entityType)})
+		}
+		if err := s.requireTagEditor(c, entityType, entityID); err != nil {
+			return readingErrorResponse(c, err)
+		}
+
+		var req models.TagIDsRequest
+		if err := c.BodyParser(&req); err != nil {
+			log.Printf("Error parsing tag IDs: %v", err)
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: err.Error()})
+		}
+		tags, err := s.allTags()
+		if err != nil {
+			log.Printf("Error querying tags: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+		wanted := map[int]bool{}
+		for _, id := range req.TagIDs {
+			if _, ok := findTag(tags, strconv.Itoa(id)); !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Message: fmt.Sprintf("tag %d not found", id)})
+			}
+			wanted[id] = true
+		}
+
+		var current []models.TagAssignment
+		err = s.sb.DB.From("tag_assignments").Select("*").
+			Eq("entity_type", entityType).
+			Eq("entity_id", c.Params("id")).
+			Execute(&current)
+		if err != nil {
+			log.Printf("Error querying tag assignments: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+
+		var stale []string
+		for _, assignment := range current {
+			if wanted[assignment.TagID] {
+				delete(wanted, assignment.TagID)
+			} else {
+				stale = append(stale, strconv.Itoa(assignment.ID))
+			}
+		}
+		if len(stale) > 0 {
+			var deleted []models.TagAssignment
+			if err := s.sb.DB.From("tag_assignments").Delete().In("id", stale).Execute(&deleted); err != nil {
+				log.Printf("Error removing tags: %v", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+			}
+		}
+		if len(wanted) > 0 {
+			now := time.Now().UTC()
+			var rows []models.TagAssignment
+			for tagID := range wanted {
+				rows = append(rows, models.TagAssignment{TagID: tagID, EntityType: entityType, EntityID: entityID, CreatedAt: now})
+			}
+			var created []models.TagAssignment
+			if err := s.sb.DB.From("tag_assignments").Insert(rows).Execute(&created); err != nil {
+				log.Printf("Error adding tags: %v", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+			}
+		}
+
+		result, err := s.entityTags(entityType, c.Params("id"))
+		if err != nil {
+			log.Printf("Error querying %s tags: %v", entityType, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Message: err.Error()})
+		}
+		return c.JSON(result)
+	}
+}
+
+// removeEntityTags drops the tag assignments of a deleted record.
+func (s *Server) removeEntityTags(entityType, entityID string) error {
+	var deleted []models.TagAssignment
+	return s.sb.DB.From("tag_assignments").Delete().
+		Eq("entity_type", entityType).
+		Eq("entity_id", entityID).
+		Execute(&deleted)
+}
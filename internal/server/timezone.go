@@ -0,0 +1,116 @@
+package server
+
+import (
+	"fmt"
+	"hippias-fiber/internal/models"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// offsetLayouts are accepted for discussion times that carry their own UTC
+// offset, in addition to RFC 3339.
+var offsetLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04Z07:00",
+}
+
+// wallClockLayouts are accepted for discussion times that carry no offset; they
+// are interpreted in the requesting user's timezone (see inputLocation).
+var wallClockLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// loadLocation resolves an IANA timezone name, treating an empty name as UTC.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return loc, nil
+}
+
+// parseDiscussionTime parses a timestamp with an explicit offset, or a
+// wall-clock time read in loc, and returns the instant in UTC.
+func parseDiscussionTime(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range offsetLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	for _, layout := range wallClockLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date_time %q: expected RFC 3339 or YYYY-MM-DDTHH:MM", value)
+}
+
+// recurringTimes returns count occurrences starting at start, each intervalDays
+// apart on the calendar of loc. Stepping by calendar days rather than by 24h
+// keeps the wall-clock time fixed when the zone changes offset for DST.
+func recurringTimes(start time.Time, loc *time.Location, intervalDays, count int) []time.Time {
+	local := start.In(loc)
+	times := make([]time.Time, 0, count)
+	for i := 0; i < count; i++ {
+		t := time.Date(local.Year(), local.Month(), local.Day()+i*intervalDays,
+			local.Hour(), local.Minute(), local.Second(), 0, loc)
+		times = append(times, t.UTC())
+	}
+	return times
+}
+
+// courseLocation returns the timezone configured for a course.
+func (s *Server) courseLocation(courseID int) (*time.Location, error) {
+	course, err := s.getCourseByID(strconv.Itoa(courseID))
+	if err != nil {
+		return nil, err
+	}
+	return loadLocation(course.Timezone)
+}
+
+// displayLocation picks the zone discussion times are rendered in: an explicit
+// ?tz= parameter, then the authenticated user's timezone, then fallback (usually
+// the course's zone).
+func (s *Server) displayLocation(c *fiber.Ctx, fallback *time.Location) (*time.Location, error) {
+	if tz := c.Query("tz"); tz != "" {
+		return loadLocation(tz)
+	}
+	if c.Get("Authorization") != "" {
+		user, err := s.currentUser(c)
+		if err != nil {
+			log.Printf("Falling back to course timezone: %v", err)
+		} else if user.Timezone != "" {
+			return loadLocation(user.Timezone)
+		}
+	}
+	return fallback, nil
+}
+
+// inputLocation picks the zone wall-clock discussion times in a request are
+// read in: the same zone they would be shown to the requester in, falling
+// back to the course's zone.
+func (s *Server) inputLocation(c *fiber.Ctx, courseID int) (*time.Location, error) {
+	courseLoc, err := s.courseLocation(courseID)
+	if err != nil {
+		return nil, err
+	}
+	return s.displayLocation(c, courseLoc)
+}
+
+// localizeDiscussions converts each discussion's DateTime into loc in place.
+func localizeDiscussions(discussions []models.Discussion, loc *time.Location) {
+	for i := range discussions {
+		discussions[i].DateTime = discussions[i].DateTime.In(loc)
+	}
+}
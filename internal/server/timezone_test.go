@@ -0,0 +1,101 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDiscussionTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"RFC 3339", "2024-03-10T18:30:00Z", time.Date(2024, 3, 10, 18, 30, 0, 0, time.UTC), false},
+		{"offset without seconds", "2024-03-10T18:30+02:00", time.Date(2024, 3, 10, 16, 30, 0, 0, time.UTC), false},
+		{"offset with a space", "2024-03-10 18:30:00-05:00", time.Date(2024, 3, 10, 23, 30, 0, 0, time.UTC), false},
+		{"wall clock in winter", "2024-03-10T18:30", time.Date(2024, 3, 10, 17, 30, 0, 0, time.UTC), false},
+		{"wall clock in summer", "2024-07-10 18:30:00", time.Date(2024, 7, 10, 16, 30, 0, 0, time.UTC), false},
+		{"date only", "2024-03-10", time.Time{}, true},
+		{"garbage", "next tuesday", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDiscussionTime(tt.value, berlin)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDiscussionTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseDiscussionTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecurringTimes(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+	tests := []struct {
+		name         string
+		start        time.Time
+		loc          *time.Location
+		intervalDays int
+		count        int
+		want         []time.Time
+	}{
+		{
+			name:         "weekly across spring forward",
+			start:        time.Date(2024, 3, 3, 19, 0, 0, 0, newYork),
+			loc:          newYork,
+			intervalDays: 7,
+			count:        3,
+			want: []time.Time{
+				time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 10, 23, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 17, 23, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:         "daily across fall back",
+			start:        time.Date(2024, 11, 2, 9, 30, 0, 0, newYork),
+			loc:          newYork,
+			intervalDays: 1,
+			count:        2,
+			want: []time.Time{
+				time.Date(2024, 11, 2, 13, 30, 0, 0, time.UTC),
+				time.Date(2024, 11, 3, 14, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:         "UTC has no shift",
+			start:        time.Date(2024, 3, 3, 19, 0, 0, 0, time.UTC),
+			loc:          time.UTC,
+			intervalDays: 14,
+			count:        2,
+			want: []time.Time{
+				time.Date(2024, 3, 3, 19, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 17, 19, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := recurringTimes(tt.start, tt.loc, tt.intervalDays, tt.count)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d times, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("time %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
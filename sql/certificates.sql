@@ -0,0 +1,24 @@
+-- Issuing completion certificates (see POST /courses/:id/certificates). A
+-- participant holds at most one certificate per course, so two concurrent
+-- issue runs cannot both create one.
+
+create unique index if not exists certificates_course_user
+  on certificates (course_id, user_id);
+
+-- Inserts a certificate unless the participant already holds one for the
+-- course, and returns the certificate they hold either way.
+create or replace function issue_certificate(certificate jsonb)
+returns certificates
+language sql
+as $$
+  insert into certificates (code, course_id, user_id, recipient_name, course_title,
+                            attendance_percent, readings_rated, issued_at)
+  select r.code, r.course_id, r.user_id, r.recipient_name, r.course_title,
+         r.attendance_percent, r.readings_rated, r.issued_at
+    from jsonb_populate_record(null::certificates, certificate) r
+  on conflict (course_id, user_id) do nothing;
+
+  select * from certificates
+   where course_id = (certificate->>'course_id')::bigint
+     and user_id = (certificate->>'user_id')::bigint;
+$$;
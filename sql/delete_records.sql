@@ -0,0 +1,59 @@
+-- Deleting catalog books and authors (see DELETE /books/:id and
+-- DELETE /authors/:id). Each delete runs as one function call, so the
+-- record and the rows hanging off it go together or not at all.
+--
+-- Both return {"found": bool, "deleted": bool, ...counts}. A record still in
+-- use is left alone and the counts say what uses it.
+
+create or replace function delete_book(book_id bigint)
+returns json
+language plpgsql
+as $$
+declare
+  target bigint := delete_book.book_id;
+  course_book_count int;
+  reading_count int;
+begin
+  perform 1 from books where id = target for update;
+  if not found then
+    return json_build_object('found', false, 'deleted', false);
+  end if;
+
+  select count(*) into course_book_count from course_books cb where cb.book_id = target;
+  select count(*) into reading_count from readings r where r.book_id = target;
+  if course_book_count > 0 or reading_count > 0 then
+    return json_build_object('found', true, 'deleted', false,
+      'course_books', course_book_count, 'readings', reading_count);
+  end if;
+
+  delete from tag_assignments where entity_type = 'book' and entity_id = target;
+  delete from editions e where e.book_id = target;
+  delete from books where id = target;
+  return json_build_object('found', true, 'deleted', true);
+end;
+$$;
+
+create or replace function delete_author(author_id bigint)
+returns json
+language plpgsql
+as $$
+declare
+  target bigint := delete_author.author_id;
+  book_count int;
+begin
+  perform 1 from authors where id = target for update;
+  if not found then
+    return json_build_object('found', false, 'deleted', false);
+  end if;
+
+  select count(*) into book_count from books where "authorId" = target;
+  if book_count > 0 then
+    return json_build_object('found', true, 'deleted', false, 'books', book_count);
+  end if;
+
+  delete from author_relations where from_author_id = target or to_author_id = target;
+  delete from tag_assignments where entity_type = 'author' and entity_id = target;
+  delete from authors where id = target;
+  return json_build_object('found', true, 'deleted', true);
+end;
+$$;
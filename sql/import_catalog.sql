@@ -0,0 +1,72 @@
+-- Applying a catalog import (see `main import -commit`). The whole plan is
+-- written in one function call, so a failure part way leaves the catalog as
+-- it was.
+--
+-- New authors and books carry a "key" that later entries refer to
+-- (author_key, book_key) when the row they belong to has no ID yet.
+
+create or replace function import_catalog(authors jsonb, books jsonb, editions jsonb, updates jsonb)
+returns json
+language plpgsql
+as $$
+declare
+  item jsonb;
+  new_id bigint;
+  author_ids jsonb := '{}';
+  book_ids jsonb := '{}';
+  authors_created int := 0;
+  books_created int := 0;
+  editions_created int := 0;
+  books_updated int := 0;
+begin
+  for item in select * from jsonb_array_elements(import_catalog.authors) loop
+    insert into authors (name, nationality, description, schools, links, "createdAt", "updatedAt")
+    values (item->>'name', '', '', '[]', '[]', now(), now())
+    returning id into new_id;
+    author_ids := author_ids || jsonb_build_object(item->>'key', new_id);
+    authors_created := authors_created + 1;
+  end loop;
+
+  for item in select * from jsonb_array_elements(import_catalog.books) loop
+    insert into books (title, author, description, "authorId", "createdAt", "updatedAt")
+    values (
+      item->>'title',
+      item->>'author',
+      coalesce(item->>'description', ''),
+      coalesce(nullif((item->>'author_id')::bigint, 0), (author_ids->>(item->>'author_key'))::bigint),
+      now(), now())
+    returning id into new_id;
+    book_ids := book_ids || jsonb_build_object(item->>'key', new_id);
+    books_created := books_created + 1;
+  end loop;
+
+  for item in select * from jsonb_array_elements(import_catalog.editions) loop
+    insert into editions (book_id, title, translator, publisher, year, isbn, language, page_count, created_at, updated_at)
+    values (
+      coalesce(nullif((item->>'book_id')::bigint, 0), (book_ids->>(item->>'book_key'))::bigint),
+      item->>'title', '', coalesce(item->>'publisher', ''), coalesce((item->>'year')::int, 0),
+      coalesce(item->>'isbn', ''), coalesce(item->>'language', ''), coalesce((item->>'page_count')::int, 0),
+      now(), now());
+    editions_created := editions_created + 1;
+  end loop;
+
+  for item in select * from jsonb_array_elements(import_catalog.updates) loop
+    update books
+       set description = case when description = '' then coalesce(item->>'description', '') else description end,
+           "authorId" = case when coalesce("authorId", 0) = 0
+                             then coalesce(nullif((item->>'author_id')::bigint, 0),
+                                           (author_ids->>(item->>'author_key'))::bigint,
+                                           "authorId")
+                             else "authorId" end,
+           "updatedAt" = now()
+     where id = (item->>'id')::bigint;
+    books_updated := books_updated + 1;
+  end loop;
+
+  return json_build_object(
+    'authors_created', authors_created,
+    'books_created', books_created,
+    'editions_created', editions_created,
+    'books_updated', books_updated);
+end;
+$$;
@@ -0,0 +1,76 @@
+-- Importing readings into a discussion from a BibTeX or RIS file (see
+-- POST /discussions/:id/readings/import). The readings, and the books and
+-- authors created for them, are written in one function call, so a failure
+-- part way leaves nothing behind.
+--
+-- New authors and books carry a "key" that later entries refer to
+-- (author_key, book_key), as in import_catalog.sql. Returns the created rows
+-- as {"authors": [...], "books": [...], "readings": [...]}.
+
+create or replace function import_readings(discussion_id bigint, authors jsonb, books jsonb, readings jsonb)
+returns json
+language plpgsql
+as $$
+declare
+  target bigint := import_readings.discussion_id;
+  item jsonb;
+  r readings;
+  new_author authors;
+  new_book books;
+  new_reading readings;
+  author_ids jsonb := '{}';
+  book_ids jsonb := '{}';
+  created_authors jsonb := '[]';
+  created_books jsonb := '[]';
+  created_readings jsonb := '[]';
+begin
+  -- Serialize imports per discussion so two uploads of the same file cannot
+  -- both add its readings
+  perform 1 from discussions where id = target for update;
+  if not found then
+    raise exception 'discussion % not found', target;
+  end if;
+
+  for item in select * from jsonb_array_elements(import_readings.authors) loop
+    insert into authors (name, nationality, description, schools, links, "createdAt", "updatedAt")
+    values (item->>'name', '', '', '[]', '[]', now(), now())
+    returning * into new_author;
+    author_ids := author_ids || jsonb_build_object(item->>'key', new_author.id);
+    created_authors := created_authors || to_jsonb(new_author);
+  end loop;
+
+  for item in select * from jsonb_array_elements(import_readings.books) loop
+    insert into books (title, author, description, "authorId", "createdAt", "updatedAt")
+    values (
+      item->>'title',
+      coalesce(item->>'author', ''),
+      '',
+      coalesce(nullif((item->>'author_id')::bigint, 0), (author_ids->>(item->>'author_key'))::bigint),
+      now(), now())
+    returning * into new_book;
+    book_ids := book_ids || jsonb_build_object(item->>'key', new_book.id);
+    created_books := created_books || to_jsonb(new_book);
+  end loop;
+
+  for item in select * from jsonb_array_elements(import_readings.readings) loop
+    r := jsonb_populate_record(null::readings, item);
+    insert into readings (discussion_id, type, title, description, url, book_id, video_url,
+                          discussion_prompt, edition_id, chapter, page_start, page_end, source,
+                          word_count, start_seconds, end_seconds, duration_seconds, estimated_minutes)
+    values (
+      target, r.type, r.title, coalesce(r.description, ''), coalesce(r.url, ''),
+      case when item ? 'book_key' then (book_ids->>(item->>'book_key'))::bigint else r.book_id end,
+      coalesce(r.video_url, ''), coalesce(r.discussion_prompt, ''), nullif(r.edition_id, 0),
+      coalesce(r.chapter, ''), coalesce(r.page_start, 0), coalesce(r.page_end, 0),
+      coalesce(r.source, ''), coalesce(r.word_count, 0), coalesce(r.start_seconds, 0),
+      coalesce(r.end_seconds, 0), coalesce(r.duration_seconds, 0), coalesce(r.estimated_minutes, 0))
+    returning * into new_reading;
+    created_readings := created_readings || to_jsonb(new_reading);
+  end loop;
+
+  return json_build_object(
+    'authors', created_authors,
+    'books', created_books,
+    'readings', created_readings);
+end;
+$$;
@@ -0,0 +1,117 @@
+-- Merging duplicate authors and books (see POST /authors/:id/merge and
+-- POST /books/:id/merge). Each merge runs as one function call, so the
+-- re-pointing, the delete and the redirect commit or fail together.
+
+-- Redirects left by merges: lookups of old_id are answered with new_id.
+create table if not exists author_redirects (
+  old_id bigint primary key,
+  new_id bigint not null references authors (id) on delete cascade,
+  merged_at timestamptz not null default now()
+);
+
+create table if not exists book_redirects (
+  old_id bigint primary key,
+  new_id bigint not null references books (id) on delete cascade,
+  merged_at timestamptz not null default now()
+);
+
+create or replace function merge_authors(from_id bigint, into_id bigint)
+returns void
+language plpgsql
+as $$
+declare
+  survivor_name text;
+begin
+  if from_id = into_id then
+    raise exception 'cannot merge author % into itself', from_id;
+  end if;
+  select name into survivor_name from authors where id = into_id;
+  if not found then
+    raise exception 'author % not found', into_id;
+  end if;
+  perform 1 from authors where id = from_id for update;
+  if not found then
+    raise exception 'author % not found', from_id;
+  end if;
+
+  update books
+     set "authorId" = into_id, author = survivor_name, "updatedAt" = now()
+   where "authorId" = from_id;
+
+  -- Relations move to the survivor, dropping any that would become
+  -- self-references or repeat an existing relation
+  delete from author_relations
+   where (from_author_id = from_id and to_author_id = into_id)
+      or (from_author_id = into_id and to_author_id = from_id);
+  delete from author_relations dup
+   using author_relations keep
+   where dup.from_author_id = from_id and keep.from_author_id = into_id
+     and keep.to_author_id = dup.to_author_id and keep.kind = dup.kind;
+  delete from author_relations dup
+   using author_relations keep
+   where dup.to_author_id = from_id and keep.to_author_id = into_id
+     and keep.from_author_id = dup.from_author_id and keep.kind = dup.kind;
+  update author_relations set from_author_id = into_id where from_author_id = from_id;
+  update author_relations set to_author_id = into_id where to_author_id = from_id;
+
+  delete from tag_assignments dup
+   using tag_assignments keep
+   where dup.entity_type = 'author' and dup.entity_id = from_id
+     and keep.entity_type = 'author' and keep.entity_id = into_id
+     and keep.tag_id = dup.tag_id;
+  update tag_assignments set entity_id = into_id
+   where entity_type = 'author' and entity_id = from_id;
+
+  -- Earlier redirects to the merged author follow it to the survivor
+  update author_redirects set new_id = into_id where new_id = from_id;
+  delete from authors where id = from_id;
+  insert into author_redirects (old_id, new_id) values (from_id, into_id);
+end;
+$$;
+
+create or replace function merge_books(from_id bigint, into_id bigint)
+returns void
+language plpgsql
+as $$
+begin
+  if from_id = into_id then
+    raise exception 'cannot merge book % into itself', from_id;
+  end if;
+  perform 1 from books where id = into_id;
+  if not found then
+    raise exception 'book % not found', into_id;
+  end if;
+  perform 1 from books where id = from_id for update;
+  if not found then
+    raise exception 'book % not found', from_id;
+  end if;
+
+  -- A course listing both books keeps the survivor's row, required if
+  -- either was
+  update course_books keep
+     set required = keep.required or dup.required, updated_at = now()
+    from course_books dup
+   where keep.book_id = into_id and dup.book_id = from_id
+     and keep.course_id = dup.course_id;
+  delete from course_books dup
+   using course_books keep
+   where dup.book_id = from_id and keep.book_id = into_id
+     and keep.course_id = dup.course_id;
+  update course_books set book_id = into_id, updated_at = now() where book_id = from_id;
+
+  update readings set book_id = into_id where book_id = from_id;
+  update editions set book_id = into_id, updated_at = now() where book_id = from_id;
+
+  delete from tag_assignments dup
+   using tag_assignments keep
+   where dup.entity_type = 'book' and dup.entity_id = from_id
+     and keep.entity_type = 'book' and keep.entity_id = into_id
+     and keep.tag_id = dup.tag_id;
+  update tag_assignments set entity_id = into_id
+   where entity_type = 'book' and entity_id = from_id;
+
+  update book_redirects set new_id = into_id where new_id = from_id;
+  delete from books where id = from_id;
+  insert into book_redirects (old_id, new_id) values (from_id, into_id);
+end;
+$$;
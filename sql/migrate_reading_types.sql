@@ -0,0 +1,46 @@
+-- Moves readings saved before reading kinds existed onto the kinds (see
+-- models.Reading). Mirrors legacyReadingTypes in internal/server/readings.go;
+-- the API maps the same values when a legacy reading is next saved, so this
+-- only needs to run once to tidy up stored rows.
+
+update readings
+set type = case regexp_replace(lower(trim(coalesce(type, ''))), '[ -]', '_', 'g')
+  when 'book' then 'book_section'
+  when 'chapter' then 'book_section'
+  when 'book_chapter' then 'book_section'
+  when 'section' then 'book_section'
+  when 'excerpt' then 'book_section'
+  when 'paper' then 'article'
+  when 'essay' then 'article'
+  when 'journal' then 'article'
+  when 'journal_article' then 'article'
+  when 'pdf' then 'article'
+  when 'lecture' then 'video'
+  when 'film' then 'video'
+  when 'youtube' then 'video'
+  when 'podcast' then 'audio'
+  when 'recording' then 'audio'
+  when 'web' then 'web_page'
+  when 'webpage' then 'web_page'
+  when 'website' then 'web_page'
+  when 'link' then 'web_page'
+  when 'blog' then 'web_page'
+  when 'blog_post' then 'web_page'
+  when 'online' then 'web_page'
+  when '' then case
+    when book_id is not null and book_id <> 0 then 'book_section'
+    when coalesce(video_url, '') <> '' then 'video'
+    when coalesce(url, '') <> '' then 'web_page'
+    else type
+  end
+  else regexp_replace(lower(trim(type)), '[ -]', '_', 'g')
+end
+where type is null
+   or type not in ('book_section', 'article', 'video', 'audio', 'web_page');
+
+-- Readings still listed here have a type that could not be mapped and need
+-- their type set by hand before they can be edited.
+select id, discussion_id, type, title
+from readings
+where type is null
+   or type not in ('book_section', 'article', 'video', 'audio', 'web_page');
@@ -0,0 +1,43 @@
+-- Recording a discussion's roster (see PUT /discussions/:id/attendance,
+-- POST /discussion-attendance and POST /check-in). The whole roster is
+-- written in one function call, so a failure part way leaves no rows changed.
+-- checked_in_at is set by self check-in and stamped on the rows written.
+
+drop function if exists record_attendance(bigint, jsonb);
+
+create or replace function record_attendance(discussion_id bigint, records jsonb,
+                                             checked_in_at timestamptz default null)
+returns setof discussion_attendance
+language plpgsql
+as $$
+#variable_conflict use_column
+declare
+  target bigint := record_attendance.discussion_id;
+  check_in timestamptz := record_attendance.checked_in_at;
+begin
+  -- Serialize roster writes per discussion so two submissions cannot both
+  -- insert a row for the same participant
+  perform 1 from discussions where id = target for update;
+  if not found then
+    raise exception 'discussion % not found', target;
+  end if;
+
+  -- Existing rows keep their ID, and their check-in time unless this is one
+  return query
+    update discussion_attendance a
+       set status = r.status, attended = r.status = 'present',
+           checked_in_at = coalesce(check_in, a.checked_in_at)
+      from jsonb_to_recordset(records) as r(user_id bigint, status text)
+     where a.discussion_id = target and a.user_id = r.user_id
+    returning a.*;
+
+  return query
+    insert into discussion_attendance (discussion_id, user_id, status, attended, checked_in_at)
+    select target, r.user_id, r.status, r.status = 'present', check_in
+      from jsonb_to_recordset(records) as r(user_id bigint, status text)
+     where not exists (
+       select 1 from discussion_attendance a
+        where a.discussion_id = target and a.user_id = r.user_id)
+    returning *;
+end;
+$$;